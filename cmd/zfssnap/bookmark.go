@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jsirianni/zfssnap/model"
+	"github.com/jsirianni/zfssnap/zfs"
+	"github.com/spf13/cobra"
+)
+
+var flagBookmarkListRecursive bool
+
+// bookmarkCreateResult is the JSON document printed by `bookmark create`.
+type bookmarkCreateResult struct {
+	Snapshot string `json:"snapshot"`
+	Bookmark string `json:"bookmark"`
+}
+
+// bookmarkDeleteResult is the JSON document printed by `bookmark delete`.
+type bookmarkDeleteResult struct {
+	Deleted []string `json:"deleted"`
+	Errors  []string `json:"errors"`
+}
+
+var bookmarkCmd = &cobra.Command{
+	Use:   "bookmark",
+	Short: "Manage bookmarks",
+	Long: `Manage bookmarks.
+
+A bookmark marks the point in time of a snapshot without holding any of its
+data. It remains after the snapshot is destroyed and can still be the source
+of an incremental send, so replicated snapshots can be pruned from the
+source without breaking replication.`,
+}
+
+var bookmarkCreateCmd = &cobra.Command{
+	Use:   "create <snapshot> [bookmark]",
+	Short: "Create a bookmark of a snapshot",
+	Long: `Create a bookmark of a snapshot in the snapshot's dataset.
+
+The bookmark is named after the snapshot unless a name is given, either as a
+short name or as dataset#name.
+
+Examples:
+  # Create pool/dataset#daily-20250101
+  zfssnap bookmark create pool/dataset@daily-20250101
+
+  # Create pool/dataset#replicated
+  zfssnap bookmark create pool/dataset@daily-20250101 replicated`,
+	Args: usageArgs(cobra.RangeArgs(1, 2)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snapshot := args[0]
+		if !zfs.IsValidSnapshotName(snapshot) {
+			return usageError(fmt.Errorf("invalid snapshot name format: %s (must contain @)", snapshot))
+		}
+		dataset, name, _ := strings.Cut(snapshot, "@")
+		if len(args) == 2 {
+			name = args[1]
+		}
+		bookmark := name
+		if !strings.Contains(bookmark, "#") {
+			bookmark = dataset + "#" + name
+		}
+
+		s := newSnapshotter()
+		if err := s.Bookmark(context.Background(), snapshot, bookmark); err != nil {
+			return fmt.Errorf("bookmark snapshot %s: %w", snapshot, err)
+		}
+		return writeOutput(bookmarkCreateResult{Snapshot: snapshot, Bookmark: bookmark}, cmd.OutOrStdout())
+	},
+}
+
+var bookmarkListCmd = &cobra.Command{
+	Use:   "list [flags] [dataset...]",
+	Short: "List bookmarks",
+	Long: `List the bookmarks of the given datasets, or of every dataset when none is
+given.
+
+Examples:
+  # List every bookmark
+  zfssnap bookmark list
+
+  # List the bookmarks of a dataset and all of its children
+  zfssnap bookmark list -r pool/dataset`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		s := newSnapshotter()
+
+		if len(args) == 0 {
+			bookmarks, err := s.Bookmarks(ctx, zfs.ListOptions{})
+			if err != nil {
+				return fmt.Errorf("list bookmarks: %w", err)
+			}
+			return writeOutput(bookmarks, cmd.OutOrStdout())
+		}
+
+		bookmarks := []*model.Bookmark{}
+		for _, dataset := range args {
+			b, err := s.Bookmarks(ctx, zfs.ListOptions{Dataset: dataset, Recursive: flagBookmarkListRecursive})
+			if err != nil {
+				return fmt.Errorf("list bookmarks for %s: %w", dataset, err)
+			}
+			bookmarks = append(bookmarks, b...)
+		}
+		return writeOutput(bookmarks, cmd.OutOrStdout())
+	},
+}
+
+var bookmarkDeleteCmd = &cobra.Command{
+	Use:   "delete <bookmark...>",
+	Short: "Destroy bookmarks",
+	Long: `Destroy one or more bookmarks.
+
+Examples:
+  zfssnap bookmark delete pool/dataset#daily-20250101`,
+	Args: usageArgs(cobra.MinimumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		s := newSnapshotter()
+
+		result := bookmarkDeleteResult{Deleted: []string{}, Errors: []string{}}
+		for _, name := range args {
+			if err := s.DeleteBookmark(ctx, name); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("delete bookmark %s: %v", name, err))
+				continue
+			}
+			result.Deleted = append(result.Deleted, name)
+		}
+		if err := writeOutput(result, cmd.OutOrStdout()); err != nil {
+			return err
+		}
+		return failureError("delete", "bookmark", len(result.Errors), len(args))
+	},
+}
+
+func init() {
+	bookmarkListCmd.Flags().BoolVarP(&flagBookmarkListRecursive, "recursive", "r", false, "Include bookmarks of all child datasets")
+
+	bookmarkCmd.AddCommand(bookmarkCreateCmd)
+	bookmarkCmd.AddCommand(bookmarkListCmd)
+	bookmarkCmd.AddCommand(bookmarkDeleteCmd)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+	"github.com/jsirianni/zfssnap/testutil"
+	"github.com/jsirianni/zfssnap/zfs"
+)
+
+func TestBookmarkCommands(t *testing.T) {
+	ctx := context.Background()
+	sim := testutil.NewSimulator(testutil.NewClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+	for _, ds := range []string{"pool/data", "pool/data/child"} {
+		if err := sim.CreateDataset(ds); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := sim.Create(ctx, "pool/data", "daily", zfs.CreateOptions{Recursive: true}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	original := newSnapshotter
+	newSnapshotter = func() snapshotter { return sim }
+	t.Cleanup(func() {
+		newSnapshotter = original
+		flagBookmarkListRecursive = false
+		rootCmd.SetOut(nil)
+		rootCmd.SetArgs(nil)
+	})
+
+	run := func(args ...string) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		rootCmd.SetOut(&buf)
+		rootCmd.SetArgs(append([]string{"bookmark"}, args...))
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("bookmark %v: %v", args, err)
+		}
+		return buf.Bytes()
+	}
+
+	var created bookmarkCreateResult
+	if err := json.Unmarshal(run("create", "pool/data@daily"), &created); err != nil {
+		t.Fatalf("Invalid output: %v", err)
+	}
+	if created.Bookmark != "pool/data#daily" {
+		t.Errorf("Expected bookmark named after the snapshot, got %+v", created)
+	}
+	run("create", "pool/data/child@daily", "replicated")
+
+	var bookmarks []*model.Bookmark
+	if err := json.Unmarshal(run("list", "-r", "pool/data"), &bookmarks); err != nil {
+		t.Fatalf("Invalid output: %v", err)
+	}
+	if len(bookmarks) != 2 || bookmarks[1].Name != "pool/data/child#replicated" {
+		t.Fatalf("Unexpected bookmarks: %+v", bookmarks)
+	}
+
+	deleteBookmarks := func(expected int, names ...string) bookmarkDeleteResult {
+		t.Helper()
+		var buf bytes.Buffer
+		bookmarkDeleteCmd.SetOut(&buf)
+		err := bookmarkDeleteCmd.RunE(bookmarkDeleteCmd, names)
+		if expected == 0 && err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		} else if code := exitCode(err); expected != 0 && code != expected {
+			t.Errorf("Expected exit code %d, got %d (%v)", expected, code, err)
+		}
+		var result bookmarkDeleteResult
+		if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+			t.Fatalf("Invalid output %q: %v", buf.String(), err)
+		}
+		return result
+	}
+
+	deleted := deleteBookmarks(exitPartialFailure, "pool/data#daily", "pool/data#missing")
+	if len(deleted.Deleted) != 1 || len(deleted.Errors) != 1 {
+		t.Errorf("Unexpected delete result: %+v", deleted)
+	}
+	deleted = deleteBookmarks(exitAllFailed, "pool/data#daily", "pool/data#missing")
+	if len(deleted.Deleted) != 0 || len(deleted.Errors) != 2 {
+		t.Errorf("Unexpected delete result: %+v", deleted)
+	}
+	deleted = deleteBookmarks(0, "pool/data/child#replicated")
+	if len(deleted.Deleted) != 1 || len(deleted.Errors) != 0 {
+		t.Errorf("Unexpected delete result: %+v", deleted)
+	}
+}
+
+func TestBookmarkCreateInvalidName(t *testing.T) {
+	err := bookmarkCreateCmd.RunE(bookmarkCreateCmd, []string{"pool/data"})
+	if code := exitCode(err); code != exitUsage {
+		t.Errorf("Expected exit code %d, got %d (%v)", exitUsage, code, err)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jsirianni/zfssnap/zfs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagCloneProperties []string
+	flagCloneParents    bool
+)
+
+// cloneResult is the JSON document printed by the clone command.
+type cloneResult struct {
+	Origin     string            `json:"origin"`
+	Clone      string            `json:"clone"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// promoteResult is the JSON document printed by the promote command.
+type promoteResult struct {
+	Promoted string `json:"promoted"`
+}
+
+var cloneCmd = &cobra.Command{
+	Use:   "clone [flags] <snapshot> <target-dataset>",
+	Short: "Create a dataset from a snapshot",
+	Long: `Create a writable dataset from a snapshot.
+
+The clone initially shares all of its data with the snapshot, so it is
+created instantly and only consumes space as it diverges. The snapshot
+cannot be destroyed while the clone exists; use promote to reverse the
+dependency.
+
+Examples:
+  # Clone a snapshot
+  zfssnap clone pool/db@nightly pool/db-test
+
+  # Override properties inherited from the origin
+  zfssnap clone -o mountpoint=/srv/db-test -o readonly=off pool/db@nightly pool/db-test
+
+  # Create missing parent datasets
+  zfssnap clone -p pool/db@nightly pool/test/db`,
+	Args: usageArgs(cobra.ExactArgs(2)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		properties, err := parseProperties(flagCloneProperties)
+		if err != nil {
+			return usageError(err)
+		}
+
+		snapshot, target := args[0], args[1]
+		s := newSnapshotter()
+		if err := s.Clone(context.Background(), snapshot, target, zfs.CloneOptions{
+			Properties:    properties,
+			CreateParents: flagCloneParents,
+		}); err != nil {
+			return fmt.Errorf("clone %s to %s: %w", snapshot, target, err)
+		}
+		return writeOutput(cloneResult{Origin: snapshot, Clone: target, Properties: properties}, cmd.OutOrStdout())
+	},
+}
+
+var promoteCmd = &cobra.Command{
+	Use:   "promote <clone-dataset>",
+	Short: "Make a clone independent of its origin",
+	Long: `Promote a clone so that it no longer depends on its origin snapshot.
+
+The origin snapshot and every snapshot before it move to the clone, and the
+former origin dataset becomes a clone of it. This allows the original
+dataset to be destroyed while keeping the clone.
+
+Examples:
+  zfssnap promote pool/db-test`,
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s := newSnapshotter()
+		if err := s.Promote(context.Background(), args[0]); err != nil {
+			return fmt.Errorf("promote %s: %w", args[0], err)
+		}
+		return writeOutput(promoteResult{Promoted: args[0]}, cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	cloneCmd.Flags().StringArrayVarP(&flagCloneProperties, "property", "o", nil, "Set a property on the clone (property=value, repeatable)")
+	cloneCmd.Flags().BoolVarP(&flagCloneParents, "parents", "p", false, "Create missing parent datasets")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jsirianni/zfssnap/testutil"
+	"github.com/jsirianni/zfssnap/zfs"
+)
+
+func TestCloneAndPromoteCommands(t *testing.T) {
+	ctx := context.Background()
+	sim := testutil.NewSimulator(testutil.NewClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+	if err := sim.CreateDataset("pool/db"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := sim.Create(ctx, "pool/db", "nightly", zfs.CreateOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	original := newSnapshotter
+	newSnapshotter = func() snapshotter { return sim }
+	t.Cleanup(func() {
+		newSnapshotter = original
+		flagCloneProperties, flagCloneParents = nil, false
+	})
+
+	var buf bytes.Buffer
+	flagCloneProperties = []string{"mountpoint=/srv/db-test"}
+	flagCloneParents = true
+	cloneCmd.SetOut(&buf)
+	if err := cloneCmd.RunE(cloneCmd, []string{"pool/db@nightly", "pool/test/db"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var cloned cloneResult
+	if err := json.Unmarshal(buf.Bytes(), &cloned); err != nil {
+		t.Fatalf("Invalid output %q: %v", buf.String(), err)
+	}
+	if cloned.Origin != "pool/db@nightly" || cloned.Clone != "pool/test/db" || cloned.Properties["mountpoint"] != "/srv/db-test" {
+		t.Errorf("Unexpected clone result: %+v", cloned)
+	}
+	if value, _ := sim.Property("pool/test/db", "mountpoint"); value != "/srv/db-test" {
+		t.Errorf("Expected property override on the clone, got %q", value)
+	}
+
+	if err := cloneCmd.RunE(cloneCmd, []string{"pool/db@nightly", "pool/test/db"}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected already exists error, got %v", err)
+	}
+
+	flagCloneProperties = []string{"novalue"}
+	if err := cloneCmd.RunE(cloneCmd, []string{"pool/db@nightly", "pool/test/db2"}); exitCode(err) != exitUsage {
+		t.Errorf("Expected exit code %d for an invalid property, got %d (%v)", exitUsage, exitCode(err), err)
+	}
+	flagCloneProperties = nil
+
+	buf.Reset()
+	promoteCmd.SetOut(&buf)
+	if err := promoteCmd.RunE(promoteCmd, []string{"pool/test/db"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !sim.Exists("pool/test/db@nightly") || sim.Exists("pool/db@nightly") {
+		t.Error("Expected the origin snapshot to move to the promoted clone")
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jsirianni/zfssnap/config"
+	"github.com/jsirianni/zfssnap/daemon"
+	"github.com/jsirianni/zfssnap/naming"
+	"github.com/spf13/cobra"
+)
+
+// configValidateResult is the JSON document printed by `config validate`.
+type configValidateResult struct {
+	File     string   `json:"file"`
+	Valid    bool     `json:"valid"`
+	Datasets int      `json:"datasets"`
+	Errors   []string `json:"errors"`
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration file",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Validate a configuration file without touching ZFS",
+	Long: `Validate a configuration file without touching ZFS.
+
+The file is taken from the argument or, if omitted, from --config. Every error
+is reported with its file, line and column.
+
+Examples:
+  zfssnap config validate /usr/local/etc/zfssnap.yaml
+  zfssnap --config /usr/local/etc/zfssnap.yaml config validate`,
+	Args: usageArgs(cobra.MaximumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := flagConfigPath
+		if len(args) == 1 {
+			path = args[0]
+		}
+		if path == "" {
+			return usageError(fmt.Errorf("config file is required"))
+		}
+
+		result := configValidateResult{File: path, Errors: []string{}}
+		cfg, err := config.Load(path)
+		if err != nil {
+			var cfgErrs config.Errors
+			if errors.As(err, &cfgErrs) {
+				for _, e := range cfgErrs {
+					result.Errors = append(result.Errors, e.Error())
+				}
+			} else {
+				result.Errors = append(result.Errors, err.Error())
+			}
+			if outErr := writeOutput(result, cmd.OutOrStdout()); outErr != nil {
+				return outErr
+			}
+			return fmt.Errorf("invalid config: %s", path)
+		}
+
+		result.Valid = true
+		result.Datasets = len(cfg.Datasets)
+		return writeOutput(result, cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+}
+
+// loadConfig loads the file given by --config, returning nil if the flag
+// was not set.
+func loadConfig() (*config.Config, error) {
+	if flagConfigPath == "" {
+		return nil, nil
+	}
+	return config.Load(flagConfigPath)
+}
+
+// configTemplate returns the naming template of the scheduled snapshots of
+// a dataset, with n.Name as its {label}.
+func configTemplate(n config.Naming) (*naming.Template, error) {
+	if n.Template != "" {
+		return naming.ParseTemplate(n.Template)
+	}
+	return naming.ApplyTemplate(naming.Options{Prefix: n.Prefix, Suffix: n.Suffix, Timestamp: true})
+}
+
+// configJobs returns a daemon job for every scheduled dataset in cfg.
+func configJobs(cfg *config.Config) []daemon.Job {
+	if cfg == nil {
+		return nil
+	}
+	var jobs []daemon.Job
+	for _, ds := range cfg.Datasets {
+		if ds.Schedule == "" {
+			continue
+		}
+		jobs = append(jobs, daemon.Job{
+			Dataset:   ds.Name,
+			Schedule:  ds.Schedule,
+			Name:      ds.Naming.Name,
+			Prefix:    ds.Naming.Prefix,
+			Suffix:    ds.Naming.Suffix,
+			Template:  ds.Naming.Template,
+			Recursive: ds.Recursive,
+			Exclude:   ds.Exclude,
+		})
+	}
+	return jobs
+}
+
+// configFreshnessChecks returns a daemon freshness check for every dataset
+// in cfg with a max_age.
+func configFreshnessChecks(cfg *config.Config) []daemon.FreshnessCheck {
+	if cfg == nil {
+		return nil
+	}
+	var checks []daemon.FreshnessCheck
+	for _, ds := range cfg.Datasets {
+		if ds.MaxAge == 0 {
+			continue
+		}
+		checks = append(checks, daemon.FreshnessCheck{Dataset: ds.Name, MaxAge: ds.MaxAge})
+	}
+	return checks
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigValidateCommand(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	if err := os.WriteFile(valid, []byte("datasets:\n  - name: pool/data\n    schedule: \"@hourly\"\n"), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("datasets:\n  - name: pool/data\n  - name: pool/data\n"), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() { flagConfigPath = "" })
+
+	run := func(args ...string) (configValidateResult, error) {
+		t.Helper()
+		var buf bytes.Buffer
+		configValidateCmd.SetOut(&buf)
+		err := configValidateCmd.RunE(configValidateCmd, args)
+		var result configValidateResult
+		if buf.Len() > 0 {
+			if jsonErr := json.Unmarshal(buf.Bytes(), &result); jsonErr != nil {
+				t.Fatalf("Invalid output %q: %v", buf.String(), jsonErr)
+			}
+		}
+		return result, err
+	}
+
+	result, err := run(valid)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !result.Valid || result.Datasets != 1 || len(result.Errors) != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	flagConfigPath = invalid
+	result, err = run()
+	if err == nil {
+		t.Error("Expected an error for an invalid file")
+	}
+	if result.Valid || result.File != invalid || len(result.Errors) != 1 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	flagConfigPath = ""
+	if _, err := run(); exitCode(err) != exitUsage {
+		t.Errorf("Expected exit code %d without a file, got %d (%v)", exitUsage, exitCode(err), err)
+	}
+}
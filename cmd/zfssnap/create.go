@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/jsirianni/zfssnap/naming"
 	"github.com/jsirianni/zfssnap/zfs"
 	"github.com/spf13/cobra"
 )
@@ -17,8 +19,108 @@ var (
 	flagPrefix    string
 	flagSuffix    string
 	flagTimestamp bool
+
+	flagNameTemplate string
+
+	flagProperties []string
+)
+
+// Statuses of a dataset in the create result.
+const (
+	createStatusCreated = "created"
+	createStatusDryRun  = "dry_run"
+	createStatusFailed  = "failed"
+)
+
+// Error codes of a dataset in the create result.
+const (
+	// createErrExists is a snapshot that already exists, without --force.
+	createErrExists = "exists"
+
+	// createErrNotFound is a dataset that does not exist.
+	createErrNotFound = "dataset_not_found"
+
+	// createErrDestroy is an existing snapshot that --force could not
+	// destroy.
+	createErrDestroy = "destroy_failed"
+
+	// createErrCreate is any other error creating the snapshot.
+	createErrCreate = "create_failed"
+
+	// createErrList is a recursive snapshot that was created, but whose
+	// descendent snapshots could not be listed.
+	createErrList = "list_failed"
 )
 
+// createResult is the result printed by the create command.
+type createResult struct {
+	DryRun bool `json:"dry_run"`
+
+	// Datasets holds one entry per dataset argument, in order.
+	Datasets []createDatasetResult `json:"datasets"`
+
+	// Created lists every snapshot created, including the descendent
+	// snapshots of recursive snapshots.
+	Created []string `json:"created"`
+
+	// Errors lists the error messages of the datasets.
+	Errors []string `json:"errors"`
+
+	// Count is the number of snapshots in Created.
+	Count int `json:"count"`
+
+	// Failed is the number of datasets that failed.
+	Failed int `json:"failed"`
+}
+
+// createDatasetResult is the outcome of snapshotting one dataset.
+type createDatasetResult struct {
+	Dataset string `json:"dataset"`
+
+	// Snapshot is the full name of the snapshot of the dataset.
+	Snapshot string `json:"snapshot"`
+
+	// Status is created, dry_run or failed.
+	Status string `json:"status"`
+
+	// Snapshots lists the snapshots created for the dataset, including
+	// descendent snapshots of a recursive snapshot.
+	Snapshots []string `json:"snapshots"`
+
+	// Replaced is set when --force destroyed an existing snapshot first.
+	Replaced bool `json:"replaced,omitempty"`
+
+	// ErrorCode classifies Error.
+	ErrorCode string `json:"error_code,omitempty"`
+
+	Error string `json:"error,omitempty"`
+
+	// Duration is the time spent on the dataset.
+	Duration float64 `json:"duration_seconds"`
+}
+
+// add records the outcome of a dataset.
+func (r *createResult) add(d createDatasetResult) {
+	if d.Snapshots == nil {
+		d.Snapshots = []string{}
+	}
+	r.Datasets = append(r.Datasets, d)
+	r.Created = append(r.Created, d.Snapshots...)
+	r.Count = len(r.Created)
+	if d.Error != "" {
+		r.Errors = append(r.Errors, d.Error)
+	}
+	if d.Status == createStatusFailed {
+		r.Failed++
+	}
+}
+
+// err returns the exit error of the result: exitAllFailed when every
+// dataset failed and exitPartialFailure when some did.
+func (r *createResult) err() error {
+	return failureError("create", "dataset", r.Failed, len(r.Datasets))
+}
+
 var createCmd = &cobra.Command{
 	Use:   "create [flags] <dataset> <snapshot-name>",
 	Short: "Create ZFS snapshots",
@@ -31,73 +133,136 @@ Examples:
   # Recursive snapshot with timestamp
   zfssnap create -r --timestamp pool/dataset daily
 
+  # Name from a template: auto_daily_2024-01-15_0300_001
+  zfssnap create --prefix auto --name-template '{prefix}_{label}_{date:%Y-%m-%d_%H%M}_{seq:3}' pool/dataset daily
+
   # Dry run to see what would be created
   zfssnap create --dry-run pool/dataset test-snapshot
 
   # With prefix and force overwrite
   zfssnap create --prefix manual --force pool/dataset backup
 
+  # Recursive snapshot with a user property
+  zfssnap create -r -o com.example:reason=deploy pool/dataset pre-deploy
+
   # Multiple datasets
   zfssnap create pool/dataset1 pool/dataset2 backup-2024-01-15`,
-	Args: cobra.MinimumNArgs(2),
-	RunE: func(_ *cobra.Command, args []string) error {
+	Args: usageArgs(cobra.MinimumNArgs(2)),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 2 {
-			return fmt.Errorf("at least dataset and snapshot name are required")
+			return usageError(fmt.Errorf("at least dataset and snapshot name are required"))
 		}
 
-		// Last argument is the snapshot name
+		// Last argument is the snapshot name, or the label of --name-template
 		snapshotName := args[len(args)-1]
 		datasets := args[:len(args)-1]
 
-		// Apply naming transformations
-		snapshotName = applyNamingTransformations(snapshotName)
+		var tmpl *naming.Template
+		if flagNameTemplate != "" {
+			if flagTimestamp {
+				return usageError(fmt.Errorf("--timestamp cannot be combined with --name-template"))
+			}
+			var err error
+			if tmpl, err = naming.ParseTemplate(flagNameTemplate); err != nil {
+				return usageError(err)
+			}
+		}
+		for _, dataset := range datasets {
+			if !zfs.IsValidDatasetName(dataset) {
+				return usageError(fmt.Errorf("invalid dataset name format: %s", dataset))
+			}
+		}
+		properties, err := parseProperties(flagProperties)
+		if err != nil {
+			return usageError(err)
+		}
+		opts := zfs.CreateOptions{
+			Recursive:  flagRecursive,
+			Properties: properties,
+		}
 
 		ctx := context.Background()
-		s := zfs.NewSnapshot(
-			zfs.WithZFSPath(flagZFSPath),
-			zfs.WithTimeout(flagTimeout),
-		)
+		s := newSnapshotter()
 
-		var createdSnapshots []string
-		var errors []string
+		// Apply naming transformations
+		if tmpl != nil {
+			if snapshotName, err = templateSnapshotName(ctx, s, tmpl, datasets, snapshotName); err != nil {
+				return err
+			}
+		} else {
+			snapshotName = applyNamingTransformations(snapshotName)
+		}
+		if !zfs.IsValidSnapshotComponent(snapshotName) {
+			return usageError(fmt.Errorf("invalid snapshot name: %s", snapshotName))
+		}
 
+		result := createResult{DryRun: flagDryRun, Created: []string{}, Errors: []string{}}
 		for _, dataset := range datasets {
-			fullSnapshotName := dataset + "@" + snapshotName
+			result.add(createSnapshot(ctx, s, dataset, snapshotName, opts))
+		}
+		if err := writeOutput(result, cmd.OutOrStdout()); err != nil {
+			return err
+		}
+		return result.err()
+	},
+}
 
-			if flagDryRun {
-				fmt.Printf("Would create snapshot: %s\n", fullSnapshotName)
-				createdSnapshots = append(createdSnapshots, fullSnapshotName)
-				continue
-			}
+// createSnapshot snapshots one dataset for the create command.
+func createSnapshot(ctx context.Context, s snapshotter, dataset, snapshotName string, opts zfs.CreateOptions) createDatasetResult {
+	start := time.Now()
+	result := createDatasetResult{
+		Dataset:  dataset,
+		Snapshot: dataset + "@" + snapshotName,
+		Status:   createStatusCreated,
+	}
+	fail := func(code string, err error) createDatasetResult {
+		result.Status = createStatusFailed
+		result.ErrorCode = code
+		result.Error = err.Error()
+		result.Duration = time.Since(start).Seconds()
+		return result
+	}
 
-			err := s.Create(ctx, dataset, snapshotName)
-			if err != nil {
-				if flagForce && strings.Contains(err.Error(), "already exists") {
-					// Force mode: try to destroy existing snapshot first
-					destroyErr := s.Delete(ctx, fullSnapshotName)
-					if destroyErr != nil {
-						errors = append(errors, fmt.Sprintf("failed to destroy existing snapshot %s: %v", fullSnapshotName, destroyErr))
-						continue
-					}
-
-					// Retry creation
-					err = s.Create(ctx, dataset, snapshotName)
-					if err != nil {
-						errors = append(errors, fmt.Sprintf("failed to create snapshot %s: %v", fullSnapshotName, err))
-						continue
-					}
-				} else {
-					errors = append(errors, fmt.Sprintf("failed to create snapshot %s: %v", fullSnapshotName, err))
-					continue
-				}
-			}
+	if flagDryRun {
+		result.Status = createStatusDryRun
+		result.Snapshots = []string{result.Snapshot}
+		result.Duration = time.Since(start).Seconds()
+		return result
+	}
 
-			createdSnapshots = append(createdSnapshots, fullSnapshotName)
+	err := s.Create(ctx, dataset, snapshotName, opts)
+	if errors.Is(err, zfs.ErrSnapshotExists) {
+		if !flagForce {
+			return fail(createErrExists, fmt.Errorf("create snapshot %s: %w", result.Snapshot, err))
+		}
+		// Force mode: destroy the existing snapshot and retry
+		if _, err := s.Delete(ctx, result.Snapshot, zfs.DeleteOptions{Recursive: opts.Recursive}); err != nil {
+			return fail(createErrDestroy, fmt.Errorf("destroy existing snapshot %s: %w", result.Snapshot, err))
 		}
+		result.Replaced = true
+		err = s.Create(ctx, dataset, snapshotName, opts)
+	}
+	if errors.Is(err, zfs.ErrDatasetNotFound) {
+		return fail(createErrNotFound, fmt.Errorf("create snapshot %s: %w", result.Snapshot, err))
+	}
+	if err != nil {
+		return fail(createErrCreate, fmt.Errorf("create snapshot %s: %w", result.Snapshot, err))
+	}
 
-		// Output results
-		return outputCreateResultsJSON(createdSnapshots, errors)
-	},
+	result.Snapshots = []string{result.Snapshot}
+	if opts.Recursive {
+		// A recursive snapshot is atomic but zfs does not report which
+		// children it covered, so discover them after the fact.
+		names, err := s.List(ctx, zfs.ListOptions{Dataset: dataset, Recursive: true})
+		if err != nil {
+			result.ErrorCode = createErrList
+			result.Error = fmt.Sprintf("list snapshots created under %s: %v", dataset, err)
+		} else {
+			result.Snapshots = filterSnapshotsByName(names, snapshotName)
+		}
+	}
+	result.Duration = time.Since(start).Seconds()
+	return result
 }
 
 func init() {
@@ -107,34 +272,65 @@ func init() {
 	createCmd.Flags().StringVar(&flagPrefix, "prefix", "", "Add prefix to snapshot name")
 	createCmd.Flags().StringVar(&flagSuffix, "suffix", "", "Add suffix to snapshot name")
 	createCmd.Flags().BoolVar(&flagTimestamp, "timestamp", false, "Auto-add timestamp to snapshot name")
+	createCmd.Flags().StringVar(&flagNameTemplate, "name-template", "", "Build the snapshot name from a naming template, with the snapshot name argument as {label}")
+	createCmd.Flags().StringArrayVarP(&flagProperties, "property", "o", nil, "Set a property on the snapshot (property=value, repeatable)")
 }
 
-func applyNamingTransformations(snapshotName string) string {
-	// Apply prefix
-	if flagPrefix != "" {
-		snapshotName = flagPrefix + "-" + snapshotName
+// parseProperties parses property=value pairs as accepted by `zfs -o`.
+func parseProperties(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
 	}
-
-	// Apply suffix
-	if flagSuffix != "" {
-		snapshotName = snapshotName + "-" + flagSuffix
+	props := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid property %q: expected property=value", pair)
+		}
+		props[key] = value
 	}
+	return props, nil
+}
 
-	// Apply timestamp
-	if flagTimestamp {
-		timestamp := time.Now().Format("20060102-150405")
-		snapshotName = snapshotName + "-" + timestamp
+// filterSnapshotsByName returns the snapshots whose snapshot component
+// equals snapshotName, preserving order.
+func filterSnapshotsByName(names []string, snapshotName string) []string {
+	suffix := "@" + snapshotName
+	var matched []string
+	for _, name := range names {
+		if strings.HasSuffix(name, suffix) {
+			matched = append(matched, name)
+		}
 	}
+	return matched
+}
 
-	return snapshotName
+func applyNamingTransformations(snapshotName string) string {
+	return naming.Apply(snapshotName, naming.Options{
+		Prefix:    flagPrefix,
+		Suffix:    flagSuffix,
+		Timestamp: flagTimestamp,
+	}, time.Now())
 }
 
-func outputCreateResultsJSON(createdSnapshots []string, errors []string) error {
-	// Use the existing JSON output mechanism
-	// Always output JSON format
-	fmt.Printf("{\"created\":%q,\"errors\":%q,\"count\":%d}\n",
-		strings.Join(createdSnapshots, ","),
-		strings.Join(errors, ","),
-		len(createdSnapshots))
-	return nil
+// templateSnapshotName returns the snapshot name built by tmpl for label
+// and the --prefix and --suffix flags. A sequence number follows the
+// highest one among the snapshots of all datasets, so they share the name.
+func templateSnapshotName(ctx context.Context, s snapshotter, tmpl *naming.Template, datasets []string, label string) (string, error) {
+	hostname, err := naming.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("get hostname: %w", err)
+	}
+	v := naming.Values{Prefix: flagPrefix, Suffix: flagSuffix, Label: label, Hostname: hostname, Time: time.Now()}
+	if tmpl.HasSequence() {
+		for _, dataset := range datasets {
+			names, err := s.List(ctx, zfs.ListOptions{Dataset: dataset, Recursive: flagRecursive})
+			if err != nil {
+				return "", fmt.Errorf("list snapshots of %s: %w", dataset, err)
+			}
+			v.Sequence = max(v.Sequence, tmpl.NextSequence(names, v))
+		}
+	}
+	return tmpl.Format(v), nil
 }
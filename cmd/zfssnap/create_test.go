@@ -1,9 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/jsirianni/zfssnap/testutil"
 	"github.com/jsirianni/zfssnap/zfs"
 )
 
@@ -178,3 +183,190 @@ func TestApplyNamingTransformations(t *testing.T) {
 		})
 	}
 }
+
+func TestParseProperties(t *testing.T) {
+	tests := []struct {
+		name        string
+		pairs       []string
+		expected    map[string]string
+		expectError bool
+	}{
+		{
+			name:     "no properties",
+			pairs:    nil,
+			expected: nil,
+		},
+		{
+			name:     "user properties",
+			pairs:    []string{"com.example:reason=deploy", "com.example:ticket=OPS-1"},
+			expected: map[string]string{"com.example:reason": "deploy", "com.example:ticket": "OPS-1"},
+		},
+		{
+			name:     "value containing equals",
+			pairs:    []string{"com.example:query=a=b"},
+			expected: map[string]string{"com.example:query": "a=b"},
+		},
+		{
+			name:        "missing equals",
+			pairs:       []string{"com.example:reason"},
+			expectError: true,
+		},
+		{
+			name:        "empty key",
+			pairs:       []string{"=value"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			props, err := parseProperties(tt.pairs)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(props) != len(tt.expected) {
+				t.Fatalf("Expected %d properties, got %d", len(tt.expected), len(props))
+			}
+			for k, v := range tt.expected {
+				if props[k] != v {
+					t.Errorf("Expected %s=%q, got %q", k, v, props[k])
+				}
+			}
+		})
+	}
+}
+
+func TestFilterSnapshotsByName(t *testing.T) {
+	names := []string{
+		"pool/dataset@daily",
+		"pool/dataset@daily-old",
+		"pool/dataset/child@daily",
+		"pool/dataset/child@weekly",
+		"pool/dataset/child/grandchild@daily",
+	}
+
+	result := filterSnapshotsByName(names, "daily")
+	expected := []string{
+		"pool/dataset@daily",
+		"pool/dataset/child@daily",
+		"pool/dataset/child/grandchild@daily",
+	}
+	if strings.Join(result, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+func TestCreateResult(t *testing.T) {
+	ctx := context.Background()
+	sim := testutil.NewSimulator(testutil.NewClock(time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)))
+	for _, ds := range []string{"pool", "pool/data", "pool/data/child", "pool/home"} {
+		if err := sim.CreateDataset(ds); err != nil {
+			t.Fatalf("CreateDataset: %v", err)
+		}
+	}
+	if err := sim.Create(ctx, "pool/home", "existing", zfs.CreateOptions{}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	original := newSnapshotter
+	newSnapshotter = func() snapshotter { return sim }
+	t.Cleanup(func() { newSnapshotter = original })
+
+	run := func(args ...string) (createResult, error) {
+		t.Helper()
+		t.Cleanup(func() { resetFlags(createCmd) })
+		resetFlags(createCmd)
+
+		var buf bytes.Buffer
+		rootCmd.SetOut(&buf)
+		rootCmd.SetArgs(append([]string{"create"}, args...))
+		err := rootCmd.Execute()
+
+		var result createResult
+		if buf.Len() > 0 {
+			if jsonErr := json.Unmarshal(buf.Bytes(), &result); jsonErr != nil {
+				t.Fatalf("Invalid output %q: %v", buf.String(), jsonErr)
+			}
+		}
+		return result, err
+	}
+
+	// Success
+	result, err := run("-r", "pool/data", "pool/home", "daily")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Count != 3 || result.Failed != 0 || len(result.Datasets) != 2 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	if d := result.Datasets[0]; d.Dataset != "pool/data" || d.Snapshot != "pool/data@daily" || d.Status != createStatusCreated ||
+		strings.Join(d.Snapshots, ",") != "pool/data@daily,pool/data/child@daily" {
+		t.Errorf("Unexpected dataset result: %+v", d)
+	}
+
+	// Partial failure
+	result, err = run("pool/data", "pool/home", "existing")
+	if code := exitCode(err); code != exitPartialFailure {
+		t.Errorf("Expected exit code %d, got %d (%v)", exitPartialFailure, code, err)
+	}
+	if result.Failed != 1 || result.Count != 1 || len(result.Errors) != 1 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if d := result.Datasets[1]; d.Status != createStatusFailed || d.ErrorCode != createErrExists || d.Error == "" || len(d.Snapshots) != 0 {
+		t.Errorf("Unexpected dataset result: %+v", d)
+	}
+
+	// Force replaces the existing snapshot
+	result, err = run("--force", "pool/home", "existing")
+	if err != nil || !result.Datasets[0].Replaced {
+		t.Errorf("Expected the snapshot to be replaced, got %+v, %v", result, err)
+	}
+
+	// Total failure
+	result, err = run("pool/data", "pool/missing", "daily")
+	if code := exitCode(err); code != exitAllFailed {
+		t.Errorf("Expected exit code %d, got %d (%v)", exitAllFailed, code, err)
+	}
+	if result.Failed != 2 || result.Count != 0 || result.Datasets[1].ErrorCode != createErrNotFound {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	// Dry run
+	result, err = run("--dry-run", "pool/data", "planned")
+	if err != nil || !result.DryRun || result.Datasets[0].Status != createStatusDryRun || sim.Exists("pool/data@planned") {
+		t.Errorf("Unexpected dry run: %+v, %v", result, err)
+	}
+
+	// A naming template numbers snapshots across datasets
+	for _, expected := range []string{"auto_nightly_001", "auto_nightly_002"} {
+		result, err = run("--prefix", "auto", "--name-template", "{prefix}_{label}_{seq:3}", "pool/data", "pool/home", "nightly")
+		if err != nil || result.Datasets[0].Snapshot != "pool/data@"+expected || result.Datasets[1].Snapshot != "pool/home@"+expected {
+			t.Errorf("Expected %s, got %+v, %v", expected, result, err)
+		}
+	}
+
+	// Validation errors change nothing
+	for _, args := range [][]string{
+		{"--name-template", "{label", "pool/data", "daily"},
+		{"--name-template", "{label}", "--timestamp", "pool/data", "daily"},
+		{"123pool", "daily"},
+		{"pool/data", "123daily"},
+		{"pool/data", "-o", "novalue", "daily"},
+		{"pool/data"},
+		{"--no-such-flag", "pool/data", "daily"},
+	} {
+		result, err := run(args...)
+		if code := exitCode(err); code != exitUsage {
+			t.Errorf("create %v: expected exit code %d, got %d (%v)", args, exitUsage, code, err)
+		}
+		if result.Datasets != nil {
+			t.Errorf("create %v: expected no output, got %+v", args, result)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,6 +18,27 @@ import (
 
 var (
 	daemonAddr string
+
+	flagSchedules         []string
+	flagScheduleName      string
+	flagSchedulePrefix    string
+	flagScheduleSuffix    string
+	flagScheduleRecursive bool
+
+	flagMetricsInclude []string
+	flagMetricsExclude []string
+	flagMaxAges        []string
+
+	flagOTLPEndpoint string
+	flagOTLPHeaders  []string
+	flagOTLPInterval time.Duration
+
+	flagAPIAllowMutations bool
+
+	flagTLSCert       string
+	flagTLSKey        string
+	flagTLSClientCA   string
+	flagAuthTokenFile string
 )
 
 var daemonCmd = &cobra.Command{
@@ -24,8 +46,64 @@ var daemonCmd = &cobra.Command{
 	Short: "Start the ZFS snapshot daemon with metrics",
 	Long: `Start the ZFS snapshot daemon with OpenTelemetry metrics.
 
-The daemon provides Prometheus metrics at /metrics endpoint and automatically
-collects ZFS snapshot counts using OpenTelemetry callbacks.`,
+The daemon lists snapshots every 30 seconds and reports them with
+OpenTelemetry observable instruments. The metrics are served in the
+Prometheus format at /metrics and, with --otlp-endpoint, are also pushed to
+an OpenTelemetry collector with OTLP over HTTP.
+
+The daemon can also snapshot datasets on a schedule. Schedules are given as
+<dataset>=<schedule> where the schedule is a five field cron expression,
+@hourly, @daily, @weekly, @monthly, @yearly or "@every <duration>".
+Snapshots missed while the daemon was stopped are taken once on startup.
+Scheduled datasets are also read from the file given by --config.
+
+Snapshot count, space and age metrics are exported for every dataset with
+snapshots. Use --metrics-include and --metrics-exclude to limit them to some
+datasets; a pattern is a dataset name or glob and also matches descendents.
+
+/health reports the result of the last snapshot listing and, for every
+dataset given a maximum age with --max-age or max_age in the config file,
+whether its newest snapshot is recent enough. It responds with 503 when the
+listing failed or any dataset is stale.
+
+Snapshots can be listed and read with the JSON API under /api/v1/snapshots.
+The endpoints that create, destroy, hold and release snapshots are disabled
+unless --api-allow-mutations is given.
+
+With --tls-cert and --tls-key the daemon serves HTTPS, and with
+--tls-client-ca it only accepts clients presenting a certificate signed by
+one of the given CAs. With --auth-token-file every endpoint except /health
+requires a bearer token with the matching scope: metrics for /metrics, read
+for reading snapshots and write for changing them. Each line of the token
+file holds a name, comma separated scopes and the token:
+
+  grafana  metrics     3f9a6c0e2b8d4f71a5c9
+  deploy   read,write  8c1be4d07f2a9356b1e0
+
+The certificate, key, client CA and token files are read again on SIGHUP.
+
+Examples:
+  # Metrics only
+  zfssnap daemon
+
+  # Hourly snapshots of one dataset and daily snapshots of another
+  zfssnap daemon --schedule 'pool/data=@hourly' --schedule 'pool/home=0 2 * * *'
+
+  # Per-dataset metrics for pool/data only, without its tmp dataset
+  zfssnap daemon --metrics-include pool/data --metrics-exclude pool/data/tmp
+
+  # Report unhealthy when pool/data has no snapshot from the last 2 hours
+  zfssnap daemon --schedule 'pool/data=@hourly' --max-age pool/data=2h
+
+  # Also push the metrics to a collector every 30 seconds
+  zfssnap daemon --otlp-endpoint http://collector:4318 --otlp-interval 30s
+
+  # Allow pre-deploy snapshots through the API
+  zfssnap daemon --api-allow-mutations
+
+  # Serve HTTPS on all interfaces and require bearer tokens
+  zfssnap daemon --addr :9464 --tls-cert server.crt --tls-key server.key \
+    --auth-token-file /usr/local/etc/zfssnap/tokens`,
 	RunE: func(_ *cobra.Command, _ []string) error {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -40,8 +118,57 @@ collects ZFS snapshot counts using OpenTelemetry callbacks.`,
 		}
 		defer zapLogger.Sync()
 
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		jobs, err := parseScheduleFlags(flagSchedules)
+		if err != nil {
+			return err
+		}
+		jobs = append(configJobs(cfg), jobs...)
+		checks, err := parseMaxAgeFlags(flagMaxAges)
+		if err != nil {
+			return err
+		}
+		checks = mergeFreshnessChecks(configFreshnessChecks(cfg), checks)
+
+		opts := []daemon.Option{
+			daemon.WithSnapshotter(newSnapshotter()),
+			daemon.WithJobs(jobs...),
+			daemon.WithDatasetFilter(daemon.DatasetFilter{
+				Include: flagMetricsInclude,
+				Exclude: flagMetricsExclude,
+			}),
+			daemon.WithFreshnessChecks(checks...),
+			daemon.WithAPIMutations(flagAPIAllowMutations),
+		}
+		if flagTLSCert != "" || flagTLSKey != "" {
+			opts = append(opts, daemon.WithTLS(daemon.TLSConfig{
+				CertFile:     flagTLSCert,
+				KeyFile:      flagTLSKey,
+				ClientCAFile: flagTLSClientCA,
+			}))
+		} else if flagTLSClientCA != "" {
+			return fmt.Errorf("--tls-client-ca requires --tls-cert and --tls-key")
+		}
+		if flagAuthTokenFile != "" {
+			opts = append(opts, daemon.WithTokenFile(flagAuthTokenFile))
+		}
+		if flagOTLPEndpoint != "" {
+			headers, err := parseOTLPHeaders(flagOTLPHeaders)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, daemon.WithOTLP(daemon.OTLPConfig{
+				Endpoint: flagOTLPEndpoint,
+				Headers:  headers,
+				Interval: flagOTLPInterval,
+			}))
+		}
+
 		// Create daemon instance
-		d, err := daemon.New(ctx, "zfssnap-daemon", version.Version(), zapLogger)
+		d, err := daemon.New(ctx, "zfssnap-daemon", version.Version(), zapLogger, opts...)
 		if err != nil {
 			return fmt.Errorf("create daemon: %w", err)
 		}
@@ -53,15 +180,28 @@ collects ZFS snapshot counts using OpenTelemetry callbacks.`,
 
 		zapLogger.Info("daemon started successfully", zap.String("addr", daemonAddr))
 
-		// Wait for interrupt signal
+		// Reload certificates and tokens on SIGHUP until interrupted
 		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
-		select {
-		case sig := <-sigChan:
-			zapLogger.Info("received signal, shutting down", zap.String("signal", sig.String()))
-		case <-ctx.Done():
-			zapLogger.Info("context cancelled, shutting down")
+	wait:
+		for {
+			select {
+			case sig := <-sigChan:
+				if sig == syscall.SIGHUP {
+					if err := d.Reload(); err != nil {
+						zapLogger.Error("reload failed, keeping previous certificates and tokens", zap.Error(err))
+					} else {
+						zapLogger.Info("reloaded certificates and tokens")
+					}
+					continue
+				}
+				zapLogger.Info("received signal, shutting down", zap.String("signal", sig.String()))
+				break wait
+			case <-ctx.Done():
+				zapLogger.Info("context cancelled, shutting down")
+				break wait
+			}
 		}
 
 		// Graceful shutdown
@@ -80,5 +220,87 @@ collects ZFS snapshot counts using OpenTelemetry callbacks.`,
 
 func init() {
 	daemonCmd.Flags().StringVarP(&daemonAddr, "addr", "a", "localhost:9464", "Address to bind the metrics server")
+	daemonCmd.Flags().StringArrayVar(&flagSchedules, "schedule", nil, "Snapshot a dataset on a schedule (<dataset>=<schedule>, repeatable)")
+	daemonCmd.Flags().StringVar(&flagScheduleName, "schedule-name", "auto", "Base name of scheduled snapshots")
+	daemonCmd.Flags().StringVar(&flagSchedulePrefix, "schedule-prefix", "", "Add prefix to scheduled snapshot names")
+	daemonCmd.Flags().StringVar(&flagScheduleSuffix, "schedule-suffix", "", "Add suffix to scheduled snapshot names")
+	daemonCmd.Flags().BoolVar(&flagScheduleRecursive, "schedule-recursive", false, "Take scheduled snapshots recursively")
+	daemonCmd.Flags().StringArrayVar(&flagMetricsInclude, "metrics-include", nil, "Only export per-dataset metrics for datasets matching this pattern (repeatable)")
+	daemonCmd.Flags().StringArrayVar(&flagMetricsExclude, "metrics-exclude", nil, "Do not export per-dataset metrics for datasets matching this pattern (repeatable)")
+	daemonCmd.Flags().StringArrayVar(&flagMaxAges, "max-age", nil, "Report unhealthy when the newest snapshot of a dataset is older than this (<dataset>=<duration>, repeatable)")
+	daemonCmd.Flags().StringVar(&flagOTLPEndpoint, "otlp-endpoint", "", "Also export metrics to this OTLP/HTTP collector URL, e.g. http://localhost:4318")
+	daemonCmd.Flags().StringArrayVar(&flagOTLPHeaders, "otlp-header", nil, "Header sent with OTLP exports (<name>=<value>, repeatable)")
+	daemonCmd.Flags().DurationVar(&flagOTLPInterval, "otlp-interval", daemon.DefaultOTLPInterval, "Interval between OTLP exports")
+	daemonCmd.Flags().BoolVar(&flagAPIAllowMutations, "api-allow-mutations", false, "Enable the API endpoints that create, destroy, hold and release snapshots")
+	daemonCmd.Flags().StringVar(&flagTLSCert, "tls-cert", "", "Serve HTTPS with this PEM certificate chain file")
+	daemonCmd.Flags().StringVar(&flagTLSKey, "tls-key", "", "Private key file of --tls-cert")
+	daemonCmd.Flags().StringVar(&flagTLSClientCA, "tls-client-ca", "", "Require client certificates signed by a CA in this PEM file")
+	daemonCmd.Flags().StringVar(&flagAuthTokenFile, "auth-token-file", "", "Require bearer tokens from this file on every endpoint except /health")
 	rootCmd.AddCommand(daemonCmd)
 }
+
+// parseScheduleFlags converts <dataset>=<schedule> pairs into daemon jobs.
+func parseScheduleFlags(specs []string) ([]daemon.Job, error) {
+	jobs := make([]daemon.Job, 0, len(specs))
+	for _, spec := range specs {
+		dataset, sched, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid schedule %q: expected <dataset>=<schedule>", spec)
+		}
+		jobs = append(jobs, daemon.Job{
+			Dataset:   strings.TrimSpace(dataset),
+			Schedule:  strings.TrimSpace(sched),
+			Name:      flagScheduleName,
+			Prefix:    flagSchedulePrefix,
+			Suffix:    flagScheduleSuffix,
+			Recursive: flagScheduleRecursive,
+		})
+	}
+	return jobs, nil
+}
+
+// parseMaxAgeFlags converts <dataset>=<duration> pairs into freshness checks.
+func parseMaxAgeFlags(specs []string) ([]daemon.FreshnessCheck, error) {
+	checks := make([]daemon.FreshnessCheck, 0, len(specs))
+	for _, spec := range specs {
+		dataset, age, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid max age %q: expected <dataset>=<duration>", spec)
+		}
+		maxAge, err := time.ParseDuration(strings.TrimSpace(age))
+		if err != nil {
+			return nil, fmt.Errorf("invalid max age %q: %w", spec, err)
+		}
+		checks = append(checks, daemon.FreshnessCheck{Dataset: strings.TrimSpace(dataset), MaxAge: maxAge})
+	}
+	return checks, nil
+}
+
+// mergeFreshnessChecks returns the checks of base, replaced by those of
+// overrides for the same dataset, followed by the remaining overrides.
+func mergeFreshnessChecks(base, overrides []daemon.FreshnessCheck) []daemon.FreshnessCheck {
+	index := make(map[string]int)
+	merged := make([]daemon.FreshnessCheck, 0, len(base)+len(overrides))
+	for _, c := range append(append([]daemon.FreshnessCheck{}, base...), overrides...) {
+		if i, ok := index[c.Dataset]; ok {
+			merged[i] = c
+			continue
+		}
+		index[c.Dataset] = len(merged)
+		merged = append(merged, c)
+	}
+	return merged
+}
+
+// parseOTLPHeaders converts <name>=<value> pairs into OTLP export headers.
+func parseOTLPHeaders(specs []string) (map[string]string, error) {
+	headers := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		name, value, ok := strings.Cut(spec, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid OTLP header %q: expected <name>=<value>", spec)
+		}
+		headers[strings.TrimSpace(name)] = value
+	}
+	return headers, nil
+}
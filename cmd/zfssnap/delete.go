@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jsirianni/zfssnap/zfs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagDeleteRecursive bool
+	flagDeleteDefer     bool
+	flagDeleteDryRun    bool
+)
+
+// deleteResult is the result printed by the delete command.
+type deleteResult struct {
+	// Destroyed lists every snapshot destroyed, or that would be destroyed
+	// for a dry run, including those of child datasets.
+	Destroyed []string `json:"destroyed"`
+
+	// Errors lists the error messages of the snapshots that could not be
+	// destroyed.
+	Errors []string `json:"errors"`
+
+	Count     int               `json:"count"`
+	Reclaimed uint64            `json:"reclaimed"`
+	Skipped   []skippedSnapshot `json:"skipped"`
+}
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete [flags] <snapshot...>",
+	Short: "Destroy ZFS snapshots",
+	Long: `Destroy the specified ZFS snapshots.
+
+Snapshots with user holds are skipped and reported with their hold tags,
+unless --defer is given.
+
+Examples:
+  # Destroy a single snapshot
+  zfssnap delete pool/dataset@backup-2024-01-15
+
+  # Destroy a snapshot in the dataset and all of its children
+  zfssnap delete -r pool/dataset@daily
+
+  # Mark a held or cloned snapshot for deferred destruction
+  zfssnap delete --defer pool/dataset@backup
+
+  # Report what would be destroyed and the space that would be reclaimed
+  zfssnap delete --dry-run pool/dataset@backup`,
+	Args: usageArgs(cobra.MinimumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		s := newSnapshotter()
+
+		opts := zfs.DeleteOptions{
+			Recursive: flagDeleteRecursive,
+			Defer:     flagDeleteDefer,
+			DryRun:    flagDeleteDryRun,
+		}
+
+		result := deleteResult{Destroyed: []string{}, Errors: []string{}, Skipped: []skippedSnapshot{}}
+		for _, name := range args {
+			// zfs refuses to destroy held snapshots unless the destruction is
+			// deferred, so report them with their tags instead of failing.
+			if !opts.Defer {
+				held, err := heldSnapshots(ctx, s, name, opts.Recursive)
+				if err != nil {
+					result.Errors = append(result.Errors, fmt.Sprintf("destroy snapshot %s: %v", name, err))
+					continue
+				}
+				if len(held) > 0 {
+					result.Skipped = append(result.Skipped, held...)
+					continue
+				}
+			}
+
+			destroyed, err := s.Delete(ctx, name, opts)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("destroy snapshot %s: %v", name, err))
+				continue
+			}
+			result.Destroyed = append(result.Destroyed, destroyed.Destroyed...)
+			result.Reclaimed += destroyed.Reclaimed
+		}
+		result.Count = len(result.Destroyed)
+
+		if err := writeOutput(result, cmd.OutOrStdout()); err != nil {
+			return err
+		}
+		return failureError("delete", "snapshot", len(result.Errors), len(args))
+	},
+}
+
+func init() {
+	deleteCmd.Flags().BoolVarP(&flagDeleteRecursive, "recursive", "r", false, "Destroy the snapshot in all child datasets")
+	deleteCmd.Flags().BoolVar(&flagDeleteDefer, "defer", false, "Defer destruction of held or cloned snapshots")
+	deleteCmd.Flags().BoolVar(&flagDeleteDryRun, "dry-run", false, "Show what would be destroyed without actually destroying")
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jsirianni/zfssnap/testutil"
+	"github.com/jsirianni/zfssnap/zfs"
+)
+
+func TestDeleteCommand(t *testing.T) {
+	ctx := context.Background()
+	sim := testutil.NewSimulator(testutil.NewClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+	if err := sim.CreateDataset("pool/data/child"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, name := range []string{"daily", "weekly", "held"} {
+		if err := sim.Create(ctx, "pool/data", name, zfs.CreateOptions{Recursive: true}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := sim.Hold(ctx, "pool/data@held", "keep", zfs.HoldOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	original := newSnapshotter
+	newSnapshotter = func() snapshotter { return sim }
+	t.Cleanup(func() {
+		newSnapshotter = original
+		flagDeleteRecursive, flagDeleteDefer, flagDeleteDryRun = false, false, false
+	})
+
+	run := func(args ...string) (deleteResult, error) {
+		t.Helper()
+		var buf bytes.Buffer
+		deleteCmd.SetOut(&buf)
+		err := deleteCmd.RunE(deleteCmd, args)
+		var result deleteResult
+		if jsonErr := json.Unmarshal(buf.Bytes(), &result); jsonErr != nil {
+			t.Fatalf("Invalid output %q: %v", buf.String(), jsonErr)
+		}
+		return result, err
+	}
+
+	// Held snapshots are skipped, not failed
+	flagDeleteRecursive = true
+	result, err := run("pool/data@daily", "pool/data@weekly", "pool/data@held")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Count != 4 || len(result.Destroyed) != 4 || result.Destroyed[2] != "pool/data@weekly" || len(result.Errors) != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].Snapshot != "pool/data@held" {
+		t.Errorf("Expected the held snapshot to be skipped, got %+v", result.Skipped)
+	}
+
+	// Partial failure
+	flagDeleteRecursive = false
+	result, err = run("pool/data/child@held", "pool/data@missing")
+	if code := exitCode(err); code != exitPartialFailure {
+		t.Errorf("Expected exit code %d, got %d (%v)", exitPartialFailure, code, err)
+	}
+	if len(result.Destroyed) != 1 || len(result.Errors) != 1 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	// Total failure
+	result, err = run("pool/data@missing")
+	if code := exitCode(err); code != exitAllFailed {
+		t.Errorf("Expected exit code %d, got %d (%v)", exitAllFailed, code, err)
+	}
+	if len(result.Destroyed) != 0 || len(result.Errors) != 1 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
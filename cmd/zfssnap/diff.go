@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jsirianni/zfssnap/model"
+	"github.com/spf13/cobra"
+)
+
+var flagDiffSummary bool
+
+// diffResult is the JSON document printed by the diff command.
+type diffResult struct {
+	From    string          `json:"from"`
+	To      string          `json:"to"`
+	Changes []*model.Change `json:"changes"`
+}
+
+// diffSummary is the JSON document printed by `diff --summary`.
+type diffSummary struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Added    int    `json:"added"`
+	Removed  int    `json:"removed"`
+	Modified int    `json:"modified"`
+	Renamed  int    `json:"renamed"`
+	Total    int    `json:"total"`
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [flags] <snapshot> [snapshot|dataset]",
+	Short: "Show the file changes between snapshots",
+	Long: `Show the files that were added, removed, modified or renamed between a
+snapshot and a later snapshot of the same dataset, or the live dataset when
+the second argument is omitted.
+
+Each change includes the file type and the change time of the file. zfs diff
+needs the diff permission on the dataset (zfs allow) or root.
+
+Examples:
+  # What changed since last night
+  zfssnap diff pool/dataset@daily-20250101
+
+  # Changes between two snapshots
+  zfssnap diff pool/dataset@daily-20250101 pool/dataset@daily-20250102
+
+  # Count the changes by kind
+  zfssnap diff --summary pool/dataset@daily-20250101`,
+	Args: usageArgs(cobra.RangeArgs(1, 2)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from := args[0]
+		to := ""
+		if len(args) == 2 {
+			to = args[1]
+		}
+
+		changes, err := newSnapshotter().Diff(context.Background(), from, to)
+		if err != nil {
+			return fmt.Errorf("diff %s: %w", from, err)
+		}
+
+		// Report the live dataset by name when comparing against it.
+		if to == "" {
+			to, _, _ = strings.Cut(from, "@")
+		}
+		if flagDiffSummary {
+			return writeOutput(summarizeChanges(from, to, changes), cmd.OutOrStdout())
+		}
+		return writeOutput(diffResult{From: from, To: to, Changes: changes}, cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	diffCmd.Flags().BoolVar(&flagDiffSummary, "summary", false, "Print the number of changes of each kind instead of the changes")
+}
+
+// summarizeChanges counts changes by kind.
+func summarizeChanges(from, to string, changes []*model.Change) diffSummary {
+	summary := diffSummary{From: from, To: to, Total: len(changes)}
+	for _, c := range changes {
+		switch c.Type {
+		case model.ChangeAdded:
+			summary.Added++
+		case model.ChangeRemoved:
+			summary.Removed++
+		case model.ChangeModified:
+			summary.Modified++
+		case model.ChangeRenamed:
+			summary.Renamed++
+		}
+	}
+	return summary
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+	"github.com/jsirianni/zfssnap/testutil"
+	"github.com/jsirianni/zfssnap/zfs"
+)
+
+func TestDiffCommand(t *testing.T) {
+	ctx := context.Background()
+	sim := testutil.NewSimulator(testutil.NewClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+	if err := sim.CreateDataset("pool/data"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := sim.Create(ctx, "pool/data", "a", zfs.CreateOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := sim.Change("pool/data",
+		&model.Change{Type: model.ChangeAdded, FileType: model.FileTypeFile, Path: "/pool/data/a"},
+		&model.Change{Type: model.ChangeAdded, FileType: model.FileTypeFile, Path: "/pool/data/b"},
+		&model.Change{Type: model.ChangeRenamed, FileType: model.FileTypeFile, Path: "/pool/data/c", NewPath: "/pool/data/d"},
+	); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := sim.Create(ctx, "pool/data", "b", zfs.CreateOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	original := newSnapshotter
+	newSnapshotter = func() snapshotter { return sim }
+	t.Cleanup(func() {
+		newSnapshotter = original
+		flagDiffSummary = false
+	})
+
+	var buf bytes.Buffer
+	diffCmd.SetOut(&buf)
+	if err := diffCmd.RunE(diffCmd, []string{"pool/data@a"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var result diffResult
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("Invalid output %q: %v", buf.String(), err)
+	}
+	if result.To != "pool/data" || len(result.Changes) != 3 || result.Changes[2].NewPath != "/pool/data/d" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	buf.Reset()
+	flagDiffSummary = true
+	if err := diffCmd.RunE(diffCmd, []string{"pool/data@a", "pool/data@b"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var summary diffSummary
+	if err := json.Unmarshal(buf.Bytes(), &summary); err != nil {
+		t.Fatalf("Invalid output %q: %v", buf.String(), err)
+	}
+	expected := diffSummary{From: "pool/data@a", To: "pool/data@b", Added: 2, Renamed: 1, Total: 3}
+	if summary != expected {
+		t.Errorf("Expected %+v, got %+v", expected, summary)
+	}
+
+	if err := diffCmd.RunE(diffCmd, []string{"pool/data@b", "pool/data@a"}); err == nil {
+		t.Error("Expected an error diffing against an earlier snapshot")
+	}
+}
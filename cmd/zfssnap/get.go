@@ -3,16 +3,37 @@ package main
 
 import (
 	"bufio"
+	"cmp"
 	"context"
 	"fmt"
+	"math"
 	"os"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jsirianni/zfssnap/model"
 	"github.com/jsirianni/zfssnap/zfs"
 	"github.com/spf13/cobra"
 )
 
+var (
+	flagGetDataset   string
+	flagGetRecursive bool
+	flagGetOlderThan string
+	flagGetNewerThan string
+	flagGetMinUsed   string
+	flagGetNameGlob  string
+	flagGetHasClones bool
+	flagGetHeld      bool
+	flagGetSort      []string
+	flagGetLimit     int
+	flagGetFields    []string
+)
+
 var getCmd = &cobra.Command{
 	Use:   "get [snapshot...]",
 	Short: "Get details for ZFS snapshots or list all snapshots",
@@ -22,6 +43,12 @@ If snapshot names are provided, returns detailed information for those snapshots
 If no snapshot names are provided, lists all snapshots.
 If no arguments are provided and stdin is not a terminal, reads snapshot names from stdin (newline-separated).
 
+--dataset lists the snapshots of one dataset, and with --recursive of its
+descendents as well, with a single zfs list call. The other filters are
+applied to the listed snapshots. Snapshots are then sorted by the --sort
+fields, in zfs list order otherwise, and cut to --limit. --fields selects the
+fields that are written.
+
 Examples:
   # List all snapshots
   zfssnap get
@@ -30,20 +57,50 @@ Examples:
   zfssnap get pool@snapshot1 pool@snapshot2
 
   # Read snapshot names from stdin
-  echo "pool@snapshot1" | zfssnap get`,
-	Args: cobra.MinimumNArgs(0),
-	RunE: func(_ *cobra.Command, args []string) error {
+  echo "pool@snapshot1" | zfssnap get
+
+  # The 10 largest snapshots under pool/data older than a week
+  zfssnap get --dataset pool/data -r --older-than 168h --sort -used --limit 10
+
+  # Names and sizes of held daily snapshots
+  zfssnap get --name-glob 'daily-*' --held --fields name,used --output table`,
+	Args: usageArgs(cobra.MinimumNArgs(0)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := newSnapshotFilter(time.Now())
+		if err != nil {
+			return usageError(err)
+		}
+		sortKeys, err := parseSortKeys(flagGetSort)
+		if err != nil {
+			return usageError(err)
+		}
+		if _, err := itemColumns([]*model.Snapshot{}, flagGetFields); err != nil {
+			return usageError(err)
+		}
+		if len(flagGetFields) > 0 && outputTemplate != nil {
+			return usageError(fmt.Errorf("--fields cannot be combined with --template"))
+		}
+		if flagGetLimit < 0 {
+			return usageError(fmt.Errorf("invalid limit %d: must not be negative", flagGetLimit))
+		}
+		if flagGetDataset != "" && !zfs.IsValidDatasetName(flagGetDataset) {
+			return usageError(fmt.Errorf("invalid dataset name format: %s", flagGetDataset))
+		}
+		if flagGetRecursive && flagGetDataset == "" {
+			return usageError(fmt.Errorf("--recursive requires --dataset"))
+		}
+		if flagGetDataset != "" && len(args) > 0 {
+			return usageError(fmt.Errorf("--dataset cannot be combined with snapshot names"))
+		}
+
 		ctx := context.Background()
-		s := zfs.NewSnapshot(
-			zfs.WithZFSPath(flagZFSPath),
-			zfs.WithTimeout(flagTimeout),
-		)
+		s := newSnapshotter()
 
 		var snapshotNames []string
 		if len(args) > 0 {
 			// Use provided arguments
 			snapshotNames = args
-		} else {
+		} else if flagGetDataset == "" {
 			// Check if stdin has data (not a terminal)
 			stat, _ := os.Stdin.Stat()
 			if (stat.Mode() & os.ModeCharDevice) == 0 {
@@ -61,33 +118,19 @@ Examples:
 				if len(snapshotNames) == 0 {
 					return fmt.Errorf("no snapshot names provided")
 				}
-			} else {
-				// stdin is a terminal, list all snapshots
-				names, err := s.List(ctx)
-				if err != nil {
-					return fmt.Errorf("list snapshots: %w", err)
-				}
-
-				// Get detailed information for all snapshots
-				var snapshots []*model.Snapshot
-				for _, snapshotName := range names {
-					info, err := s.Get(ctx, snapshotName)
-					if err != nil {
-						return fmt.Errorf("get snapshot %s: %w", snapshotName, err)
-					}
-					snapshots = append(snapshots, info)
-				}
+			}
+		}
 
-				// Use output functions for formatting
-				if len(snapshots) == 1 {
-					return outputSnapshotJSON(snapshots[0], os.Stdout)
-				}
-				return outputSnapshotJSONArray(snapshots, os.Stdout)
+		var snapshots []*model.Snapshot
+		if len(snapshotNames) == 0 {
+			// List with a single zfs call, scoped to --dataset by zfs itself
+			snapshots, err = s.ListDetailed(ctx, zfs.ListOptions{Dataset: flagGetDataset, Recursive: flagGetRecursive})
+			if err != nil {
+				return fmt.Errorf("list snapshots: %w", err)
 			}
 		}
 
 		// Get detailed information for specific snapshots
-		var snapshots []*model.Snapshot
 		for _, snapshotName := range snapshotNames {
 			info, err := s.Get(ctx, snapshotName)
 			if err != nil {
@@ -96,10 +139,213 @@ Examples:
 			snapshots = append(snapshots, info)
 		}
 
-		// Use output functions for formatting
-		if len(snapshots) == 1 {
-			return outputSnapshotJSON(snapshots[0], os.Stdout)
+		snapshots = slices.DeleteFunc(snapshots, func(snap *model.Snapshot) bool {
+			return !filter.match(snap)
+		})
+		sortSnapshots(snapshots, sortKeys)
+		if flagGetLimit > 0 && len(snapshots) > flagGetLimit {
+			snapshots = snapshots[:flagGetLimit]
 		}
-		return outputSnapshotJSONArray(snapshots, os.Stdout)
+		return writeSnapshots(snapshots, flagGetFields, cmd.OutOrStdout())
 	},
 }
+
+func init() {
+	getCmd.Flags().StringVar(&flagGetDataset, "dataset", "", "Only list snapshots of this dataset")
+	getCmd.Flags().BoolVarP(&flagGetRecursive, "recursive", "r", false, "With --dataset, also list snapshots of all descendent datasets")
+	getCmd.Flags().StringVar(&flagGetOlderThan, "older-than", "", "Only snapshots created before this duration ago or RFC 3339 time")
+	getCmd.Flags().StringVar(&flagGetNewerThan, "newer-than", "", "Only snapshots created after this duration ago or RFC 3339 time")
+	getCmd.Flags().StringVar(&flagGetMinUsed, "min-used", "", "Only snapshots using at least this much space, e.g. 512M or 1G")
+	getCmd.Flags().StringVar(&flagGetNameGlob, "name-glob", "", "Only snapshots whose name after the @ matches this glob")
+	getCmd.Flags().BoolVar(&flagGetHasClones, "has-clones", false, "Only snapshots with clones")
+	getCmd.Flags().BoolVar(&flagGetHeld, "held", false, "Only snapshots with user holds")
+	getCmd.Flags().StringSliceVar(&flagGetSort, "sort", nil, "Sort by these fields; prefix a field with - to sort descending")
+	getCmd.Flags().IntVar(&flagGetLimit, "limit", 0, "Write at most this many snapshots (default: all)")
+	getCmd.Flags().StringSliceVar(&flagGetFields, "fields", nil, "Only write these fields, in this order")
+}
+
+// snapshotFilter selects the snapshots written by get. Zero values do not
+// filter.
+type snapshotFilter struct {
+	olderThan time.Time
+	newerThan time.Time
+	minUsed   uint64
+	nameGlob  string
+	hasClones bool
+	held      bool
+}
+
+// newSnapshotFilter builds the filter of the get flags. Durations are
+// relative to now.
+func newSnapshotFilter(now time.Time) (snapshotFilter, error) {
+	f := snapshotFilter{
+		nameGlob:  flagGetNameGlob,
+		hasClones: flagGetHasClones,
+		held:      flagGetHeld,
+	}
+
+	var err error
+	if f.olderThan, err = parseAge(flagGetOlderThan, now); err != nil {
+		return f, fmt.Errorf("invalid --older-than: %w", err)
+	}
+	if f.newerThan, err = parseAge(flagGetNewerThan, now); err != nil {
+		return f, fmt.Errorf("invalid --newer-than: %w", err)
+	}
+	if f.minUsed, err = parseSize(flagGetMinUsed); err != nil {
+		return f, fmt.Errorf("invalid --min-used: %w", err)
+	}
+	if f.nameGlob != "" {
+		if _, err := path.Match(f.nameGlob, ""); err != nil {
+			return f, fmt.Errorf("invalid --name-glob %q: %w", f.nameGlob, err)
+		}
+	}
+	return f, nil
+}
+
+// match reports whether s passes the filter.
+func (f snapshotFilter) match(s *model.Snapshot) bool {
+	switch {
+	case !f.olderThan.IsZero() && !s.Creation.Before(f.olderThan):
+		return false
+	case !f.newerThan.IsZero() && !s.Creation.After(f.newerThan):
+		return false
+	case s.Used < f.minUsed:
+		return false
+	case f.hasClones && len(s.Clones) == 0:
+		return false
+	case f.held && s.UserRefs == 0:
+		return false
+	}
+	if f.nameGlob != "" {
+		_, name, _ := strings.Cut(s.Name, "@")
+		if ok, _ := path.Match(f.nameGlob, name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// parseAge parses a duration before now or an RFC 3339 time. It returns the
+// zero time for an empty value.
+func parseAge(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a duration nor an RFC 3339 time", value)
+	}
+	if d < 0 {
+		return time.Time{}, fmt.Errorf("duration %s must not be negative", value)
+	}
+	return now.Add(-d), nil
+}
+
+// parseSize parses a size in bytes with an optional binary unit suffix as
+// written by zfs list, e.g. 512, 96K or 1.5G. It returns 0 for an empty
+// value.
+func parseSize(value string) (uint64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	const units = "BKMGTPE"
+	number := strings.ToUpper(value)
+	shift := 0
+	if i := strings.IndexByte(units, number[len(number)-1]); i >= 0 {
+		number = number[:len(number)-1]
+		shift = 10 * i
+	}
+
+	if n, err := strconv.ParseUint(number, 10, 64); err == nil {
+		if n > math.MaxUint64>>shift {
+			return 0, fmt.Errorf("size %q is too large", value)
+		}
+		return n << shift, nil
+	}
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil || f < 0 || math.IsNaN(f) {
+		return 0, fmt.Errorf("%q is not a size", value)
+	}
+	size := math.Ceil(f * float64(uint64(1)<<shift))
+	if size >= math.MaxUint64 {
+		return 0, fmt.Errorf("size %q is too large", value)
+	}
+	return uint64(size), nil
+}
+
+// sortKey is a snapshot field to sort by.
+type sortKey struct {
+	column     column
+	descending bool
+}
+
+// parseSortKeys parses --sort fields, which may be prefixed with '-' to
+// sort descending.
+func parseSortKeys(fields []string) ([]sortKey, error) {
+	keys := make([]sortKey, len(fields))
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i], keys[i].descending = strings.CutPrefix(field, "-")
+	}
+	columns, err := itemColumns([]*model.Snapshot{}, names)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --sort: %w", err)
+	}
+	for i := range keys {
+		keys[i].column = columns[i]
+	}
+	return keys, nil
+}
+
+// sortSnapshots sorts snapshots by keys, keeping the order of snapshots
+// that compare equal.
+func sortSnapshots(snapshots []*model.Snapshot, keys []sortKey) {
+	if len(keys) == 0 {
+		return
+	}
+	slices.SortStableFunc(snapshots, func(a, b *model.Snapshot) int {
+		for _, key := range keys {
+			c := compareFields(
+				reflect.ValueOf(a).Elem().FieldByIndex(key.column.index),
+				reflect.ValueOf(b).Elem().FieldByIndex(key.column.index),
+			)
+			if key.descending {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
+
+// compareFields compares two values of a snapshot field. Slices compare by
+// length.
+func compareFields(a, b reflect.Value) int {
+	if ta, ok := a.Interface().(time.Time); ok {
+		return ta.Compare(b.Interface().(time.Time))
+	}
+	switch a.Kind() {
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case reflect.Bool:
+		switch {
+		case a.Bool() == b.Bool():
+			return 0
+		case a.Bool():
+			return 1
+		}
+		return -1
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Slice, reflect.Map:
+		return cmp.Compare(a.Len(), b.Len())
+	}
+	return 0
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jsirianni/zfssnap/testutil"
+	"github.com/jsirianni/zfssnap/zfs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func TestGetFilters(t *testing.T) {
+	ctx := context.Background()
+	// Ages are relative to the real time.
+	start := time.Now().Add(-100 * time.Hour).Truncate(time.Second)
+	clock := testutil.NewClock(start)
+	sim := testutil.NewSimulator(clock)
+	for _, ds := range []string{"pool", "pool/data", "pool/data/child", "pool/home"} {
+		if err := sim.CreateDataset(ds); err != nil {
+			t.Fatalf("CreateDataset: %v", err)
+		}
+	}
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// pool/data@daily-1 is the only snapshot holding 1M of freed data.
+	must(sim.Write("pool/data", 1<<20))
+	must(sim.Create(ctx, "pool/data", "daily-1", zfs.CreateOptions{}))
+	must(sim.Free("pool/data", 1<<20))
+	must(sim.Create(ctx, "pool/home", "daily-1", zfs.CreateOptions{}))
+	clock.Advance(48 * time.Hour)
+	must(sim.Create(ctx, "pool/data", "daily-2", zfs.CreateOptions{}))
+	must(sim.Hold(ctx, "pool/data@daily-2", "keep", zfs.HoldOptions{}))
+	clock.Advance(time.Hour)
+	must(sim.Create(ctx, "pool/data/child", "weekly-1", zfs.CreateOptions{}))
+	must(sim.Clone(ctx, "pool/data/child@weekly-1", "pool/clone", zfs.CloneOptions{}))
+
+	original := newSnapshotter
+	newSnapshotter = func() snapshotter { return sim }
+	t.Cleanup(func() { newSnapshotter = original })
+
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		t.Cleanup(func() { resetFlags(getCmd) })
+		resetFlags(getCmd)
+
+		var buf bytes.Buffer
+		rootCmd.SetOut(&buf)
+		rootCmd.SetArgs(append([]string{"get"}, args...))
+		err := rootCmd.Execute()
+		return buf.String(), err
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{name: "dataset", args: []string{"--dataset", "pool/data"}, expected: "pool/data@daily-1,pool/data@daily-2"},
+		{name: "recursive", args: []string{"--dataset", "pool/data", "-r"}, expected: "pool/data@daily-1,pool/data@daily-2,pool/data/child@weekly-1"},
+		{name: "older than", args: []string{"--dataset", "pool", "-r", "--older-than", "72h"}, expected: "pool/data@daily-1,pool/home@daily-1"},
+		{name: "newer than", args: []string{"--dataset", "pool", "-r", "--newer-than", start.Add(24 * time.Hour).Format(time.RFC3339)}, expected: "pool/data@daily-2,pool/data/child@weekly-1"},
+		{name: "min used", args: []string{"--dataset", "pool", "-r", "--min-used", "1M"}, expected: "pool/data@daily-1"},
+		{name: "name glob", args: []string{"--dataset", "pool", "-r", "--name-glob", "daily-*"}, expected: "pool/data@daily-1,pool/data@daily-2,pool/home@daily-1"},
+		{name: "has clones", args: []string{"--dataset", "pool", "-r", "--has-clones"}, expected: "pool/data/child@weekly-1"},
+		{name: "held", args: []string{"--dataset", "pool", "-r", "--held"}, expected: "pool/data@daily-2"},
+		{name: "sort", args: []string{"--dataset", "pool", "-r", "--sort", "-creation,dataset"}, expected: "pool/data/child@weekly-1,pool/data@daily-2,pool/data@daily-1,pool/home@daily-1"},
+		{name: "sort and limit", args: []string{"--dataset", "pool", "-r", "--sort", "-used", "--limit", "1"}, expected: "pool/data@daily-1"},
+		{name: "snapshot names", args: []string{"--held", "pool/data@daily-1", "pool/data@daily-2"}, expected: "pool/data@daily-2"},
+		{name: "no match", args: []string{"--dataset", "pool/home", "--held"}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := run(append(tt.args, "--output", "csv", "--fields", "name")...)
+			if err != nil {
+				t.Fatalf("get %v: %v", tt.args, err)
+			}
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			if lines[0] != "name" {
+				t.Fatalf("Expected name header, got %q", out)
+			}
+			if got := strings.Join(lines[1:], ","); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	out, err := run("--dataset", "pool/data", "--fields", "used,name", "--always-array")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	expected := `[{"used":1048576,"name":"pool/data@daily-1"},{"used":0,"name":"pool/data@daily-2"}]` + "\n"
+	if out != expected {
+		t.Errorf("Expected %s, got %s", expected, out)
+	}
+
+	// Runtime errors do not print the usage
+	if out, err := run("--dataset", "pool/missing"); err == nil || strings.Contains(out, "Usage:") {
+		t.Errorf("Expected an error without usage, got %q, %v", out, err)
+	}
+
+	for _, args := range [][]string{
+		{"--older-than", "yesterday"},
+		{"--min-used", "1X"},
+		{"--name-glob", "[daily"},
+		{"--sort", "size"},
+		{"--fields", "name,size"},
+		{"--limit", "-1"},
+		{"--recursive"},
+		{"--dataset", "pool", "pool/data@daily-1"},
+		{"--fields", "name", "--template", "{{.Name}}"},
+		{"--dataset", "123pool"},
+	} {
+		if _, err := run(args...); exitCode(err) != exitUsage {
+			t.Errorf("get %v: expected usage error, got %v", args, err)
+		}
+	}
+}
+
+// resetFlags restores the flags of cmd and the global flags to their
+// defaults.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if v, ok := f.Value.(pflag.SliceValue); ok {
+			_ = v.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	rootCmd.PersistentFlags().VisitAll(reset)
+	outputTemplate = nil
+	rootCmd.SetArgs(nil)
+	rootCmd.SetOut(nil)
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected uint64
+		err      bool
+	}{
+		{value: "", expected: 0},
+		{value: "512", expected: 512},
+		{value: "512B", expected: 512},
+		{value: "96K", expected: 96 << 10},
+		{value: "1.5m", expected: 3 << 19},
+		{value: "16E", err: true},
+		{value: "18446744073709551615", expected: 18446744073709551615},
+		{value: "18446744073709551616", err: true},
+		{value: "-1", err: true},
+		{value: "G", err: true},
+		{value: "1X", err: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.value)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseSize(%q): expected error, got %d", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.expected {
+			t.Errorf("parseSize(%q): expected %d, got %d, %v", tt.value, tt.expected, got, err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jsirianni/zfssnap/model"
+	"github.com/jsirianni/zfssnap/zfs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagHoldRecursive    bool
+	flagReleaseRecursive bool
+	flagHoldsRecursive   bool
+)
+
+// holdResult is the JSON document printed by the hold and release commands.
+type holdResult struct {
+	Held     []string `json:"held,omitempty"`
+	Released []string `json:"released,omitempty"`
+	Errors   []string `json:"errors"`
+}
+
+var holdCmd = &cobra.Command{
+	Use:   "hold [flags] <tag> <snapshot...>",
+	Short: "Place a user hold on snapshots",
+	Long: `Place a user hold identified by tag on one or more snapshots.
+
+A held snapshot cannot be destroyed until every hold on it is released.
+delete and prune report held snapshots as skipped, together with their tags.
+
+Examples:
+  # Protect a snapshot from being destroyed
+  zfssnap hold keep pool/dataset@backup
+
+  # Hold the snapshot in the dataset and all of its children
+  zfssnap hold -r replication pool/dataset@daily`,
+	Args: usageArgs(cobra.MinimumNArgs(2)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		s := newSnapshotter()
+		tag := args[0]
+
+		result := holdResult{Held: []string{}, Errors: []string{}}
+		for _, name := range args[1:] {
+			if err := s.Hold(ctx, name, tag, zfs.HoldOptions{Recursive: flagHoldRecursive}); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("hold snapshot %s: %v", name, err))
+				continue
+			}
+			result.Held = append(result.Held, name)
+		}
+		if err := writeOutput(result, cmd.OutOrStdout()); err != nil {
+			return err
+		}
+		return failureError("hold", "snapshot", len(result.Errors), len(args)-1)
+	},
+}
+
+var releaseCmd = &cobra.Command{
+	Use:   "release [flags] <tag> <snapshot...>",
+	Short: "Release a user hold from snapshots",
+	Long: `Release the user hold identified by tag from one or more snapshots.
+
+A snapshot that was marked for deferred destruction with 'delete --defer'
+is destroyed when its last hold is released.
+
+Examples:
+  # Release a hold
+  zfssnap release keep pool/dataset@backup
+
+  # Release the hold from the snapshot in the dataset and all of its children
+  zfssnap release -r replication pool/dataset@daily`,
+	Args: usageArgs(cobra.MinimumNArgs(2)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		s := newSnapshotter()
+		tag := args[0]
+
+		result := holdResult{Released: []string{}, Errors: []string{}}
+		for _, name := range args[1:] {
+			if err := s.Release(ctx, name, tag, zfs.HoldOptions{Recursive: flagReleaseRecursive}); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("release snapshot %s: %v", name, err))
+				continue
+			}
+			result.Released = append(result.Released, name)
+		}
+		if err := writeOutput(result, cmd.OutOrStdout()); err != nil {
+			return err
+		}
+		return failureError("release", "snapshot", len(result.Errors), len(args)-1)
+	},
+}
+
+var holdsCmd = &cobra.Command{
+	Use:   "holds [flags] <snapshot...>",
+	Short: "List the user holds on snapshots",
+	Long: `List the user holds on one or more snapshots.
+
+Examples:
+  # List the holds on a snapshot
+  zfssnap holds pool/dataset@backup
+
+  # Include the snapshot of the same name in all child datasets
+  zfssnap holds -r pool/dataset@daily`,
+	Args: usageArgs(cobra.MinimumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		s := newSnapshotter()
+
+		holds := []*model.Hold{}
+		for _, name := range args {
+			h, err := s.Holds(ctx, name, zfs.HoldOptions{Recursive: flagHoldsRecursive})
+			if err != nil {
+				return fmt.Errorf("list holds for %s: %w", name, err)
+			}
+			holds = append(holds, h...)
+		}
+		return writeOutput(holds, cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	holdCmd.Flags().BoolVarP(&flagHoldRecursive, "recursive", "r", false, "Hold the snapshot in all child datasets")
+	releaseCmd.Flags().BoolVarP(&flagReleaseRecursive, "recursive", "r", false, "Release the hold from the snapshot in all child datasets")
+	holdsCmd.Flags().BoolVarP(&flagHoldsRecursive, "recursive", "r", false, "List holds on the snapshot in all child datasets")
+}
+
+// skippedSnapshot is a snapshot that was not destroyed because it is held.
+type skippedSnapshot struct {
+	Snapshot string   `json:"snapshot"`
+	Tags     []string `json:"tags"`
+}
+
+// heldSnapshots returns the snapshots covered by name that have user holds,
+// with their tags, in the order zfs reports them.
+func heldSnapshots(ctx context.Context, h zfs.Holder, name string, recursive bool) ([]skippedSnapshot, error) {
+	holds, err := h.Holds(ctx, name, zfs.HoldOptions{Recursive: recursive})
+	if err != nil {
+		return nil, err
+	}
+
+	var held []skippedSnapshot
+	index := make(map[string]int)
+	for _, hold := range holds {
+		i, ok := index[hold.Snapshot]
+		if !ok {
+			i = len(held)
+			index[hold.Snapshot] = i
+			held = append(held, skippedSnapshot{Snapshot: hold.Snapshot})
+		}
+		held[i].Tags = append(held[i].Tags, hold.Tag)
+	}
+	for i := range held {
+		sort.Strings(held[i].Tags)
+	}
+	return held, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+	"github.com/jsirianni/zfssnap/testutil"
+	"github.com/jsirianni/zfssnap/zfs"
+)
+
+func TestHoldCommands(t *testing.T) {
+	ctx := context.Background()
+	sim := testutil.NewSimulator(testutil.NewClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+	if err := sim.CreateDataset("pool/data/child"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := sim.Create(ctx, "pool/data", "daily", zfs.CreateOptions{Recursive: true}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	original := newSnapshotter
+	newSnapshotter = func() snapshotter { return sim }
+	t.Cleanup(func() {
+		newSnapshotter = original
+		flagHoldRecursive, flagReleaseRecursive, flagHoldsRecursive = false, false, false
+	})
+
+	var buf bytes.Buffer
+	flagHoldRecursive = true
+	holdCmd.SetOut(&buf)
+	err := holdCmd.RunE(holdCmd, []string{"keep", "pool/data@daily", "pool/missing@daily"})
+	if code := exitCode(err); code != exitPartialFailure {
+		t.Errorf("Expected exit code %d, got %d (%v)", exitPartialFailure, code, err)
+	}
+	var result holdResult
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("Invalid output %q: %v", buf.String(), err)
+	}
+	if len(result.Held) != 1 || result.Held[0] != "pool/data@daily" || len(result.Errors) != 1 {
+		t.Errorf("Unexpected hold result: %+v", result)
+	}
+
+	buf.Reset()
+	flagHoldsRecursive = true
+	holdsCmd.SetOut(&buf)
+	if err := holdsCmd.RunE(holdsCmd, []string{"pool/data@daily"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var holds []model.Hold
+	if err := json.Unmarshal(buf.Bytes(), &holds); err != nil {
+		t.Fatalf("Invalid output %q: %v", buf.String(), err)
+	}
+	if len(holds) != 2 || holds[0].Snapshot != "pool/data@daily" || holds[1].Snapshot != "pool/data/child@daily" || holds[1].Tag != "keep" {
+		t.Errorf("Unexpected holds: %+v", holds)
+	}
+
+	held, err := heldSnapshots(ctx, sim, "pool/data@daily", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(held) != 2 || held[0].Tags[0] != "keep" {
+		t.Errorf("Unexpected held snapshots: %+v", held)
+	}
+
+	buf.Reset()
+	releaseCmd.SetOut(&buf)
+	if err := releaseCmd.RunE(releaseCmd, []string{"keep", "pool/data/child@daily"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	held, err = heldSnapshots(ctx, sim, "pool/data@daily", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(held) != 1 || held[0].Snapshot != "pool/data@daily" {
+		t.Errorf("Expected only the parent to remain held, got %+v", held)
+	}
+
+	buf.Reset()
+	err = releaseCmd.RunE(releaseCmd, []string{"keep", "pool/data/child@daily"})
+	if code := exitCode(err); code != exitAllFailed {
+		t.Errorf("Expected exit code %d, got %d (%v)", exitAllFailed, code, err)
+	}
+	var released holdResult
+	if err := json.Unmarshal(buf.Bytes(), &released); err != nil {
+		t.Fatalf("Invalid output %q: %v", buf.String(), err)
+	}
+	if len(released.Released) != 0 || len(released.Errors) != 1 {
+		t.Errorf("Unexpected release result: %+v", released)
+	}
+}
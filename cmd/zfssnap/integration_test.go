@@ -39,26 +39,12 @@ func TestGetCommandListAll(t *testing.T) {
 			// Create a test runner that uses our mock
 			runGetListWithMock := func(_ *cobra.Command, _ []string) error {
 				ctx := context.Background()
-				names, err := mockSnapshotter.List(ctx)
+				snapshots, err := mockSnapshotter.ListDetailed(ctx, zfs.ListOptions{})
 				if err != nil {
 					return err
 				}
 
-				// Get detailed information for all snapshots
-				var snapshots []*model.Snapshot
-				for _, snapshotName := range names {
-					info, err := mockSnapshotter.Get(ctx, snapshotName)
-					if err != nil {
-						return err
-					}
-					snapshots = append(snapshots, info)
-				}
-
-				// Use output functions for formatting
-				if len(snapshots) == 1 {
-					return outputSnapshotJSON(snapshots[0], &buf)
-				}
-				return outputSnapshotJSONArray(snapshots, &buf)
+				return writeSnapshots(snapshots, nil, &buf)
 			}
 
 			err := runGetListWithMock(cmd, []string{})
@@ -128,11 +114,7 @@ func TestGetCommand(t *testing.T) {
 					snapshots = append(snapshots, info)
 				}
 
-				// Use output functions for formatting
-				if len(snapshots) == 1 {
-					return outputSnapshotJSON(snapshots[0], &buf)
-				}
-				return outputSnapshotJSONArray(snapshots, &buf)
+				return writeSnapshots(snapshots, nil, &buf)
 			}
 
 			err := runGetWithMock(cmd, tt.args)
@@ -204,11 +186,7 @@ func TestGetCommandStdin(t *testing.T) {
 					snapshots = append(snapshots, info)
 				}
 
-				// Use output functions for formatting
-				if len(snapshots) == 1 {
-					return outputSnapshotJSON(snapshots[0], &buf)
-				}
-				return outputSnapshotJSONArray(snapshots, &buf)
+				return writeSnapshots(snapshots, nil, &buf)
 			}
 
 			err := runGetStdinWithMock(cmd, []string{})
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"time"
 
+	"github.com/jsirianni/zfssnap/zfs"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -13,21 +15,121 @@ import (
 var (
 	appLogger *zap.Logger
 
-	flagZFSPath string
-	flagTimeout time.Duration
+	flagZFSPath    string
+	flagTimeout    time.Duration
+	flagConfigPath string
 )
 
+// Exit codes of zfssnap, for scripts.
+const (
+	// exitFailure is returned for errors without a more specific code.
+	exitFailure = 1
+
+	// exitUsage is returned for invalid flags and arguments, before
+	// anything is changed.
+	exitUsage = 2
+
+	// exitAllFailed is returned when an operation on several datasets
+	// failed for every dataset.
+	exitAllFailed = 3
+
+	// exitPartialFailure is returned when an operation on several datasets
+	// failed for some of them.
+	exitPartialFailure = 4
+
+	// exitNotConfirmed is returned when a destructive operation was refused
+	// because its confirmation flag was missing, before anything is changed.
+	exitNotConfirmed = 5
+)
+
+// exitError is an error that makes zfssnap exit with code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// usageError marks err as an invalid flag or argument.
+func usageError(err error) error {
+	return &exitError{code: exitUsage, err: err}
+}
+
+// usageArgs makes the argument validation validate return usage errors.
+func usageArgs(validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			return usageError(err)
+		}
+		return nil
+	}
+}
+
+// failureError returns the exit error of an operation on total items of
+// which failed failed: exitAllFailed when every item failed and
+// exitPartialFailure when some did. The message names op and the item.
+func failureError(op, item string, failed, total int) error {
+	switch {
+	case failed == 0:
+		return nil
+	case failed >= total:
+		return &exitError{code: exitAllFailed, err: fmt.Errorf("%s failed for every %s", op, item)}
+	}
+	return &exitError{code: exitPartialFailure, err: fmt.Errorf("%s failed for %d of %d %ss", op, failed, total, item)}
+}
+
+// exitCode returns the exit code for an error returned by rootCmd.
+func exitCode(err error) int {
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return exitFailure
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "zfssnap",
 	Short: "ZFS snapshot utility",
-	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+	// Most errors are not usage mistakes; main points usage errors at --help.
+	SilenceUsage: true,
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
 		if err := initLogger(); err != nil {
 			fmt.Fprintf(os.Stderr, "initialize logger: %v\n", err)
 			os.Exit(1)
 		}
+		if err := initOutput(cmd); err != nil {
+			return usageError(err)
+		}
+		return nil
 	},
 }
 
+// snapshotter is the ZFS functionality used by commands.
+type snapshotter interface {
+	zfs.Snapshotter
+	zfs.Holder
+	zfs.Rollbacker
+	zfs.Copier
+	zfs.Cloner
+	zfs.Bookmarker
+	zfs.Differ
+}
+
+// newSnapshotter returns the ZFS backend used by commands. Tests replace it
+// with testutil.Simulator.
+var newSnapshotter = func() snapshotter {
+	return zfs.NewSnapshot(
+		zfs.WithZFSPath(flagZFSPath),
+		zfs.WithTimeout(flagTimeout),
+	)
+}
+
 func initLogger() error {
 	config := zap.NewProductionConfig()
 	config.EncoderConfig.TimeKey = "ts"
@@ -41,14 +143,39 @@ func initLogger() error {
 func init() {
 	rootCmd.PersistentFlags().StringVar(&flagZFSPath, "zfs-bin", "", "Path to zfs binary (default: detect in $PATH)")
 	rootCmd.PersistentFlags().DurationVar(&flagTimeout, "timeout", 30*time.Second, "Command timeout")
+	rootCmd.PersistentFlags().StringVar(&flagConfigPath, "config", "", "Path to YAML configuration file")
+	rootCmd.PersistentFlags().StringVar(&flagOutput, "output", formatJSON, "Output format: json, table, yaml, csv or ndjson")
+	rootCmd.PersistentFlags().StringVar(&flagTemplate, "template", "", "Format output with a Go template, executed once per item")
+	rootCmd.PersistentFlags().BoolVar(&flagAlwaysArray, "always-array", false, "Write lists of snapshots as arrays even when they hold one snapshot")
 
 	rootCmd.AddCommand(getCmd)
 	rootCmd.AddCommand(createCmd)
+	rootCmd.AddCommand(deleteCmd)
+	rootCmd.AddCommand(pruneCmd)
+	rootCmd.AddCommand(holdCmd)
+	rootCmd.AddCommand(releaseCmd)
+	rootCmd.AddCommand(holdsCmd)
+	rootCmd.AddCommand(rollbackCmd)
+	rootCmd.AddCommand(cloneCmd)
+	rootCmd.AddCommand(promoteCmd)
+	rootCmd.AddCommand(replicateCmd)
+	rootCmd.AddCommand(bookmarkCmd)
+	rootCmd.AddCommand(diffCmd)
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(configCmd)
+
+	rootCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return usageError(err)
+	})
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+	cmd, err := rootCmd.ExecuteC()
+	if err != nil {
+		code := exitCode(err)
+		if code == exitUsage {
+			fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", cmd.CommandPath())
+		}
+		os.Exit(code)
 	}
 }
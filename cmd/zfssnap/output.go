@@ -1,36 +1,520 @@
 package main
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
+	"fmt"
 	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
 
 	"github.com/jsirianni/zfssnap/model"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
-// encodeJSON writes the snapshot as JSON to the provided writer.
-func encodeSnapshotJSON(s *model.Snapshot, w io.Writer) error {
-	enc := json.NewEncoder(w)
-	enc.SetEscapeHTML(false)
-	return enc.Encode(s)
+// Output formats accepted by --output.
+const (
+	formatJSON   = "json"
+	formatTable  = "table"
+	formatYAML   = "yaml"
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+var (
+	flagOutput      string
+	flagTemplate    string
+	flagAlwaysArray bool
+
+	// outputTemplate is the parsed --template, nil when not given.
+	outputTemplate *template.Template
+)
+
+// byteFields are the columns holding sizes in bytes, humanized in tables.
+var byteFields = map[string]bool{
+	"used":               true,
+	"referenced":         true,
+	"logical_used":       true,
+	"logical_referenced": true,
+	"written":            true,
+	"available":          true,
+	"quota":              true,
+	"reclaimed":          true,
+}
+
+// templateFuncs are the functions available to --template in addition to
+// the text/template builtins.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"bytes": humanizeBytes,
+	"join":  strings.Join,
+}
+
+// initOutput validates --output and parses --template before a command runs,
+// so that a bad flag is reported before anything is changed.
+func initOutput(cmd *cobra.Command) error {
+	switch flagOutput {
+	case formatJSON, formatTable, formatYAML, formatCSV, formatNDJSON:
+	default:
+		return fmt.Errorf("invalid output format %q: must be one of json, table, yaml, csv or ndjson", flagOutput)
+	}
+
+	outputTemplate = nil
+	if flagTemplate == "" {
+		return nil
+	}
+	if cmd.Flags().Changed("output") {
+		return fmt.Errorf("--template cannot be combined with --output")
+	}
+	tmpl, err := template.New("output").Funcs(templateFuncs).Option("missingkey=error").Parse(flagTemplate)
+	if err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	outputTemplate = tmpl
+	return nil
+}
+
+// writeOutput writes the result of a command in the selected format. Slices
+// are written as one row, line or template execution per element.
+func writeOutput(v any, w io.Writer) error {
+	return writeFields(v, nil, w)
+}
+
+// writeFields writes v like writeOutput, keeping only the named JSON fields
+// of structs, in the given order. All fields are written when fields is
+// empty. Templates always see the whole value.
+func writeFields(v any, fields []string, w io.Writer) error {
+	if outputTemplate != nil {
+		return writeTemplate(v, w)
+	}
+	switch flagOutput {
+	case formatTable:
+		return writeTable(v, fields, w)
+	case formatCSV:
+		return writeCSV(v, fields, w)
+	}
+
+	v, err := project(v, fields)
+	if err != nil {
+		return err
+	}
+	switch flagOutput {
+	case formatYAML:
+		return writeYAML(v, w)
+	case formatNDJSON:
+		return writeNDJSON(v, w)
+	}
+	return encodeJSON(v, w)
+}
+
+// writeSnapshots writes snapshots with writeFields. A single snapshot is
+// written as an object rather than an array unless --always-array is given.
+func writeSnapshots(snapshots []*model.Snapshot, fields []string, w io.Writer) error {
+	if len(snapshots) == 1 && !flagAlwaysArray {
+		return writeFields(snapshots[0], fields, w)
+	}
+	if snapshots == nil {
+		snapshots = []*model.Snapshot{}
+	}
+	return writeFields(snapshots, fields, w)
 }
 
-// encodeSnapshotJSONArray writes an array of snapshots as JSON to the provided writer.
-func encodeSnapshotJSONArray(snapshots []*model.Snapshot, w io.Writer) error {
+// encodeJSON writes v as a single line of JSON.
+func encodeJSON(v any, w io.Writer) error {
 	enc := json.NewEncoder(w)
 	enc.SetEscapeHTML(false)
-	return enc.Encode(snapshots)
+	return enc.Encode(v)
+}
+
+// writeNDJSON writes each element of a slice as a line of JSON.
+func writeNDJSON(v any, w io.Writer) error {
+	for _, item := range elements(v) {
+		if err := encodeJSON(item.Interface(), w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeYAML writes v as YAML with the field names of its JSON encoding.
+func writeYAML(v any, w io.Writer) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	// JSON is YAML, so decoding it keeps the JSON field names and order.
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	blockStyle(&doc)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	return enc.Close()
 }
 
-// outputSnapshotJSON writes the snapshot as JSON to the provided writer.
-func outputSnapshotJSON(s *model.Snapshot, w io.Writer) error {
-	return encodeSnapshotJSON(s, w)
+// blockStyle clears the flow and quoting styles of the JSON document, so
+// that it is written as block YAML. Strings are still quoted when needed.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
 }
 
-// outputSnapshotJSONArray writes an array of snapshots as JSON to the provided writer.
-// Single snapshot outputs as object, multiple snapshots as array.
-func outputSnapshotJSONArray(snapshots []*model.Snapshot, w io.Writer) error {
-	if len(snapshots) == 1 {
-		return encodeSnapshotJSON(snapshots[0], w)
+// writeTemplate executes --template for each element of a slice, or once,
+// followed by a newline.
+func writeTemplate(v any, w io.Writer) error {
+	for _, item := range elements(v) {
+		if err := outputTemplate.Execute(w, item.Interface()); err != nil {
+			return fmt.Errorf("execute template: %w", err)
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTable writes v as aligned columns with humanized sizes.
+func writeTable(v any, fields []string, w io.Writer) error {
+	columns, rows, err := tabulate(v, fields, true)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = strings.ToUpper(c)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		for i, cell := range row {
+			if cell == "" {
+				row[i] = "-"
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// writeCSV writes v as CSV with a header row.
+func writeCSV(v any, fields []string, w io.Writer) error {
+	columns, rows, err := tabulate(v, fields, false)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// elements returns the elements of a slice, or v itself.
+func elements(v any) []reflect.Value {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return []reflect.Value{rv}
+	}
+	items := make([]reflect.Value, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i)
+	}
+	return items
+}
+
+// column is a field of a struct written as a table or CSV column.
+type column struct {
+	name  string
+	index []int
+}
+
+// tabulate returns the columns and cells of v, one row per element of a
+// slice. Columns are the JSON fields of structs, or the given fields; other
+// values are written in a single "value" column. Nested values are written
+// as JSON, and sizes are humanized when human is set.
+func tabulate(v any, fields []string, human bool) ([]string, [][]string, error) {
+	columns, err := itemColumns(v, fields)
+	if err != nil {
+		return nil, nil, err
+	}
+	names := []string{"value"}
+	if columns != nil {
+		names = make([]string, len(columns))
+		for i, c := range columns {
+			names[i] = c.name
+		}
+	}
+
+	var rows [][]string
+	for _, item := range elements(v) {
+		item = reflect.Indirect(item)
+		if columns == nil {
+			rows = append(rows, []string{formatCell(item, "", human)})
+			continue
+		}
+		row := make([]string, len(columns))
+		if !item.IsValid() {
+			rows = append(rows, row)
+			continue
+		}
+		for i, c := range columns {
+			field, err := item.FieldByIndexErr(c.index)
+			if err != nil {
+				// Field of a nil embedded struct
+				continue
+			}
+			row[i] = formatCell(field, c.name, human)
+		}
+		rows = append(rows, row)
+	}
+	return names, rows, nil
+}
+
+// itemColumns returns the columns of the structs in v, a struct or a slice
+// of structs, limited to fields when given. It returns nil for other values.
+func itemColumns(v any, fields []string) ([]column, error) {
+	typ := reflect.TypeOf(v)
+	if typ != nil && typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+	}
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct || typ == reflect.TypeOf(time.Time{}) {
+		if len(fields) > 0 {
+			return nil, fmt.Errorf("fields cannot be selected from %T", v)
+		}
+		return nil, nil
+	}
+
+	columns := structColumns(typ, nil)
+	if len(fields) == 0 {
+		return columns, nil
+	}
+	return selectColumns(columns, fields)
+}
+
+// selectColumns returns the columns named by fields, in that order.
+func selectColumns(columns []column, fields []string) ([]column, error) {
+	byName := make(map[string]column, len(columns))
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		byName[c.name] = c
+		names[i] = c.name
+	}
+	selected := make([]column, 0, len(fields))
+	for _, f := range fields {
+		c, ok := byName[f]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q: must be one of %s", f, strings.Join(names, ", "))
+		}
+		selected = append(selected, c)
+	}
+	return selected, nil
+}
+
+// record is a struct reduced to some of its fields. It is encoded as a JSON
+// object with the fields in the order they were selected.
+type record struct {
+	names  []string
+	values []any
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r record) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range r.names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := encodeJSON(name, &buf); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1)
+		buf.WriteByte(':')
+		if err := encodeJSON(r.values[i], &buf); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// project reduces the structs in v to fields, returning a record or a slice
+// of records. v is returned unchanged when fields is empty.
+func project(v any, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return v, nil
+	}
+	columns, err := itemColumns(v, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	toRecord := func(item reflect.Value) record {
+		r := record{names: make([]string, len(columns)), values: make([]any, len(columns))}
+		item = reflect.Indirect(item)
+		for i, c := range columns {
+			r.names[i] = c.name
+			if !item.IsValid() {
+				continue
+			}
+			if field, err := item.FieldByIndexErr(c.index); err == nil {
+				r.values[i] = field.Interface()
+			}
+		}
+		return r
+	}
+
+	if reflect.TypeOf(v).Kind() != reflect.Slice {
+		return toRecord(reflect.ValueOf(v)), nil
+	}
+	items := elements(v)
+	records := make([]record, len(items))
+	for i, item := range items {
+		records[i] = toRecord(item)
+	}
+	return records, nil
+}
+
+// structColumns returns the fields of typ as encoding/json names them,
+// including the fields of embedded structs.
+func structColumns(typ reflect.Type, index []int) []column {
+	var columns []column
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		fieldIndex := append(append([]int{}, index...), i)
+
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				columns = append(columns, structColumns(embedded, fieldIndex)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		columns = append(columns, column{name: name, index: fieldIndex})
+	}
+	return columns
+}
+
+// formatCell formats a value of a table or CSV cell.
+func formatCell(v reflect.Value, name string, human bool) string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return ""
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		switch {
+		case t.IsZero():
+			return ""
+		case human:
+			return t.Local().Format(time.DateTime)
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if human && byteFields[name] {
+			return humanizeBytes(v.Uint())
+		}
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Map:
+		if v.Len() == 0 {
+			return ""
+		}
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return ""
+		}
+		if v.Type().Elem().Kind() == reflect.String {
+			items := make([]string, v.Len())
+			for i := range items {
+				items[i] = v.Index(i).String()
+			}
+			return strings.Join(items, ",")
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := encodeJSON(v.Interface(), &buf); err != nil {
+		return fmt.Sprint(v.Interface())
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// humanizeBytes formats a size in bytes with a binary unit suffix and three
+// significant digits, like zfs list, e.g. 512B, 96K or 1.50M.
+func humanizeBytes(n uint64) string {
+	const units = "KMGTPE"
+	if n < 1024 {
+		return strconv.FormatUint(n, 10) + "B"
+	}
+	v := float64(n)
+	i := -1
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	if v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f%c", v, units[i])
+	}
+	// The digits are counted after rounding, so 9.999K becomes 10.0K and
+	// 1023.9K carries into the next unit.
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	for prec := 1; len(s) > 4 && prec >= 0; prec-- {
+		s = strconv.FormatFloat(v, 'f', prec, 64)
+	}
+	if s == "1024" && i < len(units)-1 {
+		return fmt.Sprintf("1.00%c", units[i+1])
 	}
-	return encodeSnapshotJSONArray(snapshots, w)
+	return s + string(units[i])
 }
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+)
+
+func testOutputSnapshots() []*model.Snapshot {
+	return []*model.Snapshot{
+		{
+			Name:       "pool/data@daily",
+			Dataset:    "pool/data",
+			Creation:   time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC),
+			Used:       1572864,
+			Referenced: 98304,
+			Clones:     []string{"pool/c1", "pool/c2"},
+			GUID:       42,
+			Type:       "snapshot",
+		},
+		{
+			Name:     "pool/data@123",
+			Dataset:  "pool/data",
+			Creation: time.Date(2025, 1, 16, 12, 0, 0, 0, time.UTC),
+			Type:     "snapshot",
+		},
+	}
+}
+
+func setOutputFlags(t *testing.T) {
+	t.Helper()
+
+	original := time.Local
+	time.Local = time.UTC
+	t.Cleanup(func() {
+		time.Local = original
+		flagOutput = formatJSON
+		flagTemplate = ""
+		flagAlwaysArray = false
+		outputTemplate = nil
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		for _, name := range []string{"output", "template", "always-array"} {
+			rootCmd.PersistentFlags().Lookup(name).Changed = false
+		}
+	})
+}
+
+func TestWriteOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		template string
+		expected string
+	}{
+		{
+			name:   "json",
+			output: formatJSON,
+			expected: `[{"name":"pool/data@daily","dataset":"pool/data","creation":"2025-01-15T12:00:00Z","used":1572864,"referenced":98304,"clones":["pool/c1","pool/c2"],"defer_destroy":false,"logical_used":0,"logical_referenced":0,"guid":42,"user_refs":0,"written":0,"type":"snapshot"},{"name":"pool/data@123","dataset":"pool/data","creation":"2025-01-16T12:00:00Z","used":0,"referenced":0,"defer_destroy":false,"logical_used":0,"logical_referenced":0,"guid":0,"user_refs":0,"written":0,"type":"snapshot"}]
+`,
+		},
+		{
+			name:   "ndjson",
+			output: formatNDJSON,
+			expected: `{"name":"pool/data@daily","dataset":"pool/data","creation":"2025-01-15T12:00:00Z","used":1572864,"referenced":98304,"clones":["pool/c1","pool/c2"],"defer_destroy":false,"logical_used":0,"logical_referenced":0,"guid":42,"user_refs":0,"written":0,"type":"snapshot"}
+{"name":"pool/data@123","dataset":"pool/data","creation":"2025-01-16T12:00:00Z","used":0,"referenced":0,"defer_destroy":false,"logical_used":0,"logical_referenced":0,"guid":0,"user_refs":0,"written":0,"type":"snapshot"}
+`,
+		},
+		{
+			name:   "table",
+			output: formatTable,
+			expected: `NAME             DATASET    CREATION             USED   REFERENCED  CLONES           DEFER_DESTROY  LOGICAL_USED  LOGICAL_REFERENCED  GUID  USER_REFS  WRITTEN  TYPE
+pool/data@daily  pool/data  2025-01-15 12:00:00  1.50M  96K         pool/c1,pool/c2  false          0B            0B                  42    0          0B       snapshot
+pool/data@123    pool/data  2025-01-16 12:00:00  0B     0B          -                false          0B            0B                  0     0          0B       snapshot
+`,
+		},
+		{
+			name:   "csv",
+			output: formatCSV,
+			expected: `name,dataset,creation,used,referenced,clones,defer_destroy,logical_used,logical_referenced,guid,user_refs,written,type
+pool/data@daily,pool/data,2025-01-15T12:00:00Z,1572864,98304,"pool/c1,pool/c2",false,0,0,42,0,0,snapshot
+pool/data@123,pool/data,2025-01-16T12:00:00Z,0,0,,false,0,0,0,0,0,snapshot
+`,
+		},
+		{
+			name:   "yaml",
+			output: formatYAML,
+			expected: `- name: pool/data@daily
+  dataset: pool/data
+  creation: "2025-01-15T12:00:00Z"
+  used: 1572864
+  referenced: 98304
+  clones:
+    - pool/c1
+    - pool/c2
+  defer_destroy: false
+  logical_used: 0
+  logical_referenced: 0
+  guid: 42
+  user_refs: 0
+  written: 0
+  type: snapshot
+- name: pool/data@123
+  dataset: pool/data
+  creation: "2025-01-16T12:00:00Z"
+  used: 0
+  referenced: 0
+  defer_destroy: false
+  logical_used: 0
+  logical_referenced: 0
+  guid: 0
+  user_refs: 0
+  written: 0
+  type: snapshot
+`,
+		},
+		{
+			name:     "template",
+			output:   formatJSON,
+			template: `{{.Name}} {{bytes .Used}} {{join .Clones "+"}}`,
+			expected: "pool/data@daily 1.50M pool/c1+pool/c2\npool/data@123 0B \n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setOutputFlags(t)
+			flagOutput = tt.output
+			flagTemplate = tt.template
+			if err := initOutput(rootCmd); err != nil {
+				t.Fatalf("initOutput: %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := writeSnapshots(testOutputSnapshots(), nil, &buf); err != nil {
+				t.Fatalf("writeSnapshots: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected output:\n%s\nGot:\n%s", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestWriteSnapshotsAlwaysArray(t *testing.T) {
+	setOutputFlags(t)
+	single := testOutputSnapshots()[1:]
+
+	var buf bytes.Buffer
+	if err := writeSnapshots(single, nil, &buf); err != nil {
+		t.Fatalf("writeSnapshots: %v", err)
+	}
+	if buf.Bytes()[0] != '{' {
+		t.Errorf("Expected an object for one snapshot, got %s", buf.String())
+	}
+
+	flagAlwaysArray = true
+	for _, snapshots := range [][]*model.Snapshot{single, nil} {
+		buf.Reset()
+		if err := writeSnapshots(snapshots, nil, &buf); err != nil {
+			t.Fatalf("writeSnapshots: %v", err)
+		}
+		if buf.Bytes()[0] != '[' {
+			t.Errorf("Expected an array with --always-array, got %s", buf.String())
+		}
+	}
+}
+
+func TestWriteOutputStruct(t *testing.T) {
+	setOutputFlags(t)
+	flagOutput = formatTable
+
+	var buf bytes.Buffer
+	result := deleteResult{Destroyed: []string{"pool/data@a"}, Errors: []string{}, Count: 1, Reclaimed: 2048, Skipped: []skippedSnapshot{}}
+	if err := writeOutput(result, &buf); err != nil {
+		t.Fatalf("writeOutput: %v", err)
+	}
+	expected := `DESTROYED    ERRORS  COUNT  RECLAIMED  SKIPPED
+pool/data@a  -       1      2K         -
+`
+	if buf.String() != expected {
+		t.Errorf("Expected output:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
+
+func TestOutputFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		expectError bool
+	}{
+		{name: "yaml", args: []string{"--output", "yaml"}},
+		{name: "template", args: []string{"--template", "{{.Version}}"}},
+		{name: "unknown format", args: []string{"--output", "xml"}, expectError: true},
+		{name: "template with output", args: []string{"--output", "table", "--template", "{{.Version}}"}, expectError: true},
+		{name: "invalid template", args: []string{"--template", "{{.Version"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setOutputFlags(t)
+			var buf bytes.Buffer
+			rootCmd.SetOut(&buf)
+			rootCmd.SetArgs(append([]string{"version"}, tt.args...))
+			err := rootCmd.Execute()
+			if tt.expectError != (err != nil) {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+			}
+			if !tt.expectError && buf.Len() == 0 {
+				t.Error("Expected version output")
+			}
+		})
+	}
+}
+
+func TestHumanizeBytes(t *testing.T) {
+	tests := map[uint64]string{
+		0:                    "0B",
+		512:                  "512B",
+		1024:                 "1K",
+		1536:                 "1.50K",
+		98304:                "96K",
+		1572864:              "1.50M",
+		13207024435:          "12.3G",
+		10234:                "9.99K",
+		10239:                "10.0K",
+		102399:               "100K",
+		1047552:              "1023K",
+		1048064:              "1.00M",
+		1048575:              "1.00M",
+		1073741823:           "1.00G",
+		1 << 50:              "1P",
+		18446744073709551615: "16E",
+	}
+	for n, expected := range tests {
+		if got := humanizeBytes(n); got != expected {
+			t.Errorf("humanizeBytes(%d): expected %s, got %s", n, expected, got)
+		}
+	}
+}
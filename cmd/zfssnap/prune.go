@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jsirianni/zfssnap/model"
+	"github.com/jsirianni/zfssnap/naming"
+	"github.com/jsirianni/zfssnap/retention"
+	"github.com/jsirianni/zfssnap/zfs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagPruneRecursive bool
+	flagPruneDryRun    bool
+	flagPrunePrefix    string
+	flagPrunePolicy    retention.Policy
+)
+
+// pruneResult is the JSON document printed by the prune command.
+type pruneResult struct {
+	DryRun bool `json:"dry_run"`
+	*retention.Plan
+	Destroyed []string          `json:"destroyed"`
+	Skipped   []skippedSnapshot `json:"skipped"`
+	Errors    []string          `json:"errors"`
+}
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune [flags] [dataset...]",
+	Short: "Destroy snapshots outside of a retention policy",
+	Long: `Apply a retention policy to the snapshots of the specified dataset(s) and
+destroy the snapshots the policy does not keep.
+
+For each period, the newest snapshot in each of the last N periods that
+contain a snapshot is kept. Periods are calendar aligned in UTC. Each
+dataset is evaluated independently. Snapshots with user holds are never
+destroyed; they are reported as skipped with their hold tags.
+
+When no datasets are given, every dataset with a retention policy in the
+file given by --config is pruned with its own policy.
+
+Examples:
+  # Show what a policy would keep and destroy
+  zfssnap prune --dry-run --keep-daily 7 --keep-weekly 4 pool/dataset
+
+  # Apply a policy to a dataset and all of its children
+  zfssnap prune -r --keep-hourly 24 --keep-daily 30 --keep-monthly 12 pool/dataset
+
+  # Only consider snapshots whose name starts with "auto"
+  zfssnap prune --prefix auto --keep-daily 7 pool/dataset
+
+  # Apply the retention policies from the configuration file
+  zfssnap --config /usr/local/etc/zfssnap.yaml prune`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		targets, err := pruneTargets(args)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		s := newSnapshotter()
+
+		result := pruneResult{
+			DryRun:    flagPruneDryRun,
+			Plan:      &retention.Plan{Keep: []retention.Decision{}, Destroy: []retention.Decision{}},
+			Destroyed: []string{},
+			Skipped:   []skippedSnapshot{},
+			Errors:    []string{},
+		}
+		for _, target := range targets {
+			listed, err := s.ListDetailed(ctx, zfs.ListOptions{Dataset: target.dataset, Recursive: target.recursive})
+			if err != nil {
+				return fmt.Errorf("list snapshots for %s: %w", target.dataset, err)
+			}
+			plan := retention.Apply(target.filter(listed), target.policy)
+			result.Keep = append(result.Keep, plan.Keep...)
+			result.Destroy = append(result.Destroy, plan.Destroy...)
+		}
+
+		for _, d := range result.Destroy {
+			if d.Snapshot.UserRefs > 0 {
+				held, err := heldSnapshots(ctx, s, d.Snapshot.Name, false)
+				if err != nil {
+					result.Errors = append(result.Errors, fmt.Sprintf("list holds for %s: %v", d.Snapshot.Name, err))
+					continue
+				}
+				if len(held) > 0 {
+					result.Skipped = append(result.Skipped, held...)
+					continue
+				}
+			}
+			if flagPruneDryRun {
+				continue
+			}
+			if _, err := s.Delete(ctx, d.Snapshot.Name, zfs.DeleteOptions{}); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("destroy snapshot %s: %v", d.Snapshot.Name, err))
+				continue
+			}
+			result.Destroyed = append(result.Destroyed, d.Snapshot.Name)
+		}
+
+		if err := writeOutput(result, cmd.OutOrStdout()); err != nil {
+			return err
+		}
+		// Held snapshots are skipped, not failed.
+		return failureError("prune", "snapshot", len(result.Errors), len(result.Destroy)-len(result.Skipped))
+	},
+}
+
+func init() {
+	pruneCmd.Flags().BoolVarP(&flagPruneRecursive, "recursive", "r", false, "Also prune snapshots of all child datasets")
+	pruneCmd.Flags().BoolVar(&flagPruneDryRun, "dry-run", false, "Show the retention plan without destroying anything")
+	pruneCmd.Flags().StringVar(&flagPrunePrefix, "prefix", "", "Only consider snapshots whose name starts with this prefix")
+	pruneCmd.Flags().IntVar(&flagPrunePolicy.Latest, "keep-latest", 0, "Number of most recent snapshots to keep")
+	pruneCmd.Flags().IntVar(&flagPrunePolicy.Hourly, "keep-hourly", 0, "Number of hourly snapshots to keep")
+	pruneCmd.Flags().IntVar(&flagPrunePolicy.Daily, "keep-daily", 0, "Number of daily snapshots to keep")
+	pruneCmd.Flags().IntVar(&flagPrunePolicy.Weekly, "keep-weekly", 0, "Number of weekly snapshots to keep")
+	pruneCmd.Flags().IntVar(&flagPrunePolicy.Monthly, "keep-monthly", 0, "Number of monthly snapshots to keep")
+	pruneCmd.Flags().IntVar(&flagPrunePolicy.Yearly, "keep-yearly", 0, "Number of yearly snapshots to keep")
+}
+
+// pruneTarget is a dataset and the policy applied to it.
+type pruneTarget struct {
+	dataset   string
+	recursive bool
+	policy    retention.Policy
+
+	// exclude lists descendent datasets whose snapshots are not pruned,
+	// together with their own descendents.
+	exclude []string
+
+	// prefix selects the snapshots to consider, unless template is set.
+	prefix string
+
+	// template and values select the snapshots to consider, see
+	// naming.Template.Filter.
+	template *naming.Template
+	values   naming.Values
+}
+
+// filter returns the snapshots the policy of the target applies to.
+func (t pruneTarget) filter(snapshots []*model.Snapshot) []*model.Snapshot {
+	var matched map[string]bool
+	if t.template != nil {
+		names := make([]string, len(snapshots))
+		for i, info := range snapshots {
+			names[i] = info.Name
+		}
+		matched = make(map[string]bool)
+		for _, name := range t.template.Filter(names, t.values) {
+			matched[name] = true
+		}
+	}
+
+	var filtered []*model.Snapshot
+	for _, info := range snapshots {
+		if isExcluded(info.Dataset, t.exclude) {
+			continue
+		}
+		if t.template != nil && !matched[info.Name] {
+			continue
+		}
+		if t.template == nil && !hasSnapshotPrefix(info.Name, t.prefix) {
+			continue
+		}
+		filtered = append(filtered, info)
+	}
+	return filtered
+}
+
+// pruneTargets resolves the datasets to prune from the arguments and flags,
+// or from the configuration file when no datasets are given. Datasets from
+// the configuration file only consider the snapshots named by their naming
+// section.
+func pruneTargets(args []string) ([]pruneTarget, error) {
+	if len(args) > 0 {
+		if err := flagPrunePolicy.Validate(); err != nil {
+			return nil, usageError(fmt.Errorf("invalid retention policy: %w", err))
+		}
+		targets := make([]pruneTarget, 0, len(args))
+		for _, dataset := range args {
+			targets = append(targets, pruneTarget{dataset: dataset, recursive: flagPruneRecursive, policy: flagPrunePolicy, prefix: flagPrunePrefix})
+		}
+		return targets, nil
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, usageError(fmt.Errorf("at least one dataset or --config is required"))
+	}
+	hostname, err := naming.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("get hostname: %w", err)
+	}
+	var targets []pruneTarget
+	for _, ds := range cfg.Datasets {
+		if ds.Retention == nil {
+			continue
+		}
+		tmpl, err := configTemplate(ds.Naming)
+		if err != nil {
+			return nil, fmt.Errorf("dataset %s: %w", ds.Name, err)
+		}
+		targets = append(targets, pruneTarget{
+			dataset:   ds.Name,
+			recursive: ds.Recursive,
+			policy:    *ds.Retention,
+			exclude:   ds.Exclude,
+			template:  tmpl,
+			values: naming.Values{
+				Prefix:   ds.Naming.Prefix,
+				Suffix:   ds.Naming.Suffix,
+				Label:    ds.Naming.Name,
+				Hostname: hostname,
+			},
+		})
+	}
+	return targets, nil
+}
+
+// isExcluded reports whether dataset is one of exclude or a descendent of
+// one.
+func isExcluded(dataset string, exclude []string) bool {
+	for _, excluded := range exclude {
+		if dataset == excluded || strings.HasPrefix(dataset, excluded+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSnapshotPrefix reports whether the snapshot component of name starts with prefix.
+func hasSnapshotPrefix(name, prefix string) bool {
+	if prefix == "" {
+		return true
+	}
+	_, snap, ok := strings.Cut(name, "@")
+	return ok && strings.HasPrefix(snap, prefix)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jsirianni/zfssnap/naming"
+	"github.com/jsirianni/zfssnap/retention"
+	"github.com/jsirianni/zfssnap/testutil"
+	"github.com/jsirianni/zfssnap/zfs"
+)
+
+func TestHasSnapshotPrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot string
+		prefix   string
+		expected bool
+	}{
+		{
+			name:     "no prefix matches everything",
+			snapshot: "pool/dataset@manual",
+			prefix:   "",
+			expected: true,
+		},
+		{
+			name:     "matching prefix",
+			snapshot: "pool/dataset@auto-20250101-000000",
+			prefix:   "auto",
+			expected: true,
+		},
+		{
+			name:     "non-matching prefix",
+			snapshot: "pool/dataset@manual",
+			prefix:   "auto",
+			expected: false,
+		},
+		{
+			name:     "prefix only matches snapshot component",
+			snapshot: "auto/dataset@manual",
+			prefix:   "auto",
+			expected: false,
+		},
+		{
+			name:     "not a snapshot",
+			snapshot: "pool/dataset",
+			prefix:   "pool",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := hasSnapshotPrefix(tt.snapshot, tt.prefix); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestPruneCommandWithSimulator(t *testing.T) {
+	ctx := context.Background()
+	sim := testutil.NewSimulator(testutil.NewClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)))
+	if err := sim.CreateDataset("pool/data/child"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		name := "auto-" + sim.Clock().Now().Format(naming.TimestampFormat)
+		if err := sim.Create(ctx, "pool/data", name, zfs.CreateOptions{Recursive: true}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		sim.Clock().Advance(24 * time.Hour)
+	}
+	if err := sim.Create(ctx, "pool/data", "manual", zfs.CreateOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := sim.Hold(ctx, "pool/data@auto-20250102-120000", "keep", zfs.HoldOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	original := newSnapshotter
+	newSnapshotter = func() snapshotter { return sim }
+	t.Cleanup(func() {
+		newSnapshotter = original
+		flagPruneRecursive, flagPruneDryRun, flagPrunePrefix = false, false, ""
+		flagPrunePolicy = retention.Policy{}
+	})
+	flagPruneRecursive = true
+	flagPrunePrefix = "auto"
+	flagPrunePolicy = retention.Policy{Daily: 3}
+
+	run := func(dryRun bool) (pruneResult, error) {
+		t.Helper()
+		flagPruneDryRun = dryRun
+		var buf bytes.Buffer
+		pruneCmd.SetOut(&buf)
+		err := pruneCmd.RunE(pruneCmd, []string{"pool/data"})
+		var result pruneResult
+		if jsonErr := json.Unmarshal(buf.Bytes(), &result); jsonErr != nil {
+			t.Fatalf("Invalid output %q: %v", buf.String(), jsonErr)
+		}
+		return result, err
+	}
+
+	result, err := run(true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.Keep) != 6 || len(result.Destroy) != 14 || len(result.Destroyed) != 0 {
+		t.Fatalf("Unexpected dry run: keep=%d destroy=%d destroyed=%d", len(result.Keep), len(result.Destroy), len(result.Destroyed))
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].Snapshot != "pool/data@auto-20250102-120000" || result.Skipped[0].Tags[0] != "keep" {
+		t.Fatalf("Expected held snapshot to be skipped, got %+v", result.Skipped)
+	}
+
+	// A cloned snapshot cannot be destroyed
+	if err := sim.Clone(ctx, "pool/data@auto-20250103-120000", "pool/clone", zfs.CloneOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	result, err = run(false)
+	if code := exitCode(err); code != exitPartialFailure {
+		t.Errorf("Expected exit code %d, got %d (%v)", exitPartialFailure, code, err)
+	}
+	if len(result.Destroyed) != 12 || len(result.Skipped) != 1 || len(result.Errors) != 1 {
+		t.Fatalf("Unexpected prune: destroyed=%d skipped=%v errors=%v", len(result.Destroyed), result.Skipped, result.Errors)
+	}
+
+	remaining, err := sim.List(ctx, zfs.ListOptions{Dataset: "pool/data"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{
+		"pool/data@auto-20250102-120000",
+		"pool/data@auto-20250103-120000",
+		"pool/data@auto-20250108-120000",
+		"pool/data@auto-20250109-120000",
+		"pool/data@auto-20250110-120000",
+		"pool/data@manual",
+	}
+	if strings.Join(remaining, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, remaining)
+	}
+}
+
+func TestPruneCommandWithConfig(t *testing.T) {
+	ctx := context.Background()
+	sim := testutil.NewSimulator(testutil.NewClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)))
+	for _, ds := range []string{"pool/data/child", "pool/data/skip/nested"} {
+		if err := sim.CreateDataset(ds); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		name := naming.Apply("daily", naming.Options{Prefix: "zfs", Timestamp: true}, sim.Clock().Now())
+		if err := sim.Create(ctx, "pool/data", name, zfs.CreateOptions{Recursive: true}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		sim.Clock().Advance(24 * time.Hour)
+	}
+	for _, name := range []string{"manual", "zfs-hourly-20250101-120000"} {
+		if err := sim.Create(ctx, "pool/data", name, zfs.CreateOptions{Recursive: true}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "zfssnap.yaml")
+	cfg := `datasets:
+  - name: pool/data
+    naming:
+      name: daily
+      prefix: zfs
+    recursive: true
+    exclude: [pool/data/skip]
+    retention:
+      latest: 1
+`
+	if err := os.WriteFile(path, []byte(cfg), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	original := newSnapshotter
+	newSnapshotter = func() snapshotter { return sim }
+	t.Cleanup(func() {
+		newSnapshotter = original
+		flagConfigPath = ""
+	})
+	flagConfigPath = path
+
+	var buf bytes.Buffer
+	pruneCmd.SetOut(&buf)
+	if err := pruneCmd.RunE(pruneCmd, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var result pruneResult
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("Invalid output %q: %v", buf.String(), err)
+	}
+	if len(result.Destroyed) != 4 || len(result.Keep) != 2 {
+		t.Errorf("Unexpected prune: keep=%d destroyed=%v", len(result.Keep), result.Destroyed)
+	}
+	for _, name := range result.Destroyed {
+		if !strings.Contains(name, "@zfs-daily-") || strings.HasPrefix(name, "pool/data/skip") {
+			t.Errorf("Destroyed a snapshot outside of the configuration: %s", name)
+		}
+	}
+	for _, dataset := range []string{"pool/data/skip", "pool/data/skip/nested"} {
+		remaining, err := sim.List(ctx, zfs.ListOptions{Dataset: dataset})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(remaining) != 5 {
+			t.Errorf("Expected the snapshots of excluded %s to remain, got %v", dataset, remaining)
+		}
+	}
+}
+
+func TestPruneUsageErrors(t *testing.T) {
+	t.Cleanup(func() { flagPrunePolicy = retention.Policy{} })
+
+	tests := []struct {
+		name   string
+		policy retention.Policy
+		args   []string
+	}{
+		{name: "empty policy", args: []string{"pool/data"}},
+		{name: "negative count", policy: retention.Policy{Daily: -1}, args: []string{"pool/data"}},
+		{name: "no dataset or config", policy: retention.Policy{Daily: 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flagPrunePolicy = tt.policy
+			err := pruneCmd.RunE(pruneCmd, tt.args)
+			if code := exitCode(err); code != exitUsage {
+				t.Errorf("Expected exit code %d, got %d (%v)", exitUsage, code, err)
+			}
+		})
+	}
+}
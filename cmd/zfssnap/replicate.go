@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jsirianni/zfssnap/replicate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagReplicateSourceHost     string
+	flagReplicateTargetHost     string
+	flagReplicateSSHPort        int
+	flagReplicateSSHIdentity    string
+	flagReplicateSSHOptions     []string
+	flagReplicateRemoteZFSPath  string
+	flagReplicateIntermediates  bool
+	flagReplicateForce          bool
+	flagReplicateDiscardPartial bool
+	flagReplicateBookmark       bool
+	flagReplicateDryRun         bool
+)
+
+// newReplicator returns the Replicator used by the replicate command. Tests
+// replace it to run against a scripted runner.
+var newReplicator = func(opts ...replicate.Option) *replicate.Replicator {
+	return replicate.New(opts...)
+}
+
+var replicateCmd = &cobra.Command{
+	Use:   "replicate [flags] <source-dataset> <target-dataset>",
+	Short: "Replicate snapshots to another dataset",
+	Long: `Replicate the snapshots of a dataset into another dataset, possibly on
+another host, with zfs send and receive.
+
+The newest snapshot both datasets share is found by GUID, and only the
+changes since it are sent. A target that does not exist is created from a
+full stream. Streams are received resumably and the target is not mounted;
+an interrupted replication is resumed the next time it runs.
+
+The newest replicated snapshot is bookmarked in the source dataset. Once the
+target has it, the snapshot can be pruned from the source: the next
+replication is sent from the bookmark.
+
+Either side can be on a remote host reached with ssh. The remote user must
+be able to run zfs without a password prompt.
+
+Examples:
+  # Replicate to a local backup pool
+  zfssnap replicate tank/data backup/data
+
+  # Replicate every intermediate snapshot to a remote host
+  zfssnap replicate -I --target-host backup@nas tank/data backup/data
+
+  # Show what would be sent
+  zfssnap replicate --dry-run --target-host backup@nas tank/data backup/data`,
+	Args: usageArgs(cobra.ExactArgs(2)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		source, target := args[0], args[1]
+
+		r := newReplicator(
+			replicate.WithSource(replicationEndpoint(flagReplicateSourceHost, flagZFSPath)),
+			replicate.WithTarget(replicationEndpoint(flagReplicateTargetHost, flagZFSPath)),
+			replicate.WithTimeout(flagTimeout),
+		)
+		result, err := r.Replicate(context.Background(), source, target, replicate.Options{
+			Intermediates:  flagReplicateIntermediates,
+			Force:          flagReplicateForce,
+			DiscardPartial: flagReplicateDiscardPartial,
+			Bookmark:       flagReplicateBookmark,
+			DryRun:         flagReplicateDryRun,
+		})
+		if err != nil {
+			return fmt.Errorf("replicate %s to %s: %w", source, target, err)
+		}
+		return writeOutput(result, cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	replicateCmd.Flags().StringVar(&flagReplicateSourceHost, "source-host", "", "Read the source dataset on this host over ssh ([user@]host)")
+	replicateCmd.Flags().StringVar(&flagReplicateTargetHost, "target-host", "", "Receive into the target dataset on this host over ssh ([user@]host)")
+	replicateCmd.Flags().IntVar(&flagReplicateSSHPort, "ssh-port", 0, "Port of the remote host (default: ssh default)")
+	replicateCmd.Flags().StringVar(&flagReplicateSSHIdentity, "ssh-identity", "", "Private key used to authenticate to the remote host")
+	replicateCmd.Flags().StringArrayVar(&flagReplicateSSHOptions, "ssh-option", nil, "Option passed to ssh with -o (repeatable)")
+	replicateCmd.Flags().StringVar(&flagReplicateRemoteZFSPath, "remote-zfs-bin", "", "Path to zfs on the remote host (default: zfs)")
+	replicateCmd.Flags().BoolVarP(&flagReplicateIntermediates, "intermediates", "I", false, "Replicate every snapshot since the common snapshot, not only the newest")
+	replicateCmd.Flags().BoolVarP(&flagReplicateForce, "force", "F", false, "Roll the target back to the common snapshot before receiving")
+	replicateCmd.Flags().BoolVar(&flagReplicateDiscardPartial, "discard-partial", false, "Discard an interrupted receive into the target instead of resuming it")
+	replicateCmd.Flags().BoolVar(&flagReplicateBookmark, "bookmark", true, "Bookmark the newest replicated snapshot so it can be pruned from the source")
+	replicateCmd.Flags().BoolVar(&flagReplicateDryRun, "dry-run", false, "Show what would be replicated without sending anything")
+}
+
+// replicationEndpoint returns the transport and zfs binary for one side of a
+// replication: ssh when host is set, otherwise this host with localZFSPath.
+func replicationEndpoint(host, localZFSPath string) (replicate.Transport, string) {
+	if host == "" {
+		return replicate.Local{}, localZFSPath
+	}
+	return replicate.SSH{
+		Host:         host,
+		Port:         flagReplicateSSHPort,
+		IdentityFile: flagReplicateSSHIdentity,
+		Options:      flagReplicateSSHOptions,
+	}, flagReplicateRemoteZFSPath
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/jsirianni/zfssnap/replicate"
+	"github.com/jsirianni/zfssnap/testutil"
+)
+
+func TestReplicateCommandRemoteTarget(t *testing.T) {
+	runner := testutil.NewFakeRunner(t)
+	ssh := []string{"ssh", "-o", "BatchMode=yes", "-p", "2222", "--", "backup@nas", "/usr/sbin/zfs"}
+	runner.Expect(append(ssh, "get", "-H", "-o", "value", "receive_resume_token", "backup/data")...).
+		Return("", "cannot open 'backup/data': dataset does not exist\n", 1)
+	runner.Expect("zfs", "list", "-H", "-p", "-t", "snapshot", "-o", "name,creation,used,referenced,clones,defer_destroy,logicalused,logicalreferenced,guid,userrefs,written,type", "-d", "1", "tank/data").
+		Return("tank/data@a\t100\t0\t0\t-\toff\t0\t0\t1\t0\t0\tsnapshot\n", "", 0)
+	runner.Expect("zfs", "list", "-H", "-p", "-t", "bookmark", "-o", "name,creation,guid,createtxg", "-d", "1", "tank/data")
+
+	original := newReplicator
+	newReplicator = func(opts ...replicate.Option) *replicate.Replicator {
+		return replicate.New(append(opts, replicate.WithRunner(runner))...)
+	}
+	t.Cleanup(func() {
+		newReplicator = original
+		flagReplicateTargetHost, flagReplicateSSHPort, flagReplicateRemoteZFSPath, flagReplicateDryRun = "", 0, "", false
+	})
+	flagReplicateTargetHost = "backup@nas"
+	flagReplicateSSHPort = 2222
+	flagReplicateRemoteZFSPath = "/usr/sbin/zfs"
+	flagReplicateDryRun = true
+
+	var buf bytes.Buffer
+	replicateCmd.SetOut(&buf)
+	if err := replicateCmd.RunE(replicateCmd, []string{"tank/data", "backup/data"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var result replicate.Result
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("Invalid output %q: %v", buf.String(), err)
+	}
+	if result.Mode != replicate.ModeFull || result.Snapshot != "tank/data@a" || !result.DryRun {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
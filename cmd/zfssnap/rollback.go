@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+	"github.com/jsirianni/zfssnap/naming"
+	"github.com/jsirianni/zfssnap/zfs"
+	"github.com/spf13/cobra"
+)
+
+// safetySnapshotName is the base name of the snapshot taken by
+// rollback --safety-dataset.
+const safetySnapshotName = "rollback-safety"
+
+var (
+	flagRollbackDestroyNewer  bool
+	flagRollbackDestroyClones bool
+	flagRollbackDryRun        bool
+	flagRollbackSafetyDataset string
+)
+
+// rollbackResult is the JSON document printed by the rollback command.
+type rollbackResult struct {
+	Snapshot   string   `json:"snapshot"`
+	DryRun     bool     `json:"dry_run"`
+	Snapshots  []string `json:"destroyed_snapshots"`
+	Bookmarks  []string `json:"destroyed_bookmarks"`
+	Clones     []string `json:"destroyed_clones"`
+	Safety     string   `json:"safety,omitempty"`
+	RolledBack bool     `json:"rolled_back"`
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [flags] <snapshot>",
+	Short: "Roll a dataset back to a snapshot",
+	Long: `Roll a dataset back to one of its snapshots, discarding every change made
+since the snapshot was taken.
+
+zfs destroys all snapshots and bookmarks newer than the target, and with
+them any clones of those snapshots. The command reports what would be
+destroyed and refuses to continue unless that is confirmed with
+--destroy-newer, or --destroy-clones when clones would be destroyed as well.
+
+A snapshot taken now would be newer than the target and destroyed by the
+rollback, so --safety-dataset preserves the current state by snapshotting
+the dataset and receiving a copy of that snapshot into a new dataset first.
+
+Examples:
+  # Show what rolling back would destroy
+  zfssnap rollback --dry-run pool/dataset@daily-20250101
+
+  # Roll back, destroying newer snapshots
+  zfssnap rollback --destroy-newer pool/dataset@daily-20250101
+
+  # Keep a copy of the current state in pool/dataset-before-rollback first
+  zfssnap rollback --destroy-newer --safety-dataset pool/dataset-before-rollback pool/dataset@daily-20250101`,
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if !zfs.IsValidSnapshotName(name) {
+			return usageError(fmt.Errorf("invalid snapshot name format: %s (must contain @)", name))
+		}
+		dataset, _, _ := strings.Cut(name, "@")
+		if flagRollbackSafetyDataset != "" && !zfs.IsValidDatasetName(flagRollbackSafetyDataset) {
+			return usageError(fmt.Errorf("invalid safety dataset name format: %s", flagRollbackSafetyDataset))
+		}
+
+		ctx := context.Background()
+		s := newSnapshotter()
+
+		snapshots, err := s.ListDetailed(ctx, zfs.ListOptions{Dataset: dataset})
+		if err != nil {
+			return fmt.Errorf("list snapshots for %s: %w", dataset, err)
+		}
+		newer, found := newerSnapshots(snapshots, name)
+		if !found {
+			return fmt.Errorf("snapshot not found: %s", name)
+		}
+		bookmarks, err := s.Bookmarks(ctx, zfs.ListOptions{Dataset: dataset})
+		if err != nil {
+			return fmt.Errorf("list bookmarks for %s: %w", dataset, err)
+		}
+
+		result := rollbackResult{
+			Snapshot:  name,
+			DryRun:    flagRollbackDryRun,
+			Snapshots: []string{},
+			Bookmarks: newerBookmarks(bookmarks, snapshots, name),
+			Clones:    snapshotClones(newer),
+		}
+		for _, snap := range newer {
+			result.Snapshots = append(result.Snapshots, snap.Name)
+		}
+		destroysNewer := len(result.Snapshots) > 0 || len(result.Bookmarks) > 0
+
+		if flagRollbackDryRun {
+			return writeOutput(result, cmd.OutOrStdout())
+		}
+		if len(result.Clones) > 0 && !flagRollbackDestroyClones {
+			if err := writeOutput(result, cmd.OutOrStdout()); err != nil {
+				return err
+			}
+			return &exitError{code: exitNotConfirmed, err: fmt.Errorf("rollback would destroy %d newer snapshot(s) and %d clone(s); rerun with --destroy-clones to confirm", len(result.Snapshots), len(result.Clones))}
+		}
+		if destroysNewer && !flagRollbackDestroyNewer && !flagRollbackDestroyClones {
+			if err := writeOutput(result, cmd.OutOrStdout()); err != nil {
+				return err
+			}
+			return &exitError{code: exitNotConfirmed, err: fmt.Errorf("rollback would destroy %d newer snapshot(s) and %d bookmark(s); rerun with --destroy-newer to confirm", len(result.Snapshots), len(result.Bookmarks))}
+		}
+
+		opts := zfs.RollbackOptions{
+			DestroyNewer:  destroysNewer,
+			DestroyClones: len(result.Clones) > 0,
+		}
+		if flagRollbackSafetyDataset != "" {
+			safety, err := takeSafetyCopy(ctx, s, dataset, flagRollbackSafetyDataset, time.Now())
+			if err != nil {
+				return err
+			}
+			result.Safety = safety
+			// The safety snapshot itself is newer than the target.
+			opts.DestroyNewer = true
+		}
+
+		if err := s.Rollback(ctx, name, opts); err != nil {
+			return fmt.Errorf("rollback to %s: %w", name, err)
+		}
+		result.RolledBack = true
+		return writeOutput(result, cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	rollbackCmd.Flags().BoolVar(&flagRollbackDestroyNewer, "destroy-newer", false, "Confirm destroying snapshots and bookmarks newer than the target (zfs rollback -r)")
+	rollbackCmd.Flags().BoolVar(&flagRollbackDestroyClones, "destroy-clones", false, "Confirm destroying newer snapshots and their clones (zfs rollback -R)")
+	rollbackCmd.Flags().BoolVar(&flagRollbackDryRun, "dry-run", false, "Show what would be destroyed without rolling back")
+	rollbackCmd.Flags().StringVar(&flagRollbackSafetyDataset, "safety-dataset", "", "Copy a snapshot of the current state into this new dataset before rolling back")
+}
+
+// newerSnapshots returns the snapshots created after the named snapshot, in
+// creation order, and whether the named snapshot was found. Snapshots with
+// the same creation time keep the order zfs listed them in.
+func newerSnapshots(snapshots []*model.Snapshot, name string) ([]*model.Snapshot, bool) {
+	sorted := append([]*model.Snapshot(nil), snapshots...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Creation.Before(sorted[j].Creation)
+	})
+	for i, snap := range sorted {
+		if snap.Name == name {
+			return sorted[i+1:], true
+		}
+	}
+	return nil, false
+}
+
+// newerBookmarks returns the bookmarks created after the named snapshot, in
+// creation order. A bookmark of a newer snapshot is newer even if both
+// snapshots have the same creation time.
+func newerBookmarks(bookmarks []*model.Bookmark, snapshots []*model.Snapshot, name string) []string {
+	var target *model.Snapshot
+	for _, snap := range snapshots {
+		if snap.Name == name {
+			target = snap
+		}
+	}
+	newerGUIDs := make(map[uint64]bool)
+	if target != nil {
+		newer, _ := newerSnapshots(snapshots, name)
+		for _, snap := range newer {
+			newerGUIDs[snap.GUID] = true
+		}
+	}
+
+	sorted := append([]*model.Bookmark(nil), bookmarks...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Creation.Before(sorted[j].Creation)
+	})
+	names := []string{}
+	for _, b := range sorted {
+		if target == nil || b.GUID == target.GUID {
+			continue
+		}
+		if newerGUIDs[b.GUID] || b.Creation.After(target.Creation) {
+			names = append(names, b.Name)
+		}
+	}
+	return names
+}
+
+// snapshotClones returns the sorted clones of the snapshots.
+func snapshotClones(snapshots []*model.Snapshot) []string {
+	clones := []string{}
+	for _, snap := range snapshots {
+		clones = append(clones, snap.Clones...)
+	}
+	sort.Strings(clones)
+	return clones
+}
+
+// takeSafetyCopy snapshots dataset and receives the snapshot into target. It
+// returns the name of the preserved snapshot in target.
+func takeSafetyCopy(ctx context.Context, s snapshotter, dataset, target string, now time.Time) (string, error) {
+	name := naming.Apply(safetySnapshotName, naming.Options{Timestamp: true}, now)
+	snapshot := dataset + "@" + name
+	if err := s.Create(ctx, dataset, name, zfs.CreateOptions{}); err != nil {
+		return "", fmt.Errorf("create safety snapshot %s: %w", snapshot, err)
+	}
+	if err := s.Copy(ctx, snapshot, target); err != nil {
+		if _, delErr := s.Delete(ctx, snapshot, zfs.DeleteOptions{}); delErr != nil {
+			return "", fmt.Errorf("copy safety snapshot to %s: %w (destroy %s: %v)", target, err, snapshot, delErr)
+		}
+		return "", fmt.Errorf("copy safety snapshot to %s: %w", target, err)
+	}
+	return target + "@" + name, nil
+}
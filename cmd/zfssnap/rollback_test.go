@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+	"github.com/jsirianni/zfssnap/testutil"
+	"github.com/jsirianni/zfssnap/zfs"
+)
+
+func TestRollbackCommand(t *testing.T) {
+	ctx := context.Background()
+	sim := testutil.NewSimulator(testutil.NewClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+	if err := sim.CreateDataset("pool/data"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if err := sim.Write("pool/data", 100); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := sim.Create(ctx, "pool/data", name, zfs.CreateOptions{}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		sim.Clock().Advance(time.Hour)
+	}
+	if err := sim.Clone(ctx, "pool/data@c", "pool/data-clone", zfs.CloneOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if err := sim.Bookmark(ctx, "pool/data@"+name, "pool/data#"+name); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := sim.Write("pool/data", 100); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	original := newSnapshotter
+	newSnapshotter = func() snapshotter { return sim }
+	t.Cleanup(func() {
+		newSnapshotter = original
+		flagRollbackDestroyNewer, flagRollbackDestroyClones, flagRollbackDryRun = false, false, false
+		flagRollbackSafetyDataset = ""
+	})
+
+	run := func() (rollbackResult, error) {
+		t.Helper()
+		var buf bytes.Buffer
+		rollbackCmd.SetOut(&buf)
+		err := rollbackCmd.RunE(rollbackCmd, []string{"pool/data@a"})
+		var result rollbackResult
+		if jsonErr := json.Unmarshal(buf.Bytes(), &result); jsonErr != nil {
+			t.Fatalf("Invalid output %q: %v", buf.String(), jsonErr)
+		}
+		return result, err
+	}
+
+	flagRollbackDryRun = true
+	result, err := run()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(result.Snapshots, ",") != "pool/data@b,pool/data@c" || strings.Join(result.Bookmarks, ",") != "pool/data#b" ||
+		strings.Join(result.Clones, ",") != "pool/data-clone" || result.RolledBack {
+		t.Errorf("Unexpected dry run: %+v", result)
+	}
+
+	flagRollbackDryRun = false
+	if _, err := run(); exitCode(err) != exitNotConfirmed || !strings.Contains(err.Error(), "--destroy-clones") {
+		t.Fatalf("Expected clone confirmation error, got %v", err)
+	}
+	flagRollbackDestroyNewer = true
+	if _, err := run(); exitCode(err) != exitNotConfirmed || !strings.Contains(err.Error(), "--destroy-clones") {
+		t.Fatalf("Expected clone confirmation error, got %v", err)
+	}
+	if !sim.Exists("pool/data@c") {
+		t.Fatal("Expected nothing to be destroyed without confirmation")
+	}
+
+	flagRollbackDestroyClones = true
+	flagRollbackSafetyDataset = "pool/before-rollback"
+	result, err = run()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !result.RolledBack || !strings.HasPrefix(result.Safety, "pool/before-rollback@rollback-safety-") {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	names, err := sim.List(ctx, zfs.ListOptions{Dataset: "pool", Recursive: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(names) != 2 || names[0] != result.Safety || names[1] != "pool/data@a" {
+		t.Errorf("Expected only the safety copy and the target to remain, got %v", names)
+	}
+	bookmarks, err := sim.Bookmarks(ctx, zfs.ListOptions{Dataset: "pool/data"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(bookmarks) != 1 || bookmarks[0].Name != "pool/data#a" {
+		t.Errorf("Expected only the bookmark of the target to remain, got %+v", bookmarks)
+	}
+	safety, err := sim.Get(ctx, result.Safety)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if safety.Referenced != 400 {
+		t.Errorf("Expected safety copy of the current state, got referenced=%d", safety.Referenced)
+	}
+}
+
+func TestNewerSnapshots(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshots := []*model.Snapshot{
+		{Name: "pool/data@b", Creation: base.Add(time.Hour)},
+		{Name: "pool/data@a", Creation: base},
+		{Name: "pool/data@c", Creation: base.Add(time.Hour), Clones: []string{"pool/clone"}},
+	}
+
+	newer, found := newerSnapshots(snapshots, "pool/data@a")
+	if !found || len(newer) != 2 || newer[0].Name != "pool/data@b" || newer[1].Name != "pool/data@c" {
+		t.Errorf("Unexpected newer snapshots: %v", newer)
+	}
+	if clones := snapshotClones(newer); len(clones) != 1 || clones[0] != "pool/clone" {
+		t.Errorf("Unexpected clones: %v", clones)
+	}
+
+	if newer, _ := newerSnapshots(snapshots, "pool/data@c"); len(newer) != 0 {
+		t.Errorf("Expected no snapshots newer than the latest, got %v", newer)
+	}
+	if _, found := newerSnapshots(snapshots, "pool/data@missing"); found {
+		t.Error("Expected missing snapshot not to be found")
+	}
+}
+
+func TestRollbackCommandErrors(t *testing.T) {
+	sim := testutil.NewSimulator(testutil.NewClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+	if err := sim.CreateDataset("pool/data"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if err := sim.Create(context.Background(), "pool/data", name, zfs.CreateOptions{}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		sim.Clock().Advance(time.Hour)
+	}
+
+	original := newSnapshotter
+	newSnapshotter = func() snapshotter { return sim }
+	t.Cleanup(func() {
+		newSnapshotter = original
+		flagRollbackSafetyDataset = ""
+	})
+
+	tests := []struct {
+		name     string
+		snapshot string
+		safety   string
+		expected int
+	}{
+		{name: "invalid snapshot", snapshot: "pool/data", expected: exitUsage},
+		{name: "invalid safety dataset", snapshot: "pool/data@a", safety: "pool/data@copy", expected: exitUsage},
+		{name: "newer snapshots not confirmed", snapshot: "pool/data@a", expected: exitNotConfirmed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flagRollbackSafetyDataset = tt.safety
+			rollbackCmd.SetOut(&bytes.Buffer{})
+			err := rollbackCmd.RunE(rollbackCmd, []string{tt.snapshot})
+			if code := exitCode(err); code != tt.expected {
+				t.Errorf("Expected exit code %d, got %d (%v)", tt.expected, code, err)
+			}
+		})
+	}
+	if !sim.Exists("pool/data@b") {
+		t.Error("Expected nothing to be destroyed")
+	}
+}
+
+func TestNewerBookmarks(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshots := []*model.Snapshot{
+		{Name: "pool/data@a", Creation: base, GUID: 1},
+		{Name: "pool/data@b", Creation: base, GUID: 2},
+	}
+	bookmarks := []*model.Bookmark{
+		{Name: "pool/data#later", Creation: base.Add(time.Hour), GUID: 3},
+		{Name: "pool/data#a", Creation: base, GUID: 1},
+		{Name: "pool/data#b", Creation: base, GUID: 2},
+		{Name: "pool/data#earlier", Creation: base.Add(-time.Hour), GUID: 4},
+	}
+
+	if names := newerBookmarks(bookmarks, snapshots, "pool/data@a"); strings.Join(names, ",") != "pool/data#b,pool/data#later" {
+		t.Errorf("Unexpected newer bookmarks: %v", names)
+	}
+	if names := newerBookmarks(bookmarks, snapshots, "pool/data@b"); strings.Join(names, ",") != "pool/data#later" {
+		t.Errorf("Unexpected newer bookmarks: %v", names)
+	}
+}
@@ -4,14 +4,23 @@ package main
 import (
 	"github.com/jsirianni/zfssnap/internal/version"
 	"github.com/spf13/cobra"
-	"go.uber.org/zap"
 )
 
+// versionResult is the result printed by the version command.
+type versionResult struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Show version information",
-	RunE: func(_ *cobra.Command, _ []string) error {
-		appLogger.Info("version", zap.String("version", version.Version()))
-		return nil
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return writeOutput(versionResult{
+			Version:   version.Semver(),
+			Commit:    version.CommitHash(),
+			BuildTime: version.BuildTime(),
+		}, cmd.OutOrStdout())
 	},
 }
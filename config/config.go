@@ -0,0 +1,343 @@
+// Package config loads and validates the zfssnap YAML configuration file.
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jsirianni/zfssnap/naming"
+	"github.com/jsirianni/zfssnap/retention"
+	"github.com/jsirianni/zfssnap/schedule"
+	"github.com/jsirianni/zfssnap/zfs"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultSnapshotName is the base name of scheduled snapshots when the
+// naming section does not set one.
+const DefaultSnapshotName = "auto"
+
+// Config is the top level configuration document.
+type Config struct {
+	Datasets []Dataset
+}
+
+// Dataset holds the settings for a single dataset. A dataset can be listed
+// only once, so it has at most one schedule.
+type Dataset struct {
+	// Name of the dataset, e.g. "pool/data".
+	Name string
+
+	// Schedule on which the daemon snapshots the dataset, see schedule.Parse.
+	// Empty means the dataset is not snapshotted on a schedule.
+	Schedule string
+
+	// Naming controls the names of scheduled snapshots.
+	Naming Naming
+
+	// Recursive snapshots all descendent datasets atomically.
+	Recursive bool
+
+	// Exclude lists descendent datasets whose snapshots are removed after a
+	// recursive snapshot. Each excluded dataset's own descendents are
+	// excluded as well.
+	Exclude []string
+
+	// Retention is the policy applied by `zfssnap prune`. Nil means
+	// snapshots of the dataset are never pruned.
+	Retention *retention.Policy
+
+	// MaxAge is the age beyond which the newest snapshot of the dataset is
+	// reported as stale by the daemon health check. Zero disables the check.
+	MaxAge time.Duration
+}
+
+// Naming controls the names of scheduled snapshots, see naming.Apply. A
+// Template names them instead, see naming.ParseTemplate, with Name as its
+// {label}.
+type Naming struct {
+	Name     string
+	Prefix   string
+	Suffix   string
+	Template string
+}
+
+// Error is a configuration error at a position in a file.
+type Error struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// Errors is the list of all errors found in a configuration file.
+type Errors []Error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Load reads and parses the configuration file at path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is provided by the operator
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	return Parse(path, data)
+}
+
+// Parse parses and validates a configuration document. File is used only
+// to annotate errors. All errors found are returned together as Errors.
+func Parse(file string, data []byte) (*Config, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	d := &decoder{file: file}
+	cfg := &Config{}
+	if len(root.Content) > 0 {
+		d.config(root.Content[0], cfg)
+	}
+	if len(d.errs) > 0 {
+		return nil, d.errs
+	}
+	return cfg, nil
+}
+
+// decoder walks the YAML node tree so every error can be reported with
+// the position of the offending node, rather than stopping at the first.
+type decoder struct {
+	file string
+	errs Errors
+}
+
+func (d *decoder) errorf(n *yaml.Node, format string, args ...any) {
+	d.errs = append(d.errs, Error{
+		File:    d.file,
+		Line:    n.Line,
+		Column:  n.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// mapping calls fn for each key of a mapping node, reporting keys that are
+// not in allowed.
+func (d *decoder) mapping(n *yaml.Node, what string, allowed []string, fn func(key string, value *yaml.Node)) {
+	if n.Kind != yaml.MappingNode {
+		d.errorf(n, "%s must be a mapping", what)
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if !contains(allowed, k.Value) {
+			d.errorf(k, "unknown field %q in %s", k.Value, what)
+			continue
+		}
+		fn(k.Value, v)
+	}
+}
+
+func (d *decoder) scalar(n *yaml.Node, key string, out any) {
+	if n.Kind != yaml.ScalarNode {
+		d.errorf(n, "%s must be a scalar", key)
+		return
+	}
+	if err := n.Decode(out); err != nil {
+		d.errorf(n, "invalid %s: %s", key, strings.TrimPrefix(err.Error(), "yaml: unmarshal errors:\n  "))
+	}
+}
+
+func (d *decoder) config(n *yaml.Node, cfg *Config) {
+	d.mapping(n, "config", []string{"datasets"}, func(_ string, v *yaml.Node) {
+		if v.Kind != yaml.SequenceNode {
+			d.errorf(v, "datasets must be a list")
+			return
+		}
+		seen := make(map[string]*yaml.Node)
+		for _, item := range v.Content {
+			ds, nameNode := d.dataset(item)
+			if nameNode != nil {
+				if prev, ok := seen[ds.Name]; ok {
+					d.errorf(nameNode, "duplicate dataset %q (first defined at line %d)", ds.Name, prev.Line)
+				} else {
+					seen[ds.Name] = nameNode
+				}
+			}
+			cfg.Datasets = append(cfg.Datasets, ds)
+		}
+	})
+}
+
+var datasetFields = []string{"name", "schedule", "naming", "recursive", "exclude", "retention", "max_age"}
+
+// dataset decodes and validates a dataset entry. It returns the node of
+// the name field so duplicates can be reported.
+func (d *decoder) dataset(n *yaml.Node) (Dataset, *yaml.Node) {
+	ds := Dataset{Naming: Naming{Name: DefaultSnapshotName}}
+	var nameNode, scheduleNode, excludeNode *yaml.Node
+	var excludeItems []*yaml.Node
+
+	d.mapping(n, "dataset", datasetFields, func(key string, v *yaml.Node) {
+		switch key {
+		case "name":
+			nameNode = v
+			d.scalar(v, key, &ds.Name)
+		case "schedule":
+			scheduleNode = v
+			d.scalar(v, key, &ds.Schedule)
+		case "naming":
+			d.naming(v, &ds.Naming)
+		case "recursive":
+			d.scalar(v, key, &ds.Recursive)
+		case "exclude":
+			excludeNode = v
+			if v.Kind != yaml.SequenceNode {
+				d.errorf(v, "exclude must be a list")
+				return
+			}
+			for _, item := range v.Content {
+				var name string
+				d.scalar(item, "exclude", &name)
+				ds.Exclude = append(ds.Exclude, name)
+				excludeItems = append(excludeItems, item)
+			}
+		case "retention":
+			ds.Retention = d.retention(v)
+		case "max_age":
+			d.duration(v, key, &ds.MaxAge)
+		}
+	})
+
+	if n.Kind != yaml.MappingNode {
+		return ds, nil
+	}
+	if nameNode == nil {
+		d.errorf(n, "dataset name is required")
+	} else if !zfs.IsValidDatasetName(ds.Name) {
+		d.errorf(nameNode, "invalid dataset name format: %s", ds.Name)
+	}
+	if scheduleNode != nil {
+		if _, err := schedule.Parse(ds.Schedule); err != nil {
+			d.errorf(scheduleNode, "%v", err)
+		}
+	}
+	if excludeNode != nil && !ds.Recursive && len(ds.Exclude) > 0 {
+		d.errorf(excludeNode, "exclude requires recursive: true")
+	}
+	for i, name := range ds.Exclude {
+		switch {
+		case !zfs.IsValidDatasetName(name):
+			d.errorf(excludeItems[i], "invalid dataset name format: %s", name)
+		case !strings.HasPrefix(name, ds.Name+"/"):
+			d.errorf(excludeItems[i], "excluded dataset %q is not a descendent of %q", name, ds.Name)
+		}
+	}
+	return ds, nameNode
+}
+
+// duration decodes a positive duration such as "90m" or "2h".
+func (d *decoder) duration(n *yaml.Node, key string, out *time.Duration) {
+	var s string
+	d.scalar(n, key, &s)
+	if n.Kind != yaml.ScalarNode {
+		return
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		d.errorf(n, "invalid %s: %q is not a duration", key, s)
+		return
+	}
+	if v <= 0 {
+		d.errorf(n, "invalid %s: must be positive", key)
+		return
+	}
+	*out = v
+}
+
+func (d *decoder) naming(n *yaml.Node, out *Naming) {
+	var templateNode *yaml.Node
+	d.mapping(n, "naming", []string{"name", "prefix", "suffix", "template"}, func(key string, v *yaml.Node) {
+		switch key {
+		case "name":
+			d.scalar(v, key, &out.Name)
+		case "prefix":
+			d.scalar(v, key, &out.Prefix)
+		case "suffix":
+			d.scalar(v, key, &out.Suffix)
+		case "template":
+			templateNode = v
+			d.scalar(v, key, &out.Template)
+		}
+	})
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+
+	// Check a representative timestamped name so invalid characters in any
+	// part are caught at load time rather than on the first scheduled run.
+	sample := naming.Apply(out.Name, naming.Options{Prefix: out.Prefix, Suffix: out.Suffix, Timestamp: true}, time.Now())
+	if templateNode != nil {
+		tmpl, err := naming.ParseTemplate(out.Template)
+		if err != nil {
+			d.errorf(templateNode, "%v", err)
+			return
+		}
+		if !tmpl.HasDate() {
+			d.errorf(templateNode, "naming template %q has no date field", out.Template)
+			return
+		}
+		hostname, _ := naming.Hostname()
+		sample = tmpl.Format(naming.Values{
+			Prefix:   out.Prefix,
+			Suffix:   out.Suffix,
+			Label:    out.Name,
+			Hostname: hostname,
+			Sequence: 1,
+			Time:     time.Now(),
+		})
+	}
+	if !zfs.IsValidSnapshotComponent(sample) {
+		d.errorf(n, "naming produces invalid snapshot name %q", sample)
+	}
+}
+
+func (d *decoder) retention(n *yaml.Node) *retention.Policy {
+	policy := &retention.Policy{}
+	fields := map[string]*int{
+		"latest":  &policy.Latest,
+		"hourly":  &policy.Hourly,
+		"daily":   &policy.Daily,
+		"weekly":  &policy.Weekly,
+		"monthly": &policy.Monthly,
+		"yearly":  &policy.Yearly,
+	}
+	d.mapping(n, "retention", []string{"latest", "hourly", "daily", "weekly", "monthly", "yearly"}, func(key string, v *yaml.Node) {
+		d.scalar(v, key, fields[key])
+	})
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	if err := policy.Validate(); err != nil {
+		d.errorf(n, "invalid retention: %v", err)
+	}
+	return policy
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
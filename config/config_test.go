@@ -0,0 +1,250 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	data := `
+datasets:
+  - name: pool/data
+    schedule: "@hourly"
+    naming:
+      name: hourly
+      prefix: zfssnap
+    recursive: true
+    exclude:
+      - pool/data/tmp
+    retention:
+      hourly: 24
+      daily: 7
+    max_age: 90m
+  - name: pool/home
+`
+	cfg, err := Parse("zfssnap.yaml", []byte(data))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(cfg.Datasets) != 2 {
+		t.Fatalf("Expected 2 datasets, got %d", len(cfg.Datasets))
+	}
+
+	ds := cfg.Datasets[0]
+	if ds.Name != "pool/data" || ds.Schedule != "@hourly" || !ds.Recursive {
+		t.Errorf("Unexpected dataset: %+v", ds)
+	}
+	if ds.Naming.Name != "hourly" || ds.Naming.Prefix != "zfssnap" {
+		t.Errorf("Unexpected naming: %+v", ds.Naming)
+	}
+	if len(ds.Exclude) != 1 || ds.Exclude[0] != "pool/data/tmp" {
+		t.Errorf("Unexpected exclude: %v", ds.Exclude)
+	}
+	if ds.Retention == nil || ds.Retention.Hourly != 24 || ds.Retention.Daily != 7 {
+		t.Errorf("Unexpected retention: %+v", ds.Retention)
+	}
+	if ds.MaxAge != 90*time.Minute {
+		t.Errorf("Expected max age 90m, got %v", ds.MaxAge)
+	}
+
+	defaults := cfg.Datasets[1]
+	if defaults.Naming.Name != DefaultSnapshotName {
+		t.Errorf("Expected default name %q, got %q", DefaultSnapshotName, defaults.Naming.Name)
+	}
+	if defaults.Retention != nil {
+		t.Errorf("Expected no retention, got %+v", defaults.Retention)
+	}
+	if defaults.MaxAge != 0 {
+		t.Errorf("Expected no max age, got %v", defaults.MaxAge)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected []string
+	}{
+		{
+			name: "invalid dataset name",
+			data: `datasets:
+  - name: 123pool
+`,
+			expected: []string{"test.yaml:2:11: invalid dataset name format: 123pool"},
+		},
+		{
+			name: "missing name",
+			data: `datasets:
+  - schedule: "@daily"
+`,
+			expected: []string{"test.yaml:2:5: dataset name is required"},
+		},
+		{
+			name: "unknown field",
+			data: `datasets:
+  - name: pool/data
+    schedul: "@daily"
+`,
+			expected: []string{`test.yaml:3:5: unknown field "schedul" in dataset`},
+		},
+		{
+			name: "invalid schedule",
+			data: `datasets:
+  - name: pool/data
+    schedule: "61 * * * *"
+`,
+			expected: []string{"test.yaml:3:15: invalid cron expression"},
+		},
+		{
+			name: "wrong type",
+			data: `datasets:
+  - name: pool/data
+    recursive: maybe
+`,
+			expected: []string{"test.yaml:3:16: invalid recursive"},
+		},
+		{
+			name: "exclude outside dataset",
+			data: `datasets:
+  - name: pool/data
+    recursive: true
+    exclude:
+      - pool/other
+`,
+			expected: []string{`test.yaml:5:9: excluded dataset "pool/other" is not a descendent of "pool/data"`},
+		},
+		{
+			name: "exclude without recursive",
+			data: `datasets:
+  - name: pool/data
+    exclude: [pool/data/tmp]
+`,
+			expected: []string{"test.yaml:3:14: exclude requires recursive: true"},
+		},
+		{
+			name: "invalid naming",
+			data: `datasets:
+  - name: pool/data
+    naming:
+      name: 1hourly
+`,
+			expected: []string{"test.yaml:4:7: naming produces invalid snapshot name"},
+		},
+		{
+			name: "invalid naming template",
+			data: `datasets:
+  - name: pool/data
+    naming:
+      template: "{label}_{date:%Q}"
+`,
+			expected: []string{`test.yaml:4:17: invalid naming template "{label}_{date:%Q}": unsupported date directive %Q`},
+		},
+		{
+			name: "naming template without date",
+			data: `datasets:
+  - name: pool/data
+    naming:
+      template: "{label}-{seq}"
+`,
+			expected: []string{`test.yaml:4:17: naming template "{label}-{seq}" has no date field`},
+		},
+		{
+			name: "empty retention",
+			data: `datasets:
+  - name: pool/data
+    retention: {daily: 0}
+`,
+			expected: []string{"test.yaml:3:16: invalid retention: policy keeps no snapshots"},
+		},
+		{
+			name: "invalid max age",
+			data: `datasets:
+  - name: pool/data
+    max_age: 2 hours
+`,
+			expected: []string{`test.yaml:3:14: invalid max_age: "2 hours" is not a duration`},
+		},
+		{
+			name: "negative max age",
+			data: `datasets:
+  - name: pool/data
+    max_age: -1h
+`,
+			expected: []string{"test.yaml:3:14: invalid max_age: must be positive"},
+		},
+		{
+			name: "duplicate dataset",
+			data: `datasets:
+  - name: pool/data
+  - name: pool/data
+`,
+			expected: []string{`test.yaml:3:11: duplicate dataset "pool/data" (first defined at line 2)`},
+		},
+		{
+			name: "all errors are reported",
+			data: `datasets:
+  - name: 123pool
+  - name: pool/data
+    schedule: never
+`,
+			expected: []string{
+				"test.yaml:2:11: invalid dataset name format",
+				"test.yaml:4:15: invalid cron expression",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse("test.yaml", []byte(tt.data))
+			if err == nil {
+				t.Fatal("Expected error but got none")
+			}
+			var errs Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("Expected Errors, got %T: %v", err, err)
+			}
+			if len(errs) != len(tt.expected) {
+				t.Fatalf("Expected %d errors, got %d: %v", len(tt.expected), len(errs), err)
+			}
+			for i, expected := range tt.expected {
+				if !strings.HasPrefix(errs[i].Error(), expected) {
+					t.Errorf("Expected error starting with %q, got %q", expected, errs[i].Error())
+				}
+			}
+		})
+	}
+}
+
+func TestParseSyntaxError(t *testing.T) {
+	_, err := Parse("test.yaml", []byte("datasets: [\n"))
+	if err == nil {
+		t.Fatal("Expected error but got none")
+	}
+	if !strings.Contains(err.Error(), "test.yaml") || !strings.Contains(err.Error(), "line") {
+		t.Errorf("Expected file and line in error, got %q", err.Error())
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zfssnap.yaml")
+	if err := os.WriteFile(path, []byte("datasets:\n  - name: pool/data\n"), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(cfg.Datasets) != 1 {
+		t.Errorf("Expected 1 dataset, got %d", len(cfg.Datasets))
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
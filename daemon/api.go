@@ -0,0 +1,295 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jsirianni/zfssnap/zfs"
+	"go.uber.org/zap"
+)
+
+// maxRequestBody limits the size of API request bodies.
+const maxRequestBody = 1 << 20
+
+// createRequest is the body of POST /api/v1/snapshots.
+type createRequest struct {
+	Dataset    string            `json:"dataset"`
+	Name       string            `json:"name"`
+	Recursive  bool              `json:"recursive"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// holdRequest is the body of POST /api/v1/snapshots/{name}/hold and
+// /release.
+type holdRequest struct {
+	Tag       string `json:"tag"`
+	Recursive bool   `json:"recursive"`
+}
+
+// deleteResponse is the body returned by DELETE /api/v1/snapshots/{name}.
+type deleteResponse struct {
+	Destroyed []string `json:"destroyed"`
+	Reclaimed uint64   `json:"reclaimed"`
+	DryRun    bool     `json:"dry_run"`
+}
+
+// errorResponse is the body returned with every API error.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// api serves the versioned snapshot API under /api/v1.
+type api struct {
+	snapshots zfs.Snapshotter
+	mutations bool
+	logger    *zap.Logger
+}
+
+// register adds the API routes to mux. Mutating routes answer 403 unless
+// mutations are enabled.
+func (a *api) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/snapshots", a.list)
+	mux.HandleFunc("GET /api/v1/snapshots/{name...}", a.get)
+	mux.HandleFunc("POST /api/v1/snapshots", a.mutating(a.create))
+	mux.HandleFunc("DELETE /api/v1/snapshots/{name...}", a.mutating(a.destroy))
+	mux.HandleFunc("POST /api/v1/snapshots/{name...}", a.mutating(a.action))
+}
+
+// mutating rejects requests to h unless mutations are enabled.
+func (a *api) mutating(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.mutations {
+			writeError(w, http.StatusForbidden, fmt.Errorf("snapshot mutations are disabled"))
+			return
+		}
+		h(w, r)
+	}
+}
+
+// list serves GET /api/v1/snapshots?dataset=&recursive=.
+func (a *api) list(w http.ResponseWriter, r *http.Request) {
+	opts := zfs.ListOptions{Dataset: r.URL.Query().Get("dataset")}
+	recursive, err := queryBool(r, "recursive")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	opts.Recursive = recursive
+	if opts.Dataset != "" && !zfs.IsValidDatasetName(opts.Dataset) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid dataset name format: %s", opts.Dataset))
+		return
+	}
+
+	snapshots, err := a.snapshots.ListDetailed(r.Context(), opts)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshots)
+}
+
+// get serves GET /api/v1/snapshots/{name}.
+func (a *api) get(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !zfs.IsValidSnapshotName(name) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid snapshot name format: %s (must contain @)", name))
+		return
+	}
+
+	snapshot, err := a.snapshots.Get(r.Context(), name)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// create serves POST /api/v1/snapshots.
+func (a *api) create(w http.ResponseWriter, r *http.Request) {
+	var req createRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !zfs.IsValidDatasetName(req.Dataset) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid dataset name format: %s", req.Dataset))
+		return
+	}
+	if !zfs.IsValidSnapshotComponent(req.Name) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid snapshot name: %s", req.Name))
+		return
+	}
+
+	ctx := r.Context()
+	opts := zfs.CreateOptions{Recursive: req.Recursive, Properties: req.Properties}
+	if err := a.snapshots.Create(ctx, req.Dataset, req.Name, opts); err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	name := req.Dataset + "@" + req.Name
+	a.logger.Info("snapshot created via API",
+		zap.String("snapshot", name),
+		zap.Bool("recursive", req.Recursive),
+		zap.String("token", tokenName(ctx)))
+
+	snapshot, err := a.snapshots.Get(ctx, name)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	w.Header().Set("Location", "/api/v1/snapshots/"+name)
+	writeJSON(w, http.StatusCreated, snapshot)
+}
+
+// destroy serves DELETE /api/v1/snapshots/{name}?recursive=&defer=&dry_run=.
+func (a *api) destroy(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !zfs.IsValidSnapshotName(name) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid snapshot name format: %s (must contain @)", name))
+		return
+	}
+	var opts zfs.DeleteOptions
+	for key, out := range map[string]*bool{"recursive": &opts.Recursive, "defer": &opts.Defer, "dry_run": &opts.DryRun} {
+		v, err := queryBool(r, key)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		*out = v
+	}
+
+	result, err := a.snapshots.Delete(r.Context(), name, opts)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	if !opts.DryRun {
+		a.logger.Info("snapshot destroyed via API",
+			zap.String("snapshot", name),
+			zap.Strings("destroyed", result.Destroyed),
+			zap.String("token", tokenName(r.Context())))
+	}
+	resp := deleteResponse{Destroyed: result.Destroyed, Reclaimed: result.Reclaimed, DryRun: opts.DryRun}
+	if resp.Destroyed == nil {
+		resp.Destroyed = []string{}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// action serves POST /api/v1/snapshots/{name}/hold and /release. The
+// action is the path element after the snapshot, which cannot contain '/'.
+func (a *api) action(w http.ResponseWriter, r *http.Request) {
+	path := r.PathValue("name")
+	i := strings.LastIndex(path, "/")
+	if i < 0 || !strings.Contains(path[:i], "@") {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown snapshot action: %s", path))
+		return
+	}
+	name, action := path[:i], path[i+1:]
+	if action != "hold" && action != "release" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown snapshot action: %s", action))
+		return
+	}
+	if !zfs.IsValidSnapshotName(name) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid snapshot name format: %s (must contain @)", name))
+		return
+	}
+	holder, ok := a.snapshots.(zfs.Holder)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, fmt.Errorf("holds are not supported"))
+		return
+	}
+
+	var req holdRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(req.Tag) == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("hold tag is required"))
+		return
+	}
+
+	ctx := r.Context()
+	opts := zfs.HoldOptions{Recursive: req.Recursive}
+	var err error
+	if action == "hold" {
+		err = holder.Hold(ctx, name, req.Tag, opts)
+	} else {
+		err = holder.Release(ctx, name, req.Tag, opts)
+	}
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	a.logger.Info("snapshot "+action+" via API",
+		zap.String("snapshot", name),
+		zap.String("tag", req.Tag),
+		zap.String("token", tokenName(ctx)))
+
+	holds, err := holder.Holds(ctx, name, opts)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, holds)
+}
+
+// writeJSON writes v as the JSON response body with status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as an errorResponse with status.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+// decodeBody decodes a JSON request body into v, rejecting unknown fields.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	if dec.More() {
+		return errors.New("invalid request body: unexpected data after JSON object")
+	}
+	return nil
+}
+
+// queryBool parses an optional boolean query parameter.
+func queryBool(r *http.Request, key string) (bool, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %q is not a boolean", key, v)
+	}
+	return b, nil
+}
+
+// errorStatus maps a zfs error to an HTTP status by its classification.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, zfs.ErrInvalidArgument):
+		return http.StatusBadRequest
+	case errors.Is(err, zfs.ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, zfs.ErrDatasetNotFound), errors.Is(err, zfs.ErrSnapshotNotFound), errors.Is(err, zfs.ErrHoldNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, zfs.ErrSnapshotExists), errors.Is(err, zfs.ErrBusy):
+		return http.StatusConflict
+	case errors.Is(err, zfs.ErrTimeout):
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,245 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+	"github.com/jsirianni/zfssnap/testutil"
+	"github.com/jsirianni/zfssnap/zfs"
+	"go.uber.org/zap"
+)
+
+func newAPIDaemon(t *testing.T, mutations bool) (*Daemon, *testutil.Simulator) {
+	t.Helper()
+
+	sim := testutil.NewSimulator(testutil.NewClock(time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)))
+	for _, ds := range []string{"pool", "pool/data", "pool/home"} {
+		if err := sim.CreateDataset(ds); err != nil {
+			t.Fatalf("CreateDataset: %v", err)
+		}
+	}
+	for _, snap := range [][2]string{{"pool/data", "a"}, {"pool/data", "b"}, {"pool/home", "a"}} {
+		if err := sim.Create(context.Background(), snap[0], snap[1], zfs.CreateOptions{}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	d, err := New(context.Background(), "", "", zap.NewNop(), WithSnapshotter(sim), WithAPIMutations(mutations))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return d, sim
+}
+
+func apiRequest(t *testing.T, d *Daemon, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	d.handler().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s %s: expected JSON content type, got %q", method, path, ct)
+	}
+	return rec
+}
+
+func decodeResponse[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("Unmarshal %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+func TestAPIRead(t *testing.T) {
+	d, _ := newAPIDaemon(t, false)
+
+	tests := []struct {
+		name     string
+		path     string
+		status   int
+		expected []string
+	}{
+		{name: "list all", path: "/api/v1/snapshots", status: http.StatusOK, expected: []string{"pool/data@a", "pool/data@b", "pool/home@a"}},
+		{name: "list dataset", path: "/api/v1/snapshots?dataset=pool/home", status: http.StatusOK, expected: []string{"pool/home@a"}},
+		{name: "list recursive", path: "/api/v1/snapshots?dataset=pool&recursive=true", status: http.StatusOK, expected: []string{"pool/data@a", "pool/data@b", "pool/home@a"}},
+		{name: "invalid recursive", path: "/api/v1/snapshots?recursive=maybe", status: http.StatusBadRequest},
+		{name: "invalid dataset", path: "/api/v1/snapshots?dataset=123pool", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := apiRequest(t, d, http.MethodGet, tt.path, "")
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				if decodeResponse[errorResponse](t, rec).Error == "" {
+					t.Error("Expected error message")
+				}
+				return
+			}
+			snapshots := decodeResponse[[]*model.Snapshot](t, rec)
+			var names []string
+			for _, s := range snapshots {
+				names = append(names, s.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+
+	rec := apiRequest(t, d, http.MethodGet, "/api/v1/snapshots/pool/data@b", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if s := decodeResponse[model.Snapshot](t, rec); s.Name != "pool/data@b" || s.Dataset != "pool/data" {
+		t.Errorf("Unexpected snapshot: %+v", s)
+	}
+
+	if rec := apiRequest(t, d, http.MethodGet, "/api/v1/snapshots/pool/data@missing", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for missing snapshot, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := apiRequest(t, d, http.MethodGet, "/api/v1/snapshots/pool/data", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for dataset name, got %d", rec.Code)
+	}
+}
+
+func TestAPIMutationsDisabled(t *testing.T) {
+	d, sim := newAPIDaemon(t, false)
+
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodPost, "/api/v1/snapshots", `{"dataset":"pool/data","name":"deploy"}`},
+		{http.MethodDelete, "/api/v1/snapshots/pool/data@a", ""},
+		{http.MethodPost, "/api/v1/snapshots/pool/data@a/hold", `{"tag":"keep"}`},
+		{http.MethodPost, "/api/v1/snapshots/pool/data@a/release", `{"tag":"keep"}`},
+	} {
+		rec := apiRequest(t, d, req.method, req.path, req.body)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s: expected status 403, got %d", req.method, req.path, rec.Code)
+		}
+	}
+	if sim.Exists("pool/data@deploy") || !sim.Exists("pool/data@a") {
+		t.Error("Expected snapshots to be unchanged")
+	}
+}
+
+func TestAPIMutations(t *testing.T) {
+	d, sim := newAPIDaemon(t, true)
+
+	// Create
+	rec := apiRequest(t, d, http.MethodPost, "/api/v1/snapshots", `{"dataset":"pool/data","name":"deploy","recursive":false}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if loc := rec.Header().Get("Location"); loc != "/api/v1/snapshots/pool/data@deploy" {
+		t.Errorf("Unexpected Location %q", loc)
+	}
+	if s := decodeResponse[model.Snapshot](t, rec); s.Name != "pool/data@deploy" {
+		t.Errorf("Unexpected snapshot: %+v", s)
+	}
+	if !sim.Exists("pool/data@deploy") {
+		t.Error("Expected pool/data@deploy to exist")
+	}
+
+	for _, tt := range []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{name: "duplicate", body: `{"dataset":"pool/data","name":"deploy"}`, status: http.StatusConflict},
+		{name: "invalid dataset", body: `{"dataset":"123pool","name":"deploy"}`, status: http.StatusBadRequest},
+		{name: "invalid name", body: `{"dataset":"pool/data","name":"a@b"}`, status: http.StatusBadRequest},
+		{name: "unknown field", body: `{"dataset":"pool/data","name":"x","force":true}`, status: http.StatusBadRequest},
+		{name: "malformed", body: `{"dataset":`, status: http.StatusBadRequest},
+	} {
+		if rec := apiRequest(t, d, http.MethodPost, "/api/v1/snapshots", tt.body); rec.Code != tt.status {
+			t.Errorf("create %s: expected status %d, got %d: %s", tt.name, tt.status, rec.Code, rec.Body.String())
+		}
+	}
+
+	// Hold, and destroying the held snapshot fails.
+	rec = apiRequest(t, d, http.MethodPost, "/api/v1/snapshots/pool/data@deploy/hold", `{"tag":"keep"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if holds := decodeResponse[[]*model.Hold](t, rec); len(holds) != 1 || holds[0].Tag != "keep" {
+		t.Errorf("Unexpected holds: %+v", holds)
+	}
+	if rec := apiRequest(t, d, http.MethodDelete, "/api/v1/snapshots/pool/data@deploy", ""); rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for held snapshot, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := apiRequest(t, d, http.MethodPost, "/api/v1/snapshots/pool/data@deploy/hold", `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without tag, got %d", rec.Code)
+	}
+	if rec := apiRequest(t, d, http.MethodPost, "/api/v1/snapshots/pool/data@deploy/pin", `{"tag":"keep"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown action, got %d", rec.Code)
+	}
+
+	// Release, then destroy.
+	rec = apiRequest(t, d, http.MethodPost, "/api/v1/snapshots/pool/data@deploy/release", `{"tag":"keep"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if holds := decodeResponse[[]*model.Hold](t, rec); len(holds) != 0 {
+		t.Errorf("Expected no holds, got %+v", holds)
+	}
+	if rec := apiRequest(t, d, http.MethodPost, "/api/v1/snapshots/pool/data@deploy/release", `{"tag":"keep"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for missing tag, got %d", rec.Code)
+	}
+
+	rec = apiRequest(t, d, http.MethodDelete, "/api/v1/snapshots/pool/data@deploy?dry_run=true", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if resp := decodeResponse[deleteResponse](t, rec); !resp.DryRun || len(resp.Destroyed) != 1 || !sim.Exists("pool/data@deploy") {
+		t.Errorf("Unexpected dry run: %+v", resp)
+	}
+
+	rec = apiRequest(t, d, http.MethodDelete, "/api/v1/snapshots/pool/data@deploy", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if resp := decodeResponse[deleteResponse](t, rec); resp.DryRun || len(resp.Destroyed) != 1 || resp.Destroyed[0] != "pool/data@deploy" {
+		t.Errorf("Unexpected delete response: %+v", resp)
+	}
+	if sim.Exists("pool/data@deploy") {
+		t.Error("Expected pool/data@deploy to be destroyed")
+	}
+	if rec := apiRequest(t, d, http.MethodDelete, "/api/v1/snapshots/pool/data@deploy", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for destroyed snapshot, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	runner := testutil.NewFakeRunner(t)
+	runner.Expect("zfs", "holds", "-H", "-p", "pool/data@a").Fail(context.DeadlineExceeded)
+	_, timeout := zfs.NewSnapshot(zfs.WithRunner(runner)).Holds(context.Background(), "pool/data@a", zfs.HoldOptions{})
+
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{err: zfs.NewError(nil, "cannot open 'pool/missing': dataset does not exist", 1), expected: http.StatusNotFound},
+		{err: zfs.NewError(nil, "cannot hold snapshot 'pool/data@a': tag already exists on this dataset", 1), expected: http.StatusConflict},
+		{err: zfs.NewError(nil, "cannot create snapshot 'pool/data@a': permission denied", 1), expected: http.StatusForbidden},
+		{err: fmt.Errorf("create snapshot: %w", zfs.NewArgumentError("invalid property name: %q", "")), expected: http.StatusBadRequest},
+		{err: fmt.Errorf("invalid snapshot name format: pool"), expected: http.StatusInternalServerError},
+		{err: zfs.NewError(nil, "internal error: out of memory", 1), expected: http.StatusInternalServerError},
+		{err: timeout, expected: http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		if status := errorStatus(tt.err); status != tt.expected {
+			t.Errorf("%v: expected status %d, got %d", tt.err, tt.expected, status)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Scope grants a bearer token access to a group of endpoints.
+type Scope string
+
+// Scopes of bearer tokens. /health never requires a token.
+const (
+	// ScopeMetrics allows scraping /metrics.
+	ScopeMetrics Scope = "metrics"
+
+	// ScopeRead allows listing and reading snapshots with the API.
+	ScopeRead Scope = "read"
+
+	// ScopeWrite allows creating, destroying, holding and releasing
+	// snapshots with the API.
+	ScopeWrite Scope = "write"
+)
+
+// minTokenLength is the minimum length of a bearer token.
+const minTokenLength = 16
+
+// token is a bearer token loaded from the token file.
+type token struct {
+	name   string
+	scopes map[Scope]bool
+}
+
+// authenticator requires a bearer token with the scope of the requested
+// endpoint. Tokens are read from a file, see parseTokens.
+type authenticator struct {
+	file string
+
+	mu     sync.RWMutex
+	tokens map[[sha256.Size]byte]token
+}
+
+// tokenNameKey is the context key of the name of the authenticated token.
+type tokenNameKey struct{}
+
+// newAuthenticator loads the tokens in file.
+func newAuthenticator(file string) (*authenticator, error) {
+	a := &authenticator{file: file}
+	if err := a.reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// reload reads the token file again. The previous tokens are kept when the
+// file cannot be loaded.
+func (a *authenticator) reload() error {
+	data, err := os.ReadFile(a.file)
+	if err != nil {
+		return fmt.Errorf("read token file: %w", err)
+	}
+	tokens, err := parseTokens(data)
+	if err != nil {
+		return fmt.Errorf("token file %s: %w", a.file, err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tokens = tokens
+	return nil
+}
+
+// parseTokens parses a token file. Each line holds a token name, a comma
+// separated list of scopes and the token, separated by whitespace:
+//
+//	# name   scopes      token
+//	grafana  metrics     3f9a6c0e2b8d4f71a5c9
+//	deploy   read,write  8c1be4d07f2a9356b1e0
+//
+// Empty lines and lines starting with '#' are ignored. Tokens are stored by
+// their SHA-256 digest.
+func parseTokens(data []byte) (map[[sha256.Size]byte]token, error) {
+	tokens := make(map[[sha256.Size]byte]token)
+	names := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected <name> <scopes> <token>", line)
+		}
+		name, scopeList, secret := fields[0], fields[1], fields[2]
+
+		if names[name] {
+			return nil, fmt.Errorf("line %d: duplicate token name %q", line, name)
+		}
+		names[name] = true
+		if len(secret) < minTokenLength {
+			return nil, fmt.Errorf("line %d: token %q is shorter than %d characters", line, name, minTokenLength)
+		}
+
+		t := token{name: name, scopes: make(map[Scope]bool)}
+		for _, s := range strings.Split(scopeList, ",") {
+			switch scope := Scope(s); scope {
+			case ScopeMetrics, ScopeRead, ScopeWrite:
+				t.scopes[scope] = true
+			default:
+				return nil, fmt.Errorf("line %d: unknown scope %q", line, s)
+			}
+		}
+
+		digest := sha256.Sum256([]byte(secret))
+		if _, ok := tokens[digest]; ok {
+			return nil, fmt.Errorf("line %d: token %q is already used by another token", line, name)
+		}
+		tokens[digest] = t
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("no tokens defined")
+	}
+	return tokens, nil
+}
+
+// requiredScope returns the scope needed for a request and whether a token
+// is needed at all.
+func requiredScope(r *http.Request) (Scope, bool) {
+	switch {
+	case r.URL.Path == "/health":
+		return "", false
+	case r.URL.Path == "/metrics":
+		return ScopeMetrics, true
+	case strings.HasPrefix(r.URL.Path, "/api/"):
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			return ScopeRead, true
+		}
+		return ScopeWrite, true
+	}
+	// Unknown paths only require a valid token.
+	return "", true
+}
+
+// middleware rejects requests without a valid bearer token with 401 and
+// requests whose token lacks the required scope with 403.
+func (a *authenticator) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope, required := requiredScope(r)
+		if !required {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || secret == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="zfssnap"`)
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("bearer token required"))
+			return
+		}
+
+		a.mu.RLock()
+		t, ok := a.tokens[sha256.Sum256([]byte(secret))]
+		a.mu.RUnlock()
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="zfssnap", error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid bearer token"))
+			return
+		}
+		if scope != "" && !t.scopes[scope] {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="zfssnap", error="insufficient_scope", scope="%s"`, scope))
+			writeError(w, http.StatusForbidden, fmt.Errorf("token %s lacks the %s scope", t.name, scope))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tokenNameKey{}, t.name)))
+	})
+}
+
+// tokenName returns the name of the token that authenticated the request
+// of ctx, or "" when authentication is disabled.
+func tokenName(ctx context.Context) string {
+	name, _ := ctx.Value(tokenNameKey{}).(string)
+	return name
+}
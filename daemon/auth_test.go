@@ -0,0 +1,136 @@
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+const testTokens = `
+# name     scopes      token
+grafana    metrics     grafana-0123456789abcdef
+deploy     read,write  deploy-0123456789abcdef
+readonly   read        readonly-0123456789abcdef
+`
+
+func TestParseTokens(t *testing.T) {
+	tokens, err := parseTokens([]byte(testTokens))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tokens) != 3 {
+		t.Errorf("Expected 3 tokens, got %d", len(tokens))
+	}
+
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{name: "missing field", data: "deploy read\n", expected: "line 1: expected <name> <scopes> <token>"},
+		{name: "unknown scope", data: "deploy admin deploy-0123456789abcdef\n", expected: `line 1: unknown scope "admin"`},
+		{name: "short token", data: "deploy read secret\n", expected: `line 1: token "deploy" is shorter than 16 characters`},
+		{name: "duplicate name", data: "a read 0123456789abcdef\na read fedcba9876543210\n", expected: `line 2: duplicate token name "a"`},
+		{name: "duplicate token", data: "a read 0123456789abcdef\nb write 0123456789abcdef\n", expected: `line 2: token "b" is already used`},
+		{name: "empty", data: "# no tokens\n", expected: "no tokens defined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTokens([]byte(tt.data))
+			if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+				t.Errorf("Expected error starting with %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tokens")
+	if err := os.WriteFile(file, []byte(testTokens), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	d, err := New(context.Background(), "", "", zap.NewNop(),
+		WithSnapshotter(mockWithSnapshots()),
+		WithAPIMutations(true),
+		WithTokenFile(file),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	d.updateMetrics()
+	h := d.handler()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{name: "health is public", method: http.MethodGet, path: "/health", status: http.StatusOK},
+		{name: "metrics without token", method: http.MethodGet, path: "/metrics", status: http.StatusUnauthorized},
+		{name: "metrics with invalid token", method: http.MethodGet, path: "/metrics", token: "wrong-0123456789abcdef", status: http.StatusUnauthorized},
+		{name: "metrics with metrics scope", method: http.MethodGet, path: "/metrics", token: "grafana-0123456789abcdef", status: http.StatusOK},
+		{name: "metrics without metrics scope", method: http.MethodGet, path: "/metrics", token: "deploy-0123456789abcdef", status: http.StatusForbidden},
+		{name: "list with read scope", method: http.MethodGet, path: "/api/v1/snapshots", token: "readonly-0123456789abcdef", status: http.StatusOK},
+		{name: "list with metrics scope", method: http.MethodGet, path: "/api/v1/snapshots", token: "grafana-0123456789abcdef", status: http.StatusForbidden},
+		{name: "create with read scope", method: http.MethodPost, path: "/api/v1/snapshots", token: "readonly-0123456789abcdef", status: http.StatusForbidden},
+		{name: "create with write scope", method: http.MethodPost, path: "/api/v1/snapshots", token: "deploy-0123456789abcdef", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("{}"))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.status == http.StatusUnauthorized && !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Bearer") {
+				t.Errorf("Expected Bearer challenge, got %q", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	// Reloading replaces the tokens; a broken file keeps the previous ones.
+	if err := os.WriteFile(file, []byte("grafana metrics rotated-0123456789abcdef\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := d.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if err := os.WriteFile(file, []byte("broken\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := d.Reload(); err == nil {
+		t.Error("Expected reload error for broken token file")
+	}
+	for token, status := range map[string]int{
+		"grafana-0123456789abcdef": http.StatusUnauthorized,
+		"rotated-0123456789abcdef": http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != status {
+			t.Errorf("Token %s after reload: expected status %d, got %d", token, status, rec.Code)
+		}
+	}
+}
+
+func TestNewMissingTokenFile(t *testing.T) {
+	_, err := New(context.Background(), "", "", zap.NewNop(), WithTokenFile(filepath.Join(t.TempDir(), "missing")))
+	if err == nil {
+		t.Error("Expected error for missing token file")
+	}
+}
@@ -1,60 +1,157 @@
-// Package daemon provides daemon services with Prometheus metrics.
+// Package daemon provides daemon services with OpenTelemetry metrics,
+// exported to Prometheus and optionally OTLP, and scheduled snapshots.
 package daemon
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/jsirianni/zfssnap/zfs"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.uber.org/zap"
 )
 
-var (
-	// snapshotCountGauge tracks the total number of ZFS snapshots
-	snapshotCountGauge = prometheus.NewGauge(prometheus.GaugeOpts{
-		Name: "zfs_snapshot_count",
-		Help: "Total number of ZFS snapshots",
-	})
-)
-
-func init() {
-	// Register the Prometheus metrics
-	prometheus.MustRegister(snapshotCountGauge)
-}
-
-// Daemon represents a daemon service with Prometheus metrics.
+// Daemon represents a daemon service with OpenTelemetry metrics. Each Daemon
+// has its own meter provider and Prometheus registry.
 type Daemon struct {
-	snapshot   *zfs.Snapshot
+	snapshot   zfs.Snapshotter
 	httpServer *http.Server
 	logger     *zap.Logger
+
+	filter    DatasetFilter
+	checks    []FreshnessCheck
+	otlp      *OTLPConfig
+	mutations bool
+	tls       *TLSConfig
+	tokenFile string
+	certs     *certReloader
+	auth      *authenticator
+	metrics   *snapshotMetrics
+	health    *healthTracker
+	registry  *prometheus.Registry
+	provider  *sdkmetric.MeterProvider
+
+	jobs      []Job
+	scheduler *scheduler
+	cancel    context.CancelFunc
 }
 
-// New creates a new Daemon instance with Prometheus metrics.
-func New(_ context.Context, _, _ string, log *zap.Logger) (*Daemon, error) {
-	snapshotter := zfs.NewSnapshot()
+// Option configures the Daemon.
+type Option func(*Daemon)
+
+// WithSnapshotter sets the snapshotter used by the daemon. If not provided,
+// a zfs.Snapshot with default options is used.
+func WithSnapshotter(s zfs.Snapshotter) Option { return func(d *Daemon) { d.snapshot = s } }
+
+// WithDatasetFilter limits the per-dataset metrics to the datasets selected
+// by f. The total snapshot count is not affected.
+func WithDatasetFilter(f DatasetFilter) Option { return func(d *Daemon) { d.filter = f } }
+
+// WithFreshnessChecks sets the datasets whose newest snapshot must be
+// younger than a maximum age for /health to report the daemon healthy.
+func WithFreshnessChecks(checks ...FreshnessCheck) Option {
+	return func(d *Daemon) { d.checks = append(d.checks, checks...) }
+}
+
+// WithOTLP exports the daemon metrics to an OpenTelemetry collector in
+// addition to the Prometheus endpoint.
+func WithOTLP(cfg OTLPConfig) Option { return func(d *Daemon) { d.otlp = &cfg } }
+
+// WithAPIMutations enables the API endpoints that create, destroy, hold and
+// release snapshots. They are disabled by default.
+func WithAPIMutations(enabled bool) Option { return func(d *Daemon) { d.mutations = enabled } }
+
+// WithTLS serves HTTPS with the certificate and key files of cfg and, when
+// cfg.ClientCAFile is set, requires client certificates.
+func WithTLS(cfg TLSConfig) Option { return func(d *Daemon) { d.tls = &cfg } }
+
+// WithTokenFile requires a bearer token from file with the scope of the
+// requested endpoint, see Scope. /health remains unauthenticated.
+func WithTokenFile(file string) Option { return func(d *Daemon) { d.tokenFile = file } }
+
+// WithJobs sets the datasets snapshotted on a schedule by the daemon.
+func WithJobs(jobs ...Job) Option { return func(d *Daemon) { d.jobs = append(d.jobs, jobs...) } }
 
+// New creates a new Daemon instance. The service name and version are
+// attached to the exported metrics.
+func New(ctx context.Context, serviceName, version string, log *zap.Logger, opts ...Option) (*Daemon, error) {
 	daemon := &Daemon{
-		snapshot: snapshotter,
-		logger:   log,
+		logger: log,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(daemon)
+		}
+	}
+	if daemon.snapshot == nil {
+		daemon.snapshot = zfs.NewSnapshot()
+	}
+
+	if err := daemon.filter.validate(); err != nil {
+		return nil, fmt.Errorf("metrics dataset filter: %w", err)
+	}
+	daemon.metrics = newSnapshotMetrics(daemon.filter)
+	health, err := newHealthTracker(daemon.checks)
+	if err != nil {
+		return nil, fmt.Errorf("freshness checks: %w", err)
+	}
+	daemon.health = health
+
+	if daemon.tls != nil {
+		if daemon.certs, err = newCertReloader(*daemon.tls); err != nil {
+			return nil, err
+		}
+	}
+	if daemon.tokenFile != "" {
+		if daemon.auth, err = newAuthenticator(daemon.tokenFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(daemon.jobs) > 0 {
+		sched, err := newScheduler(daemon.snapshot, log, daemon.jobs)
+		if err != nil {
+			return nil, fmt.Errorf("create scheduler: %w", err)
+		}
+		daemon.scheduler = sched
+	}
+
+	daemon.registry = prometheus.NewRegistry()
+	daemon.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	daemon.provider, err = newMeterProvider(ctx, serviceName, version, daemon.registry, daemon.otlp)
+	if err != nil {
+		return nil, err
+	}
+	meter := daemon.provider.Meter(meterName)
+	if err := errors.Join(daemon.metrics.register(meter), daemon.health.register(meter)); err != nil {
+		_ = daemon.provider.Shutdown(ctx)
+		return nil, fmt.Errorf("register metrics: %w", err)
 	}
 
 	return daemon, nil
 }
 
-// updateSnapshotCount updates the Prometheus gauge with the current snapshot count
-func (d *Daemon) updateSnapshotCount() {
+// updateMetrics lists every snapshot and updates the snapshot metrics. The
+// previous values are kept when listing fails.
+func (d *Daemon) updateMetrics() {
 	ctx := context.Background()
-	snapshots, err := d.snapshot.List(ctx)
+	snapshots, err := d.snapshot.ListDetailed(ctx, zfs.ListOptions{})
+	d.health.record(snapshots, err)
 	if err != nil {
 		d.logger.Error("list snapshots", zap.Error(err))
 		return
 	}
 
-	snapshotCountGauge.Set(float64(len(snapshots)))
+	d.metrics.update(snapshots)
 }
 
 // startMetricUpdates starts a goroutine that periodically updates metrics
@@ -67,39 +164,48 @@ func (d *Daemon) startMetricUpdates(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			d.updateSnapshotCount()
+			d.updateMetrics()
 		}
 	}
 }
 
-// Start starts the HTTP server for metrics.
+// Start starts the HTTP server for metrics and the snapshot scheduler.
 func (d *Daemon) Start(ctx context.Context, addr string) error {
+	ctx, d.cancel = context.WithCancel(ctx)
+
+	if d.scheduler != nil {
+		go d.scheduler.run(ctx)
+		d.logger.Info("snapshot scheduler started", zap.Int("jobs", len(d.jobs)))
+	}
+
 	// Update metrics before starting server
-	d.updateSnapshotCount()
+	d.updateMetrics()
 
 	// Start periodic metric updates
 	go d.startMetricUpdates(ctx)
 
-	mux := http.NewServeMux()
-
-	// Use the proper Prometheus HTTP handler
-	mux.Handle("/metrics", promhttp.Handler())
-
-	// Add a health check endpoint
-	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprint(w, "OK")
-	})
-
 	d.httpServer = &http.Server{
 		Addr:              addr,
-		Handler:           mux,
+		Handler:           d.handler(),
 		ReadHeaderTimeout: 30 * time.Second,
 	}
-	d.logger.Info("HTTP server starting", zap.String("addr", addr+"/metrics"))
+	if d.certs != nil {
+		d.httpServer.TLSConfig = d.certs.tlsConfig()
+	}
+	d.logger.Info("HTTP server starting",
+		zap.String("addr", addr+"/metrics"),
+		zap.Bool("tls", d.certs != nil),
+		zap.Bool("client_certs", d.tls != nil && d.tls.ClientCAFile != ""),
+		zap.Bool("auth", d.auth != nil))
 
 	go func() {
-		if err := d.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if d.certs != nil {
+			err = d.httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = d.httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			d.logger.Error("HTTP server error", zap.Error(err))
 		}
 	}()
@@ -107,10 +213,52 @@ func (d *Daemon) Start(ctx context.Context, addr string) error {
 	return nil
 }
 
-// Stop stops the HTTP server.
+// handler returns the routes served by the daemon.
+func (d *Daemon) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.Handle("/metrics", promhttp.InstrumentMetricHandler(
+		d.registry, promhttp.HandlerFor(d.registry, promhttp.HandlerOpts{}),
+	))
+	mux.Handle("/health", d.health)
+
+	a := &api{snapshots: d.snapshot, mutations: d.mutations, logger: d.logger}
+	a.register(mux)
+
+	if d.auth != nil {
+		return d.auth.middleware(mux)
+	}
+	return mux
+}
+
+// Reload reads the TLS certificate, key and client CA files and the token
+// file again. Files that cannot be loaded keep their previous contents.
+func (d *Daemon) Reload() error {
+	var errs []error
+	if d.certs != nil {
+		errs = append(errs, d.certs.reload())
+	}
+	if d.auth != nil {
+		errs = append(errs, d.auth.reload())
+	}
+	return errors.Join(errs...)
+}
+
+// Stop stops the HTTP server, waits for in-flight scheduled snapshots and
+// flushes the metrics to the OTLP collector.
 func (d *Daemon) Stop(ctx context.Context) error {
+	if d.cancel != nil {
+		d.cancel()
+	}
+	if d.scheduler != nil {
+		d.scheduler.wait()
+	}
+	var errs []error
 	if d.httpServer != nil {
-		return d.httpServer.Shutdown(ctx)
+		errs = append(errs, d.httpServer.Shutdown(ctx))
 	}
-	return nil
+	if err := d.provider.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("shut down meter provider: %w", err))
+	}
+	return errors.Join(errs...)
 }
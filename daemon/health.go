@@ -0,0 +1,192 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+	"github.com/jsirianni/zfssnap/zfs"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Health statuses reported by the /health endpoint.
+const (
+	HealthOK      = "ok"
+	HealthFailing = "failing"
+	HealthStale   = "stale"
+	HealthMissing = "missing"
+)
+
+// FreshnessCheck requires the newest snapshot of a dataset to be younger
+// than MaxAge.
+type FreshnessCheck struct {
+	Dataset string
+	MaxAge  time.Duration
+}
+
+// HealthReport is the JSON document served by /health.
+type HealthReport struct {
+	// Status is HealthOK, or HealthFailing when the last snapshot listing
+	// failed or any dataset is stale or missing.
+	Status string `json:"status"`
+
+	// LastCollection is the time of the last successful snapshot listing.
+	LastCollection *time.Time `json:"last_collection,omitempty"`
+
+	// Error of the last snapshot listing, if it failed.
+	Error string `json:"error,omitempty"`
+
+	// Datasets holds the result of each freshness check.
+	Datasets []DatasetHealth `json:"datasets"`
+}
+
+// DatasetHealth is the result of a freshness check.
+type DatasetHealth struct {
+	Dataset string `json:"dataset"`
+
+	// Status is HealthOK, HealthStale when the newest snapshot is older
+	// than MaxAge, or HealthMissing when the dataset has no snapshots.
+	Status string `json:"status"`
+
+	MaxAgeSeconds float64 `json:"max_age_seconds"`
+
+	// LastSnapshot is the creation time of the newest snapshot.
+	LastSnapshot *time.Time `json:"last_snapshot,omitempty"`
+
+	// AgeSeconds is the age of the newest snapshot.
+	AgeSeconds float64 `json:"age_seconds,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// healthTracker records the outcome of snapshot listings and evaluates
+// freshness checks against them. Ages are computed when the report is
+// requested so a dataset becomes stale even when listings keep failing.
+type healthTracker struct {
+	checks []FreshnessCheck
+	now    func() time.Time
+
+	mu             sync.Mutex
+	lastCollection time.Time
+	lastErr        error
+	newest         map[string]time.Time
+}
+
+func newHealthTracker(checks []FreshnessCheck) (*healthTracker, error) {
+	seen := make(map[string]bool)
+	for _, c := range checks {
+		if !zfs.IsValidDatasetName(c.Dataset) {
+			return nil, fmt.Errorf("invalid dataset name format: %s", c.Dataset)
+		}
+		if c.MaxAge <= 0 {
+			return nil, fmt.Errorf("maximum snapshot age of dataset %s must be positive", c.Dataset)
+		}
+		if seen[c.Dataset] {
+			return nil, fmt.Errorf("duplicate freshness check for dataset %s", c.Dataset)
+		}
+		seen[c.Dataset] = true
+	}
+	return &healthTracker{
+		checks: checks,
+		now:    time.Now,
+		newest: make(map[string]time.Time),
+	}, nil
+}
+
+// record stores the result of a snapshot listing. The newest snapshots of
+// the previous successful listing are kept when err is not nil.
+func (h *healthTracker) record(snapshots []*model.Snapshot, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastErr = err
+	if err != nil {
+		return
+	}
+	h.lastCollection = h.now()
+	h.newest = make(map[string]time.Time)
+	for _, s := range snapshots {
+		if s.Creation.After(h.newest[s.Dataset]) {
+			h.newest[s.Dataset] = s.Creation
+		}
+	}
+}
+
+// report evaluates every freshness check.
+func (h *healthTracker) report() HealthReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	r := HealthReport{Status: HealthOK, Datasets: make([]DatasetHealth, 0, len(h.checks))}
+	if !h.lastCollection.IsZero() {
+		last := h.lastCollection
+		r.LastCollection = &last
+	}
+	if h.lastErr != nil {
+		r.Status = HealthFailing
+		r.Error = h.lastErr.Error()
+	} else if h.lastCollection.IsZero() {
+		r.Status = HealthFailing
+		r.Error = "snapshots have not been listed yet"
+	}
+
+	for _, c := range h.checks {
+		dh := DatasetHealth{Dataset: c.Dataset, Status: HealthOK, MaxAgeSeconds: c.MaxAge.Seconds()}
+		newest, ok := h.newest[c.Dataset]
+		if !ok {
+			dh.Status = HealthMissing
+			dh.Error = "no snapshots"
+		} else {
+			dh.LastSnapshot = &newest
+			age := now.Sub(newest)
+			dh.AgeSeconds = age.Seconds()
+			if age > c.MaxAge {
+				dh.Status = HealthStale
+				dh.Error = fmt.Sprintf("newest snapshot is older than %s", c.MaxAge)
+			}
+		}
+		if dh.Status != HealthOK {
+			r.Status = HealthFailing
+		}
+		r.Datasets = append(r.Datasets, dh)
+	}
+	return r
+}
+
+// ServeHTTP writes the health report, with status 503 when it is failing.
+func (h *healthTracker) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	r := h.report()
+	w.Header().Set("Content-Type", "application/json")
+	if r.Status != HealthOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(r)
+}
+
+// register creates the freshness instrument with a callback that evaluates
+// every freshness check.
+func (h *healthTracker) register(meter metric.Meter) error {
+	stale, err := meter.Int64ObservableGauge("zfs_dataset_snapshot_stale",
+		metric.WithDescription("Whether the newest snapshot of the dataset is older than its maximum age or missing (1) or not (0)"))
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for _, dh := range h.report().Datasets {
+			var v int64
+			if dh.Status != HealthOK {
+				v = 1
+			}
+			o.ObserveInt64(stale, v, metric.WithAttributes(attribute.String("dataset", dh.Dataset)))
+		}
+		return nil
+	}, stale)
+	return err
+}
@@ -0,0 +1,163 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+	"github.com/jsirianni/zfssnap/testutil"
+	"github.com/jsirianni/zfssnap/zfs"
+	"go.uber.org/zap"
+)
+
+func TestNewHealthTrackerValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		check FreshnessCheck
+	}{
+		{name: "invalid dataset", check: FreshnessCheck{Dataset: "123pool", MaxAge: time.Hour}},
+		{name: "zero max age", check: FreshnessCheck{Dataset: "pool/data"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newHealthTracker([]FreshnessCheck{tt.check}); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+
+	dup := FreshnessCheck{Dataset: "pool/data", MaxAge: time.Hour}
+	if _, err := newHealthTracker([]FreshnessCheck{dup, dup}); err == nil {
+		t.Error("Expected error for duplicate check")
+	}
+}
+
+func TestHealth(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC))
+	snapshots := []*model.Snapshot{
+		{Name: "pool/data@a", Dataset: "pool/data", Creation: clock.Now().Add(-3 * time.Hour)},
+		{Name: "pool/data@b", Dataset: "pool/data", Creation: clock.Now().Add(-30 * time.Minute)},
+		{Name: "pool/home@a", Dataset: "pool/home", Creation: clock.Now().Add(-10 * time.Minute)},
+	}
+	var listErr error
+	mock := testutil.NewMockSnapshotter().WithListDetailedFunc(func(_ context.Context, _ zfs.ListOptions) ([]*model.Snapshot, error) {
+		return snapshots, listErr
+	})
+
+	d, err := New(context.Background(), "", "", zap.NewNop(),
+		WithSnapshotter(mock),
+		WithFreshnessChecks(
+			FreshnessCheck{Dataset: "pool/data", MaxAge: time.Hour},
+			FreshnessCheck{Dataset: "pool/home", MaxAge: time.Hour},
+		),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	d.health.now = clock.Now
+
+	// Nothing has been listed before the first update.
+	report := getHealth(t, d, http.StatusServiceUnavailable)
+	if report.Error == "" || report.LastCollection != nil {
+		t.Errorf("Expected error and no collection before the first update: %+v", report)
+	}
+
+	d.updateMetrics()
+	report = getHealth(t, d, http.StatusOK)
+	if report.LastCollection == nil || !report.LastCollection.Equal(clock.Now()) {
+		t.Errorf("Unexpected last collection: %v", report.LastCollection)
+	}
+	if len(report.Datasets) != 2 {
+		t.Fatalf("Expected 2 datasets, got %+v", report.Datasets)
+	}
+	if dh := report.Datasets[0]; dh.Dataset != "pool/data" || dh.Status != HealthOK || dh.AgeSeconds != 1800 || dh.MaxAgeSeconds != 3600 {
+		t.Errorf("Unexpected dataset health: %+v", dh)
+	}
+	assertStale(t, d, map[string]float64{"pool/data": 0, "pool/home": 0})
+
+	// pool/data becomes stale as time passes without a new snapshot.
+	clock.Advance(45 * time.Minute)
+	report = getHealth(t, d, http.StatusServiceUnavailable)
+	if dh := report.Datasets[0]; dh.Status != HealthStale || dh.Error == "" {
+		t.Errorf("Expected pool/data to be stale: %+v", dh)
+	}
+	if dh := report.Datasets[1]; dh.Status != HealthOK {
+		t.Errorf("Expected pool/home to be ok: %+v", dh)
+	}
+	assertStale(t, d, map[string]float64{"pool/data": 1, "pool/home": 0})
+
+	// A failing listing fails the health check but keeps the last collection.
+	clock.Set(time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC))
+	listErr = errors.New("zfs list failed")
+	d.updateMetrics()
+	report = getHealth(t, d, http.StatusServiceUnavailable)
+	if report.Status != HealthFailing || report.Error != "zfs list failed" || report.LastCollection == nil {
+		t.Errorf("Unexpected report after failed listing: %+v", report)
+	}
+	if dh := report.Datasets[0]; dh.Status != HealthOK {
+		t.Errorf("Expected previous snapshots to be kept: %+v", dh)
+	}
+
+	// Destroying every snapshot of a dataset reports it missing.
+	listErr = nil
+	snapshots = snapshots[:2]
+	d.updateMetrics()
+	report = getHealth(t, d, http.StatusServiceUnavailable)
+	if report.Error != "" {
+		t.Errorf("Expected no listing error: %+v", report)
+	}
+	if dh := report.Datasets[1]; dh.Status != HealthMissing || dh.LastSnapshot != nil {
+		t.Errorf("Expected pool/home to be missing: %+v", dh)
+	}
+}
+
+func getHealth(t *testing.T, d *Daemon, expectedStatus int) HealthReport {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	d.health.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != expectedStatus {
+		t.Errorf("Expected status %d, got %d: %s", expectedStatus, rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON content type, got %q", ct)
+	}
+
+	var report HealthReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Unmarshal health report: %v", err)
+	}
+	return report
+}
+
+func assertStale(t *testing.T, d *Daemon, expected map[string]float64) {
+	t.Helper()
+
+	families, err := d.registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	got := make(map[string]float64)
+	for _, mf := range families {
+		if mf.GetName() != "zfs_dataset_snapshot_stale" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			got[m.GetLabel()[0].GetValue()] = m.GetGauge().GetValue()
+		}
+	}
+	if len(got) != len(expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	for dataset, want := range expected {
+		if got[dataset] != want {
+			t.Errorf("zfs_dataset_snapshot_stale{%s} = %v, expected %v", dataset, got[dataset], want)
+		}
+	}
+}
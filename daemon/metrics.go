@@ -0,0 +1,203 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// DatasetFilter selects the datasets that get per-dataset metrics, which
+// bounds the number of series exported on systems with many datasets.
+//
+// Patterns are dataset names or path.Match globs such as "pool/*". A pattern
+// matches a dataset and all of its descendents. A dataset is selected when
+// Include is empty or one of its patterns matches, and no Exclude pattern
+// matches.
+type DatasetFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// validate checks that every pattern is well formed.
+func (f DatasetFilter) validate() error {
+	for _, pattern := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if strings.TrimSpace(pattern) == "" {
+			return fmt.Errorf("empty dataset pattern")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid dataset pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Match reports whether the dataset is selected by the filter.
+func (f DatasetFilter) Match(dataset string) bool {
+	if len(f.Include) > 0 && !matchAny(f.Include, dataset) {
+		return false
+	}
+	return !matchAny(f.Exclude, dataset)
+}
+
+// matchAny reports whether a pattern matches the dataset or an ancestor.
+func matchAny(patterns []string, dataset string) bool {
+	for name := dataset; ; {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+		i := strings.LastIndex(name, "/")
+		if i < 0 {
+			return false
+		}
+		name = name[:i]
+	}
+}
+
+// datasetStats are the metrics of the snapshots of one dataset.
+type datasetStats struct {
+	count   int
+	used    uint64
+	written uint64
+	oldest  time.Time
+	newest  time.Time
+}
+
+// snapshotMetrics observes the snapshot metrics computed from the latest
+// snapshot listing. Seconds since the last snapshot are computed when the
+// metrics are collected so they keep increasing between listings.
+type snapshotMetrics struct {
+	filter DatasetFilter
+	now    func() time.Time
+
+	mu       sync.Mutex
+	total    int
+	datasets map[string]*datasetStats
+}
+
+func newSnapshotMetrics(filter DatasetFilter) *snapshotMetrics {
+	return &snapshotMetrics{
+		filter:   filter,
+		now:      time.Now,
+		datasets: make(map[string]*datasetStats),
+	}
+}
+
+// update replaces the observed metrics with those of snapshots. Datasets
+// that no longer have snapshots stop being observed.
+func (m *snapshotMetrics) update(snapshots []*model.Snapshot) {
+	datasets := make(map[string]*datasetStats)
+	for _, s := range snapshots {
+		if !m.filter.Match(s.Dataset) {
+			continue
+		}
+		stats, ok := datasets[s.Dataset]
+		if !ok {
+			stats = &datasetStats{oldest: s.Creation, newest: s.Creation}
+			datasets[s.Dataset] = stats
+		}
+		stats.count++
+		stats.used = addSaturating(stats.used, s.Used)
+		stats.written = addSaturating(stats.written, s.Written)
+		if s.Creation.Before(stats.oldest) {
+			stats.oldest = s.Creation
+		}
+		if s.Creation.After(stats.newest) {
+			stats.newest = s.Creation
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.total = len(snapshots)
+	m.datasets = datasets
+}
+
+// register creates the snapshot instruments with a callback that observes
+// the latest listing.
+func (m *snapshotMetrics) register(meter metric.Meter) error {
+	total, err := meter.Int64ObservableGauge("zfs_snapshot_count",
+		metric.WithDescription("Total number of ZFS snapshots"),
+		metric.WithUnit("{snapshot}"))
+	if err != nil {
+		return err
+	}
+	count, err := meter.Int64ObservableGauge("zfs_dataset_snapshot_count",
+		metric.WithDescription("Number of snapshots of the dataset"),
+		metric.WithUnit("{snapshot}"))
+	if err != nil {
+		return err
+	}
+	used, err := meter.Float64ObservableGauge("zfs_dataset_snapshot_used_bytes",
+		metric.WithDescription("Sum of the used space of the snapshots of the dataset"),
+		metric.WithUnit("By"))
+	if err != nil {
+		return err
+	}
+	written, err := meter.Float64ObservableGauge("zfs_dataset_snapshot_written_bytes",
+		metric.WithDescription("Sum of the space written to the snapshots of the dataset"),
+		metric.WithUnit("By"))
+	if err != nil {
+		return err
+	}
+	oldest, err := meter.Float64ObservableGauge("zfs_dataset_oldest_snapshot_timestamp_seconds",
+		metric.WithDescription("Creation time of the oldest snapshot of the dataset"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+	newest, err := meter.Float64ObservableGauge("zfs_dataset_newest_snapshot_timestamp_seconds",
+		metric.WithDescription("Creation time of the newest snapshot of the dataset"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+	// No unit: the Prometheus exporter would append a _seconds suffix.
+	sinceLast, err := meter.Float64ObservableGauge("zfs_dataset_seconds_since_last_snapshot",
+		metric.WithDescription("Seconds since the newest snapshot of the dataset was created"))
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		now := m.now()
+		o.ObserveInt64(total, int64(m.total))
+		for dataset, stats := range m.datasets {
+			attrs := metric.WithAttributes(attribute.String("dataset", dataset))
+			o.ObserveInt64(count, int64(stats.count), attrs)
+			o.ObserveFloat64(used, float64(stats.used), attrs)
+			o.ObserveFloat64(written, float64(stats.written), attrs)
+			o.ObserveFloat64(oldest, unixSeconds(stats.oldest), attrs)
+			o.ObserveFloat64(newest, unixSeconds(stats.newest), attrs)
+			o.ObserveFloat64(sinceLast, now.Sub(stats.newest).Seconds(), attrs)
+		}
+		return nil
+	}, total, count, used, written, oldest, newest, sinceLast)
+	return err
+}
+
+// addSaturating returns a+b, or math.MaxUint64 if the sum overflows.
+func addSaturating(a, b uint64) uint64 {
+	if b > math.MaxUint64-a {
+		return math.MaxUint64
+	}
+	return a + b
+}
+
+// unixSeconds returns t as fractional seconds since the epoch. Unlike
+// UnixNano it does not overflow for times far from the epoch.
+func unixSeconds(t time.Time) float64 {
+	return float64(t.Unix()) + float64(t.Nanosecond())/float64(time.Second)
+}
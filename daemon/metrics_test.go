@@ -0,0 +1,151 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+	"github.com/jsirianni/zfssnap/testutil"
+	"github.com/jsirianni/zfssnap/zfs"
+	"go.uber.org/zap"
+)
+
+func TestDatasetFilterMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   DatasetFilter
+		dataset  string
+		expected bool
+	}{
+		{name: "empty filter", dataset: "pool/data", expected: true},
+		{name: "include exact", filter: DatasetFilter{Include: []string{"pool/data"}}, dataset: "pool/data", expected: true},
+		{name: "include descendent", filter: DatasetFilter{Include: []string{"pool/data"}}, dataset: "pool/data/child", expected: true},
+		{name: "include prefix is not a parent", filter: DatasetFilter{Include: []string{"pool/data"}}, dataset: "pool/database", expected: false},
+		{name: "include glob", filter: DatasetFilter{Include: []string{"pool/*"}}, dataset: "pool/home", expected: true},
+		{name: "include glob does not match pool", filter: DatasetFilter{Include: []string{"pool/*"}}, dataset: "pool", expected: false},
+		{name: "exclude wins", filter: DatasetFilter{Include: []string{"pool"}, Exclude: []string{"pool/tmp"}}, dataset: "pool/tmp/cache", expected: false},
+		{name: "exclude only", filter: DatasetFilter{Exclude: []string{"*/tmp"}}, dataset: "pool/data", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.dataset); got != tt.expected {
+				t.Errorf("Match(%q) = %v, expected %v", tt.dataset, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewInvalidDatasetFilter(t *testing.T) {
+	for _, filter := range []DatasetFilter{
+		{Include: []string{"pool/["}},
+		{Exclude: []string{" "}},
+	} {
+		_, err := New(context.Background(), "", "", zap.NewNop(), WithDatasetFilter(filter))
+		if err == nil {
+			t.Errorf("Expected error for filter %+v", filter)
+		}
+	}
+}
+
+func TestDatasetMetrics(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	snapshots := []*model.Snapshot{
+		{Name: "pool/data@a", Dataset: "pool/data", Creation: now.Add(-3 * time.Hour), Used: 100, Written: 1000},
+		{Name: "pool/data@b", Dataset: "pool/data", Creation: now.Add(-time.Hour), Used: 200, Written: 500},
+		{Name: "pool/data/tmp@a", Dataset: "pool/data/tmp", Creation: now.Add(-2 * time.Hour), Used: 1, Written: 1},
+		{Name: "pool/home@a", Dataset: "pool/home", Creation: now.Add(-30 * time.Minute), Used: 50, Written: 60},
+	}
+	var listErr error
+	mock := testutil.NewMockSnapshotter().WithListDetailedFunc(func(_ context.Context, _ zfs.ListOptions) ([]*model.Snapshot, error) {
+		return snapshots, listErr
+	})
+
+	d, err := New(context.Background(), "", "", zap.NewNop(),
+		WithSnapshotter(mock),
+		WithDatasetFilter(DatasetFilter{Exclude: []string{"pool/data/tmp"}}),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	d.metrics.now = func() time.Time { return now }
+	d.updateMetrics()
+
+	expected := map[string]float64{
+		"zfs_snapshot_count":                                       4,
+		"zfs_dataset_snapshot_count{pool/data}":                    2,
+		"zfs_dataset_snapshot_used_bytes{pool/data}":               300,
+		"zfs_dataset_snapshot_written_bytes{pool/data}":            1500,
+		"zfs_dataset_oldest_snapshot_timestamp_seconds{pool/data}": float64(now.Add(-3 * time.Hour).Unix()),
+		"zfs_dataset_newest_snapshot_timestamp_seconds{pool/data}": float64(now.Add(-time.Hour).Unix()),
+		"zfs_dataset_seconds_since_last_snapshot{pool/data}":       3600,
+		"zfs_dataset_snapshot_count{pool/home}":                    1,
+		"zfs_dataset_snapshot_used_bytes{pool/home}":               50,
+		"zfs_dataset_snapshot_written_bytes{pool/home}":            60,
+		"zfs_dataset_oldest_snapshot_timestamp_seconds{pool/home}": float64(now.Add(-30 * time.Minute).Unix()),
+		"zfs_dataset_newest_snapshot_timestamp_seconds{pool/home}": float64(now.Add(-30 * time.Minute).Unix()),
+		"zfs_dataset_seconds_since_last_snapshot{pool/home}":       1800,
+	}
+	assertMetrics(t, d, expected)
+
+	// Seconds since the last snapshot advance between listings.
+	now = now.Add(time.Minute)
+	expected["zfs_dataset_seconds_since_last_snapshot{pool/data}"] = 3660
+	expected["zfs_dataset_seconds_since_last_snapshot{pool/home}"] = 1860
+	assertMetrics(t, d, expected)
+
+	// Failed listings keep the previous values.
+	listErr = errors.New("zfs list failed")
+	d.updateMetrics()
+	assertMetrics(t, d, expected)
+
+	// Datasets without snapshots are no longer exported.
+	listErr = nil
+	snapshots = snapshots[3:]
+	d.updateMetrics()
+	assertMetrics(t, d, map[string]float64{
+		"zfs_snapshot_count":                                       1,
+		"zfs_dataset_snapshot_count{pool/home}":                    1,
+		"zfs_dataset_snapshot_used_bytes{pool/home}":               50,
+		"zfs_dataset_snapshot_written_bytes{pool/home}":            60,
+		"zfs_dataset_oldest_snapshot_timestamp_seconds{pool/home}": float64(now.Add(-31 * time.Minute).Unix()),
+		"zfs_dataset_newest_snapshot_timestamp_seconds{pool/home}": float64(now.Add(-31 * time.Minute).Unix()),
+		"zfs_dataset_seconds_since_last_snapshot{pool/home}":       1860,
+	})
+}
+
+// assertMetrics gathers the zfs metrics of the daemon and compares them to
+// expected, keyed by name{dataset}.
+func assertMetrics(t *testing.T, d *Daemon, expected map[string]float64) {
+	t.Helper()
+
+	families, err := d.registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	got := make(map[string]float64)
+	for _, mf := range families {
+		if !strings.HasPrefix(mf.GetName(), "zfs_") {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			key := mf.GetName()
+			for _, l := range m.GetLabel() {
+				key += "{" + l.GetValue() + "}"
+			}
+			got[key] = m.GetGauge().GetValue()
+		}
+	}
+
+	if len(got) != len(expected) {
+		t.Errorf("Expected %d metrics, got %d: %v", len(expected), len(got), got)
+	}
+	for key, want := range expected {
+		if v, ok := got[key]; !ok || v != want {
+			t.Errorf("%s = %v (present %v), expected %v", key, v, ok, want)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jsirianni/zfssnap/naming"
+	"github.com/jsirianni/zfssnap/schedule"
+	"github.com/jsirianni/zfssnap/zfs"
+	"go.uber.org/zap"
+)
+
+// Job describes a dataset that is snapshotted on a schedule. Snapshots are
+// named <prefix>-<name>-<suffix>-<timestamp> using the naming package, or
+// by Template.
+type Job struct {
+	// Dataset to snapshot.
+	Dataset string
+
+	// Schedule specification, see schedule.Parse.
+	Schedule string
+
+	// Name is the base snapshot name, e.g. "hourly".
+	Name string
+
+	// Prefix and Suffix are applied to Name as in `zfssnap create`.
+	Prefix string
+	Suffix string
+
+	// Template is a naming template with a date field, see
+	// naming.ParseTemplate. Name is its {label}, Prefix its {prefix} and
+	// Suffix its {suffix}.
+	Template string
+
+	// Recursive atomically snapshots all descendent datasets.
+	Recursive bool
+
+	// Exclude lists descendent datasets whose snapshots are destroyed right
+	// after a recursive snapshot, together with their own descendents.
+	Exclude []string
+}
+
+// validate checks the job and returns its parsed schedule.
+func (j Job) validate(hostname string) (schedule.Schedule, error) {
+	if !zfs.IsValidDatasetName(j.Dataset) {
+		return nil, fmt.Errorf("invalid dataset name format: %s", j.Dataset)
+	}
+	for _, excluded := range j.Exclude {
+		if !j.Recursive || !strings.HasPrefix(excluded, j.Dataset+"/") {
+			return nil, fmt.Errorf("excluded dataset %s is not a recursive descendent of %s", excluded, j.Dataset)
+		}
+	}
+	if strings.TrimSpace(j.Name) == "" {
+		return nil, fmt.Errorf("snapshot name is required for dataset %s", j.Dataset)
+	}
+	tmpl, err := j.template()
+	if err != nil {
+		return nil, fmt.Errorf("dataset %s: %w", j.Dataset, err)
+	}
+	if !tmpl.HasDate() {
+		// The date is how missed runs are found after a restart.
+		return nil, fmt.Errorf("dataset %s: naming template %q has no date field", j.Dataset, tmpl)
+	}
+	// Check a representative name so invalid characters in any part are
+	// caught now rather than on the first scheduled run.
+	sample := j.values(hostname, time.Now())
+	sample.Sequence = 1
+	if name := tmpl.Format(sample); !zfs.IsValidSnapshotComponent(name) {
+		return nil, fmt.Errorf("dataset %s: naming produces invalid snapshot name %q", j.Dataset, name)
+	}
+	s, err := schedule.Parse(j.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("dataset %s: %w", j.Dataset, err)
+	}
+	return s, nil
+}
+
+// template returns the naming template of the job. Without a Template, it
+// is the template of the names built by naming.Apply with a timestamp.
+func (j Job) template() (*naming.Template, error) {
+	if j.Template != "" {
+		return naming.ParseTemplate(j.Template)
+	}
+	return naming.ApplyTemplate(naming.Options{Prefix: j.Prefix, Suffix: j.Suffix, Timestamp: true})
+}
+
+// values returns the naming template values of a snapshot taken at now.
+func (j Job) values(hostname string, now time.Time) naming.Values {
+	return naming.Values{Prefix: j.Prefix, Suffix: j.Suffix, Label: j.Name, Hostname: hostname, Time: now}
+}
+
+// scheduledJob tracks the next activation of a Job.
+type scheduledJob struct {
+	Job
+	schedule schedule.Schedule
+	next     time.Time
+}
+
+// scheduler takes snapshots for jobs when they are due. At most one snapshot
+// per dataset is in flight at any time; activations that arrive while the
+// dataset is busy are skipped.
+type scheduler struct {
+	snapshotter zfs.Snapshotter
+	logger      *zap.Logger
+	now         func() time.Time
+	hostname    string
+	jobs        []*scheduledJob
+
+	mu      sync.Mutex
+	running map[string]bool
+	wg      sync.WaitGroup
+}
+
+func newScheduler(snapshotter zfs.Snapshotter, logger *zap.Logger, jobs []Job) (*scheduler, error) {
+	hostname, err := naming.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("get hostname: %w", err)
+	}
+	s := &scheduler{
+		snapshotter: snapshotter,
+		logger:      logger,
+		now:         time.Now,
+		hostname:    hostname,
+		running:     make(map[string]bool),
+	}
+	for _, j := range jobs {
+		sched, err := j.validate(hostname)
+		if err != nil {
+			return nil, err
+		}
+		s.jobs = append(s.jobs, &scheduledJob{Job: j, schedule: sched})
+	}
+	return s, nil
+}
+
+// run schedules jobs until ctx is cancelled. Jobs whose previous activation
+// was missed while the daemon was not running are run once immediately.
+func (s *scheduler) run(ctx context.Context) {
+	s.catchUp(ctx)
+
+	for {
+		next := s.nextActivation()
+		if next.IsZero() {
+			<-ctx.Done()
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.runDue(ctx, s.now())
+		}
+	}
+}
+
+// catchUp runs each job whose latest snapshot is older than its most
+// recent activation and schedules every job from now.
+func (s *scheduler) catchUp(ctx context.Context) {
+	now := s.now()
+	for _, j := range s.jobs {
+		j.next = j.schedule.Next(now)
+
+		last, err := s.lastRun(ctx, j.Job)
+		if err != nil {
+			s.logger.Error("find last scheduled snapshot", zap.String("dataset", j.Dataset), zap.Error(err))
+			continue
+		}
+		if last.IsZero() {
+			continue
+		}
+		if due := j.schedule.Next(last); !due.IsZero() && !due.After(now) {
+			s.logger.Info("catching up missed snapshot",
+				zap.String("dataset", j.Dataset),
+				zap.Time("last", last),
+				zap.Time("missed", due))
+			s.trigger(ctx, j.Job, now)
+		}
+	}
+}
+
+// runDue triggers every job whose activation time is at or before now.
+func (s *scheduler) runDue(ctx context.Context, now time.Time) {
+	for _, j := range s.jobs {
+		if j.next.IsZero() || j.next.After(now) {
+			continue
+		}
+		j.next = j.schedule.Next(now)
+		s.trigger(ctx, j.Job, now)
+	}
+}
+
+func (s *scheduler) nextActivation() time.Time {
+	var next time.Time
+	for _, j := range s.jobs {
+		if j.next.IsZero() {
+			continue
+		}
+		if next.IsZero() || j.next.Before(next) {
+			next = j.next
+		}
+	}
+	return next
+}
+
+// trigger starts a snapshot for the job unless one is already running for
+// the same dataset.
+func (s *scheduler) trigger(ctx context.Context, j Job, now time.Time) {
+	s.mu.Lock()
+	if s.running[j.Dataset] {
+		s.mu.Unlock()
+		s.logger.Warn("skipping scheduled snapshot, previous run still in progress", zap.String("dataset", j.Dataset))
+		return
+	}
+	s.running[j.Dataset] = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, j.Dataset)
+			s.mu.Unlock()
+		}()
+
+		name, err := s.snapshotName(ctx, j, now)
+		if err != nil {
+			s.logger.Error("name scheduled snapshot", zap.String("dataset", j.Dataset), zap.Error(err))
+			return
+		}
+		if err := s.snapshotter.Create(ctx, j.Dataset, name, zfs.CreateOptions{Recursive: j.Recursive}); err != nil {
+			s.logger.Error("scheduled snapshot", zap.String("dataset", j.Dataset), zap.String("name", name), zap.Error(err))
+			return
+		}
+		s.logger.Info("scheduled snapshot created", zap.String("snapshot", j.Dataset+"@"+name))
+
+		// zfs cannot exclude children from an atomic recursive snapshot, so
+		// excluded datasets are cleaned up afterwards.
+		for _, excluded := range j.Exclude {
+			snapshot := excluded + "@" + name
+			if _, err := s.snapshotter.Delete(ctx, snapshot, zfs.DeleteOptions{Recursive: true}); err != nil {
+				s.logger.Error("destroy excluded snapshot", zap.String("snapshot", snapshot), zap.Error(err))
+			}
+		}
+	}()
+}
+
+// wait blocks until all in-flight snapshots have finished.
+func (s *scheduler) wait() {
+	s.wg.Wait()
+}
+
+// lastRun returns the time of the newest snapshot taken for the job, as
+// recorded in its name, or the zero time if there is none.
+func (s *scheduler) lastRun(ctx context.Context, j Job) (time.Time, error) {
+	names, err := s.snapshotter.List(ctx, zfs.ListOptions{Dataset: j.Dataset})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	tmpl, err := j.template()
+	if err != nil {
+		return time.Time{}, err
+	}
+	var last time.Time
+	for _, found := range tmpl.Find(names, j.values(s.hostname, time.Time{})) {
+		if found.Time.After(last) {
+			last = found.Time
+		}
+	}
+	return last, nil
+}
+
+// snapshotName returns the name of the snapshot taken for the job at now.
+// A sequence number follows the highest one of the dataset's snapshots.
+func (s *scheduler) snapshotName(ctx context.Context, j Job, now time.Time) (string, error) {
+	tmpl, err := j.template()
+	if err != nil {
+		return "", err
+	}
+	v := j.values(s.hostname, now)
+	if tmpl.HasSequence() {
+		names, err := s.snapshotter.List(ctx, zfs.ListOptions{Dataset: j.Dataset})
+		if err != nil {
+			return "", fmt.Errorf("list snapshots: %w", err)
+		}
+		v.Sequence = tmpl.NextSequence(names, v)
+	}
+	return tmpl.Format(v), nil
+}
@@ -0,0 +1,323 @@
+package daemon
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jsirianni/zfssnap/naming"
+	"github.com/jsirianni/zfssnap/testutil"
+	"github.com/jsirianni/zfssnap/zfs"
+	"go.uber.org/zap"
+)
+
+func TestNewSchedulerValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		job         Job
+		expectError bool
+	}{
+		{
+			name: "valid job",
+			job:  Job{Dataset: "pool/data", Schedule: "@hourly", Name: "auto"},
+		},
+		{
+			name:        "invalid dataset",
+			job:         Job{Dataset: "123pool", Schedule: "@hourly", Name: "auto"},
+			expectError: true,
+		},
+		{
+			name:        "invalid schedule",
+			job:         Job{Dataset: "pool/data", Schedule: "every hour", Name: "auto"},
+			expectError: true,
+		},
+		{
+			name:        "missing name",
+			job:         Job{Dataset: "pool/data", Schedule: "@hourly"},
+			expectError: true,
+		},
+		{
+			name: "template",
+			job:  Job{Dataset: "pool/data", Schedule: "@hourly", Name: "auto", Template: "{label}_{utcdate:%Y-%m-%d_%H%M}"},
+		},
+		{
+			name:        "invalid template",
+			job:         Job{Dataset: "pool/data", Schedule: "@hourly", Name: "auto", Template: "{label}_{date:%Q}"},
+			expectError: true,
+		},
+		{
+			name:        "template without date",
+			job:         Job{Dataset: "pool/data", Schedule: "@hourly", Name: "auto", Template: "{label}-{seq}"},
+			expectError: true,
+		},
+		{
+			name:        "template with invalid characters",
+			job:         Job{Dataset: "pool/data", Schedule: "@hourly", Name: "auto", Template: "{label} {date:%Y}"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newScheduler(testutil.NewMockSnapshotter(), zap.NewNop(), []Job{tt.job})
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSchedulerCatchUp(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.Local)
+	job := Job{Dataset: "pool/data", Schedule: "@hourly", Name: "hourly", Prefix: "zfssnap"}
+
+	tests := []struct {
+		name           string
+		existing       []string
+		expectedCreate int
+	}{
+		{
+			name:           "no previous snapshots",
+			existing:       nil,
+			expectedCreate: 0,
+		},
+		{
+			name: "previous run is current",
+			existing: []string{
+				"pool/data@zfssnap-hourly-" + now.Add(-30*time.Minute).Format(naming.TimestampFormat),
+			},
+			expectedCreate: 0,
+		},
+		{
+			name: "missed runs are caught up once",
+			existing: []string{
+				"pool/data@zfssnap-hourly-" + now.Add(-5*time.Hour).Format(naming.TimestampFormat),
+				"pool/data@zfssnap-hourly-" + now.Add(-4*time.Hour).Format(naming.TimestampFormat),
+			},
+			expectedCreate: 1,
+		},
+		{
+			name: "other snapshots are ignored",
+			existing: []string{
+				"pool/data@manual-" + now.Add(-5*time.Hour).Format(naming.TimestampFormat),
+				"pool/data@zfssnap-daily-" + now.Add(-5*time.Hour).Format(naming.TimestampFormat),
+			},
+			expectedCreate: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var creates atomic.Int32
+			mock := testutil.NewMockSnapshotter().
+				WithListFunc(func(_ context.Context, opts zfs.ListOptions) ([]string, error) {
+					if opts.Dataset != job.Dataset {
+						t.Errorf("Expected list of %s, got %q", job.Dataset, opts.Dataset)
+					}
+					return tt.existing, nil
+				}).
+				WithCreateFunc(func(_ context.Context, _, _ string, _ zfs.CreateOptions) error {
+					creates.Add(1)
+					return nil
+				})
+
+			s, err := newScheduler(mock, zap.NewNop(), []Job{job})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			s.now = func() time.Time { return now }
+
+			s.catchUp(context.Background())
+			s.wait()
+
+			if got := int(creates.Load()); got != tt.expectedCreate {
+				t.Errorf("Expected %d creates, got %d", tt.expectedCreate, got)
+			}
+			expectedNext := time.Date(2025, 1, 15, 11, 0, 0, 0, time.Local)
+			if !s.jobs[0].next.Equal(expectedNext) {
+				t.Errorf("Expected next run %v, got %v", expectedNext, s.jobs[0].next)
+			}
+		})
+	}
+}
+
+func TestSchedulerRunDue(t *testing.T) {
+	now := time.Date(2025, 1, 15, 11, 0, 0, 0, time.Local)
+
+	var mu sync.Mutex
+	var created []string
+	mock := testutil.NewMockSnapshotter().
+		WithCreateFunc(func(_ context.Context, dataset, name string, opts zfs.CreateOptions) error {
+			if !opts.Recursive {
+				t.Error("Expected recursive create")
+			}
+			mu.Lock()
+			created = append(created, dataset+"@"+name)
+			mu.Unlock()
+			return nil
+		})
+
+	s, err := newScheduler(mock, zap.NewNop(), []Job{
+		{Dataset: "pool/data", Schedule: "@hourly", Name: "hourly", Recursive: true},
+		{Dataset: "pool/home", Schedule: "@daily", Name: "daily", Recursive: true},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s.jobs[0].next = now
+	s.jobs[1].next = now.Add(13 * time.Hour)
+
+	s.runDue(context.Background(), now)
+	s.wait()
+
+	expected := "pool/data@hourly-" + now.Format(naming.TimestampFormat)
+	if len(created) != 1 || created[0] != expected {
+		t.Errorf("Expected [%s], got %v", expected, created)
+	}
+	if !s.jobs[0].next.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected next run %v, got %v", now.Add(time.Hour), s.jobs[0].next)
+	}
+	if next := s.nextActivation(); !next.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected next activation %v, got %v", now.Add(time.Hour), next)
+	}
+}
+
+func TestSchedulerSkipsBusyDataset(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	var creates atomic.Int32
+	mock := testutil.NewMockSnapshotter().
+		WithCreateFunc(func(_ context.Context, _, _ string, _ zfs.CreateOptions) error {
+			creates.Add(1)
+			started <- struct{}{}
+			<-release
+			return nil
+		})
+
+	job := Job{Dataset: "pool/data", Schedule: "@hourly", Name: "hourly"}
+	daily := Job{Dataset: "pool/data", Schedule: "@daily", Name: "daily"}
+	s, err := newScheduler(mock, zap.NewNop(), []Job{job, daily})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Neither the same job nor another job on the dataset runs while the
+	// dataset is busy.
+	now := time.Now()
+	s.trigger(context.Background(), job, now)
+	<-started
+	s.trigger(context.Background(), job, now.Add(time.Hour))
+	s.trigger(context.Background(), daily, now.Add(time.Hour))
+	close(release)
+	s.wait()
+
+	if got := creates.Load(); got != 1 {
+		t.Errorf("Expected 1 create while dataset was busy, got %d", got)
+	}
+
+	// Once the first run finished, the dataset can be snapshotted again.
+	s.trigger(context.Background(), job, now.Add(2*time.Hour))
+	<-started
+	s.wait()
+	if got := creates.Load(); got != 2 {
+		t.Errorf("Expected 2 creates, got %d", got)
+	}
+}
+
+func TestSchedulerWithSimulator(t *testing.T) {
+	ctx := context.Background()
+	clock := testutil.NewClock(time.Date(2025, 1, 15, 10, 0, 0, 0, time.Local))
+	sim := testutil.NewSimulator(clock)
+	for _, ds := range []string{"pool/data/keep", "pool/data/tmp"} {
+		if err := sim.CreateDataset(ds); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	job := Job{Dataset: "pool/data", Schedule: "@hourly", Name: "hourly", Recursive: true, Exclude: []string{"pool/data/tmp"}}
+	s, err := newScheduler(sim, zap.NewNop(), []Job{job})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s.now = clock.Now
+
+	s.catchUp(ctx)
+	for i := 0; i < 3; i++ {
+		s.runDue(ctx, clock.Advance(time.Hour))
+		s.wait()
+	}
+
+	names, err := sim.List(ctx, zfs.ListOptions{Dataset: "pool/data", Recursive: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(names) != 6 {
+		t.Fatalf("Expected 3 snapshots of pool/data and pool/data/keep, got %v", names)
+	}
+	for _, name := range names {
+		if strings.HasPrefix(name, "pool/data/tmp@") {
+			t.Errorf("Expected excluded snapshot to be destroyed, found %s", name)
+		}
+	}
+
+	// The daemon is down for a few hours; on restart the missed run is
+	// caught up exactly once.
+	clock.Advance(5*time.Hour + 30*time.Minute)
+	s.catchUp(ctx)
+	s.wait()
+	names, err = sim.List(ctx, zfs.ListOptions{Dataset: "pool/data"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(names) != 4 {
+		t.Errorf("Expected 4 snapshots after catch up, got %v", names)
+	}
+}
+
+func TestSchedulerTemplate(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	job := Job{Dataset: "pool/data", Schedule: "@hourly", Name: "hourly", Template: "{label}_{utcdate:%Y-%m-%d_%H%M}_{seq:3}"}
+
+	var mu sync.Mutex
+	var created []string
+	mock := testutil.NewMockSnapshotter().
+		WithListFunc(func(_ context.Context, _ zfs.ListOptions) ([]string, error) {
+			return []string{
+				"pool/data@hourly_2025-01-15_0500_006",
+				"pool/data@hourly_2025-01-15_0600_007",
+				"pool/data@daily_2025-01-15_0900_042",
+			}, nil
+		}).
+		WithCreateFunc(func(_ context.Context, _, name string, _ zfs.CreateOptions) error {
+			mu.Lock()
+			created = append(created, name)
+			mu.Unlock()
+			return nil
+		})
+
+	s, err := newScheduler(mock, zap.NewNop(), []Job{job})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s.now = func() time.Time { return now }
+
+	last, err := s.lastRun(context.Background(), job)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := time.Date(2025, 1, 15, 6, 0, 0, 0, time.UTC); !last.Equal(expected) {
+		t.Errorf("Expected last run %v, got %v", expected, last)
+	}
+
+	s.catchUp(context.Background())
+	s.wait()
+	if len(created) != 1 || created[0] != "hourly_2025-01-15_1030_008" {
+		t.Errorf("Expected [hourly_2025-01-15_1030_008], got %v", created)
+	}
+}
@@ -0,0 +1,103 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// DefaultOTLPInterval is the interval between OTLP exports when
+// OTLPConfig.Interval is not set.
+const DefaultOTLPInterval = time.Minute
+
+// meterName is the instrumentation scope of the daemon metrics.
+const meterName = "github.com/jsirianni/zfssnap/daemon"
+
+// OTLPConfig configures the export of the daemon metrics to an
+// OpenTelemetry collector with OTLP over HTTP. The OTEL_EXPORTER_OTLP_*
+// environment variables apply to settings that are not given.
+type OTLPConfig struct {
+	// Endpoint is the collector URL, e.g. "http://localhost:4318". Metrics
+	// are sent to /v1/metrics unless the URL has a path. TLS is used for
+	// https URLs.
+	Endpoint string
+
+	// Headers are sent with every export, e.g. for authentication.
+	Headers map[string]string
+
+	// Interval between exports. Zero means DefaultOTLPInterval.
+	Interval time.Duration
+}
+
+// validate checks the endpoint URL and interval.
+func (c OTLPConfig) validate() error {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid OTLP endpoint: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid OTLP endpoint %q: expected http(s)://host[:port][/path]", c.Endpoint)
+	}
+	if c.Interval < 0 {
+		return fmt.Errorf("OTLP export interval must not be negative")
+	}
+	return nil
+}
+
+// newMeterProvider returns a meter provider that exports to the Prometheus
+// registry and, when otlp is not nil, to an OTLP/HTTP collector.
+func newMeterProvider(ctx context.Context, serviceName, version string, registry prometheus.Registerer, otlp *OTLPConfig) (*sdkmetric.MeterProvider, error) {
+	var attrs []attribute.KeyValue
+	if serviceName != "" {
+		attrs = append(attrs, semconv.ServiceName(serviceName))
+	}
+	if version != "" {
+		attrs = append(attrs, semconv.ServiceVersion(version))
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, attrs...))
+	if err != nil {
+		return nil, fmt.Errorf("create resource: %w", err)
+	}
+
+	promExporter, err := otelprom.New(otelprom.WithRegisterer(registry), otelprom.WithoutScopeInfo())
+	if err != nil {
+		return nil, fmt.Errorf("create prometheus exporter: %w", err)
+	}
+	opts := []sdkmetric.Option{
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(promExporter),
+	}
+
+	if otlp != nil {
+		if err := otlp.validate(); err != nil {
+			return nil, err
+		}
+		exporterOpts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpointURL(otlp.Endpoint)}
+		if u, _ := url.Parse(otlp.Endpoint); u.Path == "" || u.Path == "/" {
+			exporterOpts = append(exporterOpts, otlpmetrichttp.WithURLPath("/v1/metrics"))
+		}
+		if len(otlp.Headers) > 0 {
+			exporterOpts = append(exporterOpts, otlpmetrichttp.WithHeaders(otlp.Headers))
+		}
+		exporter, err := otlpmetrichttp.New(ctx, exporterOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("create OTLP exporter: %w", err)
+		}
+		interval := otlp.Interval
+		if interval == 0 {
+			interval = DefaultOTLPInterval
+		}
+		opts = append(opts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(interval))))
+	}
+
+	return sdkmetric.NewMeterProvider(opts...), nil
+}
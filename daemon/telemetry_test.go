@@ -0,0 +1,133 @@
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+	"github.com/jsirianni/zfssnap/testutil"
+	"github.com/jsirianni/zfssnap/zfs"
+	"go.uber.org/zap"
+)
+
+func mockWithSnapshots(snapshots ...*model.Snapshot) *testutil.MockSnapshotter {
+	return testutil.NewMockSnapshotter().WithListDetailedFunc(func(_ context.Context, _ zfs.ListOptions) ([]*model.Snapshot, error) {
+		return snapshots, nil
+	})
+}
+
+func TestMultipleDaemons(t *testing.T) {
+	now := time.Now()
+	first, err := New(context.Background(), "first", "", zap.NewNop(), WithSnapshotter(mockWithSnapshots(
+		&model.Snapshot{Name: "pool/a@1", Dataset: "pool/a", Creation: now},
+	)))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	second, err := New(context.Background(), "second", "", zap.NewNop(), WithSnapshotter(mockWithSnapshots(
+		&model.Snapshot{Name: "pool/b@1", Dataset: "pool/b", Creation: now},
+		&model.Snapshot{Name: "pool/b@2", Dataset: "pool/b", Creation: now},
+	)))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	first.metrics.now = func() time.Time { return now }
+	second.metrics.now = func() time.Time { return now }
+
+	first.updateMetrics()
+	second.updateMetrics()
+
+	assertMetrics(t, first, map[string]float64{
+		"zfs_snapshot_count":                                    1,
+		"zfs_dataset_snapshot_count{pool/a}":                    1,
+		"zfs_dataset_snapshot_used_bytes{pool/a}":               0,
+		"zfs_dataset_snapshot_written_bytes{pool/a}":            0,
+		"zfs_dataset_oldest_snapshot_timestamp_seconds{pool/a}": unixSeconds(now),
+		"zfs_dataset_newest_snapshot_timestamp_seconds{pool/a}": unixSeconds(now),
+		"zfs_dataset_seconds_since_last_snapshot{pool/a}":       0,
+	})
+	assertMetrics(t, second, map[string]float64{
+		"zfs_snapshot_count":                                    2,
+		"zfs_dataset_snapshot_count{pool/b}":                    2,
+		"zfs_dataset_snapshot_used_bytes{pool/b}":               0,
+		"zfs_dataset_snapshot_written_bytes{pool/b}":            0,
+		"zfs_dataset_oldest_snapshot_timestamp_seconds{pool/b}": unixSeconds(now),
+		"zfs_dataset_newest_snapshot_timestamp_seconds{pool/b}": unixSeconds(now),
+		"zfs_dataset_seconds_since_last_snapshot{pool/b}":       0,
+	})
+
+	for _, d := range []*Daemon{first, second} {
+		if err := d.Stop(context.Background()); err != nil {
+			t.Errorf("Stop: %v", err)
+		}
+	}
+}
+
+func TestOTLPExport(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		paths    []string
+		apiKeys  []string
+		received int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+		apiKeys = append(apiKeys, r.Header.Get("X-Api-Key"))
+		if r.ContentLength != 0 {
+			received++
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	d, err := New(context.Background(), "zfssnap-daemon", "test", zap.NewNop(),
+		WithSnapshotter(mockWithSnapshots(&model.Snapshot{Name: "pool/a@1", Dataset: "pool/a", Creation: time.Now()})),
+		WithOTLP(OTLPConfig{
+			Endpoint: srv.URL,
+			Headers:  map[string]string{"X-Api-Key": "secret"},
+			Interval: time.Hour,
+		}),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	d.updateMetrics()
+
+	// Stopping the daemon flushes the metrics to the collector.
+	if err := d.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if received == 0 {
+		t.Fatal("Expected metrics to be exported")
+	}
+	for i, path := range paths {
+		if path != "/v1/metrics" {
+			t.Errorf("Expected export to /v1/metrics, got %q", path)
+		}
+		if apiKeys[i] != "secret" {
+			t.Errorf("Expected X-Api-Key header, got %q", apiKeys[i])
+		}
+	}
+}
+
+func TestNewInvalidOTLP(t *testing.T) {
+	for _, cfg := range []OTLPConfig{
+		{Endpoint: "localhost:4318"},
+		{Endpoint: "ftp://collector:4318"},
+		{Endpoint: "http://"},
+		{Endpoint: "http://collector:4318", Interval: -time.Second},
+	} {
+		if _, err := New(context.Background(), "", "", zap.NewNop(), WithOTLP(cfg)); err == nil {
+			t.Errorf("Expected error for %+v", cfg)
+		}
+	}
+}
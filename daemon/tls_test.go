@@ -0,0 +1,203 @@
+package daemon
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert is a certificate and key written as PEM files.
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// newTestCert creates a certificate for 127.0.0.1 signed by parent, or a
+// self-signed CA when parent is nil.
+func newTestCert(t *testing.T, dir, name string, serial int64, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey: %v", err)
+	}
+
+	c := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".crt"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	writePEM(t, c.certFile, "CERTIFICATE", der)
+	writePEM(t, c.keyFile, "EC PRIVATE KEY", keyDER)
+	return c
+}
+
+func writePEM(t *testing.T, file, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+// newTLSServer starts a test server with the configuration of r.
+func newTLSServer(t *testing.T, r *certReloader) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.TLS = r.tlsConfig()
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// tlsGet requests url trusting ca, presenting client when set, and returns
+// the serial number of the server certificate.
+func tlsGet(url string, ca *testCert, client *testCert) (*big.Int, error) {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	cfg := &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+	if client != nil {
+		pair, err := tls.LoadX509KeyPair(client.certFile, client.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{pair}
+	}
+	c := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg, DisableKeepAlives: true}}
+	resp, err := c.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return resp.TLS.PeerCertificates[0].SerialNumber, nil
+}
+
+func TestNewCertReloaderErrors(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, dir, "ca", 1, nil)
+
+	tests := []struct {
+		name string
+		cfg  TLSConfig
+	}{
+		{name: "missing key", cfg: TLSConfig{CertFile: ca.certFile}},
+		{name: "missing cert", cfg: TLSConfig{KeyFile: ca.keyFile}},
+		{name: "unreadable cert", cfg: TLSConfig{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: ca.keyFile}},
+		{name: "missing client CA", cfg: TLSConfig{CertFile: ca.certFile, KeyFile: ca.keyFile, ClientCAFile: filepath.Join(dir, "missing.crt")}},
+		{name: "invalid client CA", cfg: TLSConfig{CertFile: ca.certFile, KeyFile: ca.keyFile, ClientCAFile: ca.keyFile}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newCertReloader(tt.cfg); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestCertReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, dir, "ca", 1, nil)
+	server := newTestCert(t, dir, "server", 2, ca)
+
+	r, err := newCertReloader(TLSConfig{CertFile: server.certFile, KeyFile: server.keyFile})
+	if err != nil {
+		t.Fatalf("newCertReloader: %v", err)
+	}
+	srv := newTLSServer(t, r)
+
+	serial, err := tlsGet(srv.URL, ca, nil)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if serial.Int64() != 2 {
+		t.Errorf("Expected serial 2, got %s", serial)
+	}
+
+	// A broken key keeps the current certificate.
+	if err := os.WriteFile(server.keyFile, []byte("broken"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := r.reload(); err == nil {
+		t.Error("Expected reload error for broken key")
+	}
+	if serial, err := tlsGet(srv.URL, ca, nil); err != nil || serial.Int64() != 2 {
+		t.Errorf("Expected serial 2 after failed reload, got %v, %v", serial, err)
+	}
+
+	newTestCert(t, dir, "server", 3, ca)
+	if err := r.reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if serial, err := tlsGet(srv.URL, ca, nil); err != nil || serial.Int64() != 3 {
+		t.Errorf("Expected serial 3 after reload, got %v, %v", serial, err)
+	}
+}
+
+func TestClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, dir, "ca", 1, nil)
+	server := newTestCert(t, dir, "server", 2, ca)
+	client := newTestCert(t, dir, "client", 3, ca)
+	other := newTestCert(t, dir, "other", 4, nil)
+
+	r, err := newCertReloader(TLSConfig{CertFile: server.certFile, KeyFile: server.keyFile, ClientCAFile: ca.certFile})
+	if err != nil {
+		t.Fatalf("newCertReloader: %v", err)
+	}
+	srv := newTLSServer(t, r)
+
+	if _, err := tlsGet(srv.URL, ca, client); err != nil {
+		t.Errorf("Expected client certificate to be accepted: %v", err)
+	}
+	if _, err := tlsGet(srv.URL, ca, nil); err == nil {
+		t.Error("Expected request without client certificate to fail")
+	}
+	if _, err := tlsGet(srv.URL, ca, other); err == nil {
+		t.Error("Expected client certificate from another CA to fail")
+	}
+}
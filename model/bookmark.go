@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Bookmark represents a ZFS bookmark: the point in time of a snapshot, kept
+// after the snapshot is destroyed so that it can still be the source of an
+// incremental send. A bookmark holds no data.
+type Bookmark struct {
+	// Fully qualified bookmark name: pool/dataset#bookmark
+	Name string `json:"name"`
+
+	// Dataset the bookmark belongs to
+	Dataset string `json:"dataset"`
+
+	// Creation time of the snapshot the bookmark was created from
+	Creation time.Time `json:"creation"`
+
+	// GUID of the snapshot the bookmark was created from
+	GUID uint64 `json:"guid"`
+
+	// Transaction group of the snapshot the bookmark was created from
+	CreateTXG uint64 `json:"createtxg"`
+}
@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// ChangeType is the kind of change `zfs diff` reports for a path.
+type ChangeType string
+
+const (
+	// ChangeAdded is a path that was created.
+	ChangeAdded ChangeType = "added"
+
+	// ChangeRemoved is a path that was removed.
+	ChangeRemoved ChangeType = "removed"
+
+	// ChangeModified is a path whose content or metadata changed.
+	ChangeModified ChangeType = "modified"
+
+	// ChangeRenamed is a path that was renamed or moved.
+	ChangeRenamed ChangeType = "renamed"
+)
+
+// FileType is the type of the file at a changed path.
+type FileType string
+
+// File types reported by `zfs diff -F`. Doors and event ports exist only on
+// illumos.
+const (
+	FileTypeFile            FileType = "file"
+	FileTypeDirectory       FileType = "directory"
+	FileTypeSymlink         FileType = "symlink"
+	FileTypeBlockDevice     FileType = "block_device"
+	FileTypeCharacterDevice FileType = "character_device"
+	FileTypeFIFO            FileType = "fifo"
+	FileTypeSocket          FileType = "socket"
+	FileTypeDoor            FileType = "door"
+	FileTypeEventPort       FileType = "event_port"
+)
+
+// Change is a change to a path between a snapshot and a later snapshot or
+// the live dataset, as reported by `zfs diff`.
+type Change struct {
+	// Change time (ctime) of the path: in the later state, or in the
+	// snapshot for removed paths
+	Time time.Time `json:"time"`
+
+	// Kind of change
+	Type ChangeType `json:"change"`
+
+	// Type of the file at the path
+	FileType FileType `json:"file_type"`
+
+	// Absolute path of the file; the old path for renamed files
+	Path string `json:"path"`
+
+	// New path of a renamed file
+	NewPath string `json:"new_path,omitempty"`
+}
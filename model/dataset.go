@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// Dataset represents a ZFS filesystem or volume.
+type Dataset struct {
+	// Fully qualified dataset name: pool/dataset
+	Name string `json:"name"`
+
+	// Pool the dataset belongs to
+	Pool string `json:"pool"`
+
+	// Dataset type: "filesystem" or "volume"
+	Type string `json:"type"`
+
+	// Creation time of the dataset
+	Creation time.Time `json:"creation"`
+
+	// Space consumed by the dataset, its snapshots and descendents (bytes)
+	Used uint64 `json:"used"`
+
+	// Space available to the dataset and its descendents (bytes)
+	Available uint64 `json:"available"`
+
+	// Space accessible by the dataset, including shared data (bytes)
+	Referenced uint64 `json:"referenced"`
+
+	// Mountpoint of a filesystem: a path, "none" or "legacy". Empty for volumes.
+	Mountpoint string `json:"mountpoint,omitempty"`
+
+	// Limit on the space used by the dataset and its descendents (bytes); 0 if none
+	Quota uint64 `json:"quota"`
+
+	// Snapshot a clone was created from; empty if the dataset is not a clone
+	Origin string `json:"origin,omitempty"`
+
+	// User properties (module:property) set on or inherited by the dataset
+	Properties map[string]string `json:"properties,omitempty"`
+}
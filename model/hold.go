@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// Hold is a user hold on a snapshot. A held snapshot cannot be destroyed
+// until every hold on it has been released.
+type Hold struct {
+	// Fully qualified snapshot name: pool/dataset@snap
+	Snapshot string `json:"snapshot"`
+
+	// Tag identifying the hold
+	Tag string `json:"tag"`
+
+	// Time the hold was placed
+	Timestamp time.Time `json:"timestamp"`
+}
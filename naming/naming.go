@@ -0,0 +1,59 @@
+// Package naming builds snapshot names from a base name and naming options,
+// or from a Template, which can also parse the names it built.
+package naming
+
+import (
+	"strings"
+	"time"
+)
+
+// TimestampFormat is the layout of the timestamp appended to snapshot names.
+const TimestampFormat = "20060102-150405"
+
+// TimestampDateFormat is TimestampFormat as the format of a Template date
+// field.
+const TimestampDateFormat = "%Y%m%d-%H%M%S"
+
+// Options configures the transformations applied to a snapshot name.
+type Options struct {
+	// Prefix is prepended to the name, separated by a hyphen.
+	Prefix string
+
+	// Suffix is appended to the name, separated by a hyphen.
+	Suffix string
+
+	// Timestamp appends now, formatted with TimestampFormat in local time.
+	Timestamp bool
+}
+
+// Apply returns name with the prefix, suffix and timestamp applied, in that
+// order: <prefix>-<name>-<suffix>-<timestamp>.
+func Apply(name string, opts Options, now time.Time) string {
+	if opts.Prefix != "" {
+		name = opts.Prefix + "-" + name
+	}
+	if opts.Suffix != "" {
+		name = name + "-" + opts.Suffix
+	}
+	if opts.Timestamp {
+		name = name + "-" + now.Local().Format(TimestampFormat)
+	}
+	return name
+}
+
+// ApplyTemplate returns the Template of the names built by Apply with opts,
+// with the name as its {label}, so those names can be parsed.
+func ApplyTemplate(opts Options) (*Template, error) {
+	var fields []string
+	if opts.Prefix != "" {
+		fields = append(fields, "{prefix}")
+	}
+	fields = append(fields, "{label}")
+	if opts.Suffix != "" {
+		fields = append(fields, "{suffix}")
+	}
+	if opts.Timestamp {
+		fields = append(fields, "{date:"+TimestampDateFormat+"}")
+	}
+	return ParseTemplate(strings.Join(fields, "-"))
+}
@@ -0,0 +1,64 @@
+package naming
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApply(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		base     string
+		opts     Options
+		expected string
+	}{
+		{
+			name:     "no transformations",
+			base:     "backup",
+			expected: "backup",
+		},
+		{
+			name:     "prefix",
+			base:     "backup",
+			opts:     Options{Prefix: "daily"},
+			expected: "daily-backup",
+		},
+		{
+			name:     "suffix",
+			base:     "backup",
+			opts:     Options{Suffix: "manual"},
+			expected: "backup-manual",
+		},
+		{
+			name:     "timestamp",
+			base:     "backup",
+			opts:     Options{Timestamp: true},
+			expected: "backup-20250115-103000",
+		},
+		{
+			name:     "all transformations",
+			base:     "backup",
+			opts:     Options{Prefix: "daily", Suffix: "manual", Timestamp: true},
+			expected: "daily-backup-manual-20250115-103000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := Apply(tt.base, tt.opts, now); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+
+			tmpl, err := ApplyTemplate(tt.opts)
+			if err != nil {
+				t.Fatalf("ApplyTemplate: %v", err)
+			}
+			v := Values{Prefix: tt.opts.Prefix, Suffix: tt.opts.Suffix, Label: tt.base, Time: now}
+			if name := tmpl.Format(v); name != tt.expected {
+				t.Errorf("Expected template %s to format %q, got %q", tmpl, tt.expected, name)
+			}
+		})
+	}
+}
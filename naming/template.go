@@ -0,0 +1,540 @@
+package naming
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Template builds snapshot names from fields and parses the names it built
+// back into their fields. A template is literal text with fields in braces:
+//
+//	{prefix}          Values.Prefix
+//	{suffix}          Values.Suffix
+//	{label}           Values.Label, e.g. the schedule name "hourly"
+//	{hostname}        Values.Hostname
+//	{seq}             Values.Sequence; {seq:N} pads it with zeros to N digits
+//	{date:FORMAT}     Values.Time in local time, formatted with strftime FORMAT
+//	{utcdate:FORMAT}  Values.Time in UTC
+//
+// FORMAT supports %Y, %y, %m, %d, %H, %M, %S, %j, %s and %%. Each field may
+// be used once. Fields that are not digits are matched as short as
+// possible when parsing, so they should be separated by text they do not
+// contain, e.g. {prefix}_{label}_{date:%Y-%m-%d_%H%M}.
+type Template struct {
+	spec  string
+	parts []part
+	re    *regexp.Regexp
+}
+
+// Values are the fields of a snapshot name.
+type Values struct {
+	Prefix   string
+	Suffix   string
+	Label    string
+	Hostname string
+	Sequence int
+
+	// Time is formatted to the second by the date fields.
+	Time time.Time
+}
+
+// Fields of a template.
+const (
+	fieldLiteral = ""
+	fieldPrefix  = "prefix"
+	fieldSuffix  = "suffix"
+	fieldLabel   = "label"
+	fieldHost    = "hostname"
+	fieldSeq     = "seq"
+	fieldDate    = "date"
+	fieldUTCDate = "utcdate"
+)
+
+// part is literal text or a field of a template.
+type part struct {
+	field string
+
+	// text is the literal text of a literal part.
+	text string
+
+	// width is the zero padded width of a sequence.
+	width int
+
+	// directives is the strftime format of a date, split into literal
+	// text and directives such as "%Y".
+	directives []string
+}
+
+// ParseTemplate parses a naming template.
+func ParseTemplate(spec string) (*Template, error) {
+	t := &Template{spec: spec}
+	seen := make(map[string]bool)
+	for rest := spec; rest != ""; {
+		open := strings.IndexByte(rest, '{')
+		if open != 0 {
+			text := rest
+			if open > 0 {
+				text = rest[:open]
+			}
+			if strings.ContainsRune(text, '}') {
+				return nil, fmt.Errorf("invalid naming template %q: unexpected }", spec)
+			}
+			t.parts = append(t.parts, part{text: text})
+			rest = rest[len(text):]
+			continue
+		}
+
+		end := strings.IndexByte(rest, '}')
+		if end < 0 {
+			return nil, fmt.Errorf("invalid naming template %q: unclosed {", spec)
+		}
+		p, err := parseField(rest[1:end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid naming template %q: %w", spec, err)
+		}
+		key := p.field
+		if key == fieldUTCDate {
+			key = fieldDate
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("invalid naming template %q: field %s is used more than once", spec, key)
+		}
+		seen[key] = true
+		t.parts = append(t.parts, p)
+		rest = rest[end+1:]
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("invalid naming template %q: no fields", spec)
+	}
+
+	re, err := t.regexp(nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid naming template %q: %w", spec, err)
+	}
+	t.re = re
+	return t, nil
+}
+
+// regexp returns the regular expression matching the names built by the
+// template, with a group per field. With v, the text fields must equal
+// those of v.
+func (t *Template) regexp(v *Values) (*regexp.Regexp, error) {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for _, p := range t.parts {
+		if p.field == fieldLiteral {
+			pattern.WriteString(regexp.QuoteMeta(p.text))
+			continue
+		}
+		if text, ok := p.value(v); ok {
+			pattern.WriteString("(" + regexp.QuoteMeta(text) + ")")
+			continue
+		}
+		pattern.WriteString("(" + p.pattern() + ")")
+	}
+	pattern.WriteString("$")
+	return regexp.Compile(pattern.String())
+}
+
+// parseField parses the text between the braces of a field.
+func parseField(text string) (part, error) {
+	name, arg, hasArg := strings.Cut(text, ":")
+	switch name {
+	case fieldPrefix, fieldSuffix, fieldLabel, fieldHost:
+		if hasArg {
+			return part{}, fmt.Errorf("field %s takes no argument", name)
+		}
+		return part{field: name}, nil
+	case fieldSeq:
+		p := part{field: name}
+		if hasArg {
+			width, err := strconv.Atoi(arg)
+			if err != nil || width < 1 || width > 20 {
+				return part{}, fmt.Errorf("invalid sequence width %q: must be 1 to 20", arg)
+			}
+			p.width = width
+		}
+		return p, nil
+	case fieldDate, fieldUTCDate:
+		directives, err := splitFormat(arg)
+		if err != nil {
+			return part{}, err
+		}
+		return part{field: name, directives: directives}, nil
+	}
+	return part{}, fmt.Errorf("unknown field {%s}", text)
+}
+
+// splitFormat splits a strftime format into literal text and directives.
+func splitFormat(format string) ([]string, error) {
+	if format == "" {
+		return nil, fmt.Errorf("date format is required, e.g. {date:%%Y%%m%%d}")
+	}
+	var directives []string
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			directives = append(directives, format[i:i+1])
+			continue
+		}
+		if i+1 == len(format) {
+			return nil, fmt.Errorf("date format %q ends with %%", format)
+		}
+		d := format[i : i+2]
+		if _, ok := directivePatterns[d]; !ok && d != "%%" {
+			return nil, fmt.Errorf("unsupported date directive %s in %q", d, format)
+		}
+		directives = append(directives, d)
+		i++
+	}
+	// %s has no fixed width, so the digits that follow it could not be told
+	// apart from its own.
+	for i := 1; i < len(directives); i++ {
+		if directives[i-1] != "%s" {
+			continue
+		}
+		d := directives[i]
+		if _, ok := directivePatterns[d]; ok || (d[0] >= '0' && d[0] <= '9') {
+			return nil, fmt.Errorf("date directive %s cannot follow %%s in %q", d, format)
+		}
+	}
+	return directives, nil
+}
+
+// directivePatterns are the regular expressions of the supported strftime
+// directives.
+var directivePatterns = map[string]string{
+	"%Y": `\d{4}`,
+	"%y": `\d{2}`,
+	"%m": `\d{2}`,
+	"%d": `\d{2}`,
+	"%H": `\d{2}`,
+	"%M": `\d{2}`,
+	"%S": `\d{2}`,
+	"%j": `\d{3}`,
+	"%s": `\d+`,
+}
+
+// directiveRegexps match the text of a directive at the start of a string.
+var directiveRegexps = func() map[string]*regexp.Regexp {
+	res := make(map[string]*regexp.Regexp, len(directivePatterns))
+	for d, pattern := range directivePatterns {
+		res[d] = regexp.MustCompile("^" + pattern)
+	}
+	return res
+}()
+
+// value returns the text of a prefix, suffix, label or hostname field in
+// v, and false for other fields or a nil v.
+func (p part) value(v *Values) (string, bool) {
+	if v == nil {
+		return "", false
+	}
+	switch p.field {
+	case fieldPrefix:
+		return v.Prefix, true
+	case fieldSuffix:
+		return v.Suffix, true
+	case fieldLabel:
+		return v.Label, true
+	case fieldHost:
+		return v.Hostname, true
+	}
+	return "", false
+}
+
+// pattern returns the regular expression matching a field.
+func (p part) pattern() string {
+	switch p.field {
+	case fieldSeq:
+		if p.width > 0 {
+			return fmt.Sprintf(`\d{%d,}`, p.width)
+		}
+		return `\d+`
+	case fieldDate, fieldUTCDate:
+		var pattern strings.Builder
+		for _, d := range p.directives {
+			switch {
+			case d == "%%":
+				pattern.WriteString("%")
+			case len(d) == 2 && d[0] == '%':
+				pattern.WriteString(directivePatterns[d])
+			default:
+				pattern.WriteString(regexp.QuoteMeta(d))
+			}
+		}
+		return pattern.String()
+	}
+	return `.+?`
+}
+
+// String returns the template as it was parsed.
+func (t *Template) String() string {
+	return t.spec
+}
+
+// HasDate reports whether the template has a date field, so the time of a
+// name can be recovered by Parse.
+func (t *Template) HasDate() bool {
+	return t.uses(fieldDate) || t.uses(fieldUTCDate)
+}
+
+// HasSequence reports whether the template has a sequence field, see
+// NextSequence.
+func (t *Template) HasSequence() bool {
+	return t.uses(fieldSeq)
+}
+
+func (t *Template) uses(field string) bool {
+	for _, p := range t.parts {
+		if p.field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Format returns the snapshot name for v.
+func (t *Template) Format(v Values) string {
+	var name strings.Builder
+	for _, p := range t.parts {
+		switch p.field {
+		case fieldLiteral:
+			name.WriteString(p.text)
+		case fieldPrefix:
+			name.WriteString(v.Prefix)
+		case fieldSuffix:
+			name.WriteString(v.Suffix)
+		case fieldLabel:
+			name.WriteString(v.Label)
+		case fieldHost:
+			name.WriteString(v.Hostname)
+		case fieldSeq:
+			fmt.Fprintf(&name, "%0*d", p.width, v.Sequence)
+		case fieldDate:
+			name.WriteString(formatDate(p.directives, v.Time.Local()))
+		case fieldUTCDate:
+			name.WriteString(formatDate(p.directives, v.Time.UTC()))
+		}
+	}
+	return name.String()
+}
+
+// formatDate formats t with strftime directives.
+func formatDate(directives []string, t time.Time) string {
+	var out strings.Builder
+	for _, d := range directives {
+		switch d {
+		case "%Y":
+			fmt.Fprintf(&out, "%04d", t.Year())
+		case "%y":
+			fmt.Fprintf(&out, "%02d", t.Year()%100)
+		case "%m":
+			fmt.Fprintf(&out, "%02d", int(t.Month()))
+		case "%d":
+			fmt.Fprintf(&out, "%02d", t.Day())
+		case "%H":
+			fmt.Fprintf(&out, "%02d", t.Hour())
+		case "%M":
+			fmt.Fprintf(&out, "%02d", t.Minute())
+		case "%S":
+			fmt.Fprintf(&out, "%02d", t.Second())
+		case "%j":
+			fmt.Fprintf(&out, "%03d", t.YearDay())
+		case "%s":
+			fmt.Fprintf(&out, "%d", t.Unix())
+		case "%%":
+			out.WriteString("%")
+		default:
+			out.WriteString(d)
+		}
+	}
+	return out.String()
+}
+
+// Parse returns the values of a snapshot name built by the template, and
+// false if the template cannot have built it. name may be a full snapshot
+// name, in which case the part after the @ is parsed. Fields the template
+// does not use are left empty.
+func (t *Template) Parse(name string) (Values, bool) {
+	return t.parse(t.re, name)
+}
+
+// parse parses name with re, a regular expression returned by t.regexp.
+func (t *Template) parse(re *regexp.Regexp, name string) (Values, bool) {
+	if _, snapshot, ok := strings.Cut(name, "@"); ok {
+		name = snapshot
+	}
+	m := re.FindStringSubmatch(name)
+	if m == nil {
+		return Values{}, false
+	}
+
+	var v Values
+	groups := m[1:]
+	for _, p := range t.parts {
+		if p.field == fieldLiteral {
+			continue
+		}
+		text := groups[0]
+		groups = groups[1:]
+		switch p.field {
+		case fieldPrefix:
+			v.Prefix = text
+		case fieldSuffix:
+			v.Suffix = text
+		case fieldLabel:
+			v.Label = text
+		case fieldHost:
+			v.Hostname = text
+		case fieldSeq:
+			seq, err := strconv.Atoi(text)
+			if err != nil {
+				return Values{}, false
+			}
+			v.Sequence = seq
+		case fieldDate, fieldUTCDate:
+			loc := time.Local
+			if p.field == fieldUTCDate {
+				loc = time.UTC
+			}
+			parsed, ok := parseDate(p.directives, text, loc)
+			if !ok {
+				return Values{}, false
+			}
+			v.Time = parsed
+		}
+	}
+	return v, true
+}
+
+// parseDate parses text formatted by formatDate. It rejects dates that do
+// not exist, such as month 13.
+func parseDate(directives []string, text string, loc *time.Location) (time.Time, bool) {
+	year, month, day, yearDay := 1970, 1, 1, 0
+	var hour, minute, second int
+	var unix int64
+	hasUnix := false
+
+	rest := text
+	for _, d := range directives {
+		re, ok := directiveRegexps[d]
+		if !ok {
+			// A literal byte or %%, which was matched by the template.
+			if rest == "" {
+				return time.Time{}, false
+			}
+			rest = rest[1:]
+			continue
+		}
+		digits := re.FindString(rest)
+		if digits == "" {
+			return time.Time{}, false
+		}
+		rest = rest[len(digits):]
+		if d == "%s" {
+			n, err := strconv.ParseInt(digits, 10, 64)
+			if err != nil {
+				return time.Time{}, false
+			}
+			unix, hasUnix = n, true
+			continue
+		}
+		n, _ := strconv.Atoi(digits)
+		switch d {
+		case "%Y":
+			year = n
+		case "%y":
+			year = 2000 + n
+		case "%m":
+			month = n
+		case "%d":
+			day = n
+		case "%H":
+			hour = n
+		case "%M":
+			minute = n
+		case "%S":
+			second = n
+		case "%j":
+			yearDay = n
+		}
+	}
+
+	var t time.Time
+	switch {
+	case hasUnix:
+		t = time.Unix(unix, 0).In(loc)
+	case yearDay > 0:
+		t = time.Date(year, 1, yearDay, hour, minute, second, 0, loc)
+	default:
+		t = time.Date(year, time.Month(month), day, hour, minute, second, 0, loc)
+	}
+	// time.Date normalizes out of range values, so a date that does not
+	// exist formats differently.
+	if formatDate(directives, t) != text {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Find returns the values of the names that the template built for the
+// prefix, suffix, label and hostname of v, in order. Unlike comparing the
+// results of Parse, it also finds names whose text fields contain the text
+// separating them, such as the prefix "zfs-auto" in "{prefix}-{label}".
+func (t *Template) Find(names []string, v Values) []Values {
+	re, err := t.regexp(&v)
+	if err != nil {
+		return nil
+	}
+	var found []Values
+	for _, name := range names {
+		if parsed, ok := t.parse(re, name); ok {
+			found = append(found, parsed)
+		}
+	}
+	return found
+}
+
+// Filter returns the names that the template built for the prefix, suffix,
+// label and hostname of v, in order, see Find.
+func (t *Template) Filter(names []string, v Values) []string {
+	re, err := t.regexp(&v)
+	if err != nil {
+		return nil
+	}
+	var matched []string
+	for _, name := range names {
+		if _, ok := t.parse(re, name); ok {
+			matched = append(matched, name)
+		}
+	}
+	return matched
+}
+
+// NextSequence returns the sequence number following the highest one of
+// the names that the template built for v, see Find, or 1 if there are
+// none.
+func (t *Template) NextSequence(names []string, v Values) int {
+	next := 1
+	for _, found := range t.Find(names, v) {
+		if found.Sequence >= next && found.Sequence < math.MaxInt {
+			next = found.Sequence + 1
+		}
+	}
+	return next
+}
+
+// Hostname returns the host name up to the first dot, for Values.Hostname.
+func Hostname() (string, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	host, _, _ = strings.Cut(host, ".")
+	return host, nil
+}
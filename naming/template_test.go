@@ -0,0 +1,157 @@
+package naming
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTemplateFormatAndParse(t *testing.T) {
+	local := time.FixedZone("EST", -5*3600)
+	original := time.Local
+	time.Local = local
+	t.Cleanup(func() { time.Local = original })
+
+	now := time.Date(2025, 1, 15, 10, 30, 5, 0, local)
+	values := Values{Prefix: "zfssnap", Suffix: "manual", Label: "hourly", Hostname: "db1", Sequence: 7, Time: now}
+
+	tests := []struct {
+		name     string
+		spec     string
+		expected string
+		time     time.Time
+	}{
+		{name: "local date", spec: "{prefix}_{date:%Y-%m-%d_%H%M}_{label}", expected: "zfssnap_2025-01-15_1030_hourly", time: now.Truncate(time.Minute)},
+		{name: "utc date", spec: "{label}-{utcdate:%Y%m%dT%H%M%SZ}", expected: "hourly-20250115T153005Z", time: now},
+		{name: "hostname and sequence", spec: "{hostname}.{label}.{seq:4}", expected: "db1.hourly.0007"},
+		{name: "unpadded sequence", spec: "{label}-{seq}", expected: "hourly-7"},
+		{name: "short year and day of year", spec: "{label}-{date:%y%j.%H%M%S}-{suffix}", expected: "hourly-25015.103005-manual", time: now},
+		{name: "unix time", spec: "{label}-{date:%s}", expected: "hourly-1736955005", time: now},
+		{name: "legacy", spec: "{prefix}-{label}-{suffix}-{date:%Y%m%d-%H%M%S}", expected: "zfssnap-hourly-manual-20250115-103005", time: now},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseTemplate(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseTemplate: %v", err)
+			}
+			name := tmpl.Format(values)
+			if name != tt.expected {
+				t.Fatalf("Expected %q, got %q", tt.expected, name)
+			}
+
+			parsed, ok := tmpl.Parse("pool/data@" + name)
+			if !ok {
+				t.Fatalf("Parse(%q): no match", name)
+			}
+			if formatted := tmpl.Format(parsed); formatted != name {
+				t.Errorf("Expected parsed values to format as %q, got %q from %+v", name, formatted, parsed)
+			}
+			if !parsed.Time.Equal(tt.time) {
+				t.Errorf("Expected time %v, got %v", tt.time, parsed.Time)
+			}
+		})
+	}
+}
+
+func TestTemplateParseRejects(t *testing.T) {
+	tmpl, err := ParseTemplate("{label}_{date:%Y-%m-%d_%H%M}")
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	for _, name := range []string{
+		"hourly_2025-13-01_1030",
+		"hourly_2025-02-30_1030",
+		"hourly_2025-01-15_1030_extra",
+		"hourly-2025-01-15_1030",
+		"_2025-01-15_1030",
+		"manual",
+	} {
+		if v, ok := tmpl.Parse(name); ok {
+			t.Errorf("Parse(%q): expected no match, got %+v", name, v)
+		}
+	}
+}
+
+func TestParseDateUnmatched(t *testing.T) {
+	for _, tt := range []struct {
+		directives []string
+		text       string
+	}{
+		{directives: []string{"%s", "0"}, text: "17000000000"},
+		{directives: []string{"%s", "%Y"}, text: "17000000000"},
+		{directives: []string{"%Y", "-", "%m"}, text: "2025-"},
+	} {
+		if got, ok := parseDate(tt.directives, tt.text, time.UTC); ok {
+			t.Errorf("parseDate(%q, %q): expected no match, got %v", tt.directives, tt.text, got)
+		}
+	}
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"snapshot",
+		"{label",
+		"label}",
+		"{name}",
+		"{label}-{label}",
+		"{date:%Y}-{utcdate:%m}",
+		"{date}",
+		"{date:%Q}",
+		"{date:%Y%}",
+		"{date:%s0}",
+		"{date:%s%Y}",
+		"{seq:0}",
+		"{seq:x}",
+		"{label:upper}",
+	} {
+		if _, err := ParseTemplate(spec); err == nil {
+			t.Errorf("ParseTemplate(%q): expected error", spec)
+		}
+	}
+}
+
+func TestTemplateFindAndNextSequence(t *testing.T) {
+	tmpl, err := ParseTemplate("{hostname}_{label}_{seq:3}")
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	names := []string{
+		"pool/data@db1_hourly_001",
+		"pool/data@db1_hourly_012",
+		"pool/data@db1_daily_040",
+		"pool/data@db2_hourly_099",
+		"pool/data@manual",
+	}
+
+	hourly := Values{Hostname: "db1", Label: "hourly"}
+	if found := tmpl.Find(names, hourly); len(found) != 2 || found[1].Sequence != 12 {
+		t.Errorf("Unexpected matches: %+v", found)
+	}
+	if matched := tmpl.Filter(names, hourly); len(matched) != 2 || matched[1] != "pool/data@db1_hourly_012" {
+		t.Errorf("Unexpected filtered names: %v", matched)
+	}
+	if next := tmpl.NextSequence(names, hourly); next != 13 {
+		t.Errorf("Expected next sequence 13, got %d", next)
+	}
+	if next := tmpl.NextSequence(names, Values{Hostname: "db1", Label: "weekly"}); next != 1 {
+		t.Errorf("Expected next sequence 1, got %d", next)
+	}
+}
+
+func TestTemplateFindSeparatorsInValues(t *testing.T) {
+	tmpl, err := ParseTemplate("{prefix}-{label}-{date:%Y%m%d-%H%M%S}")
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	names := []string{"zfs-auto-hourly-20250115-103000", "zfs-auto-daily-20250115-103000"}
+
+	if v, ok := tmpl.Parse(names[0]); !ok || v.Prefix != "zfs" || v.Label != "auto-hourly" {
+		t.Errorf("Expected the shortest prefix, got %+v, %v", v, ok)
+	}
+	found := tmpl.Find(names, Values{Prefix: "zfs-auto", Label: "hourly"})
+	if len(found) != 1 || found[0].Prefix != "zfs-auto" || found[0].Label != "hourly" || found[0].Time.IsZero() {
+		t.Errorf("Unexpected matches: %+v", found)
+	}
+}
@@ -0,0 +1,397 @@
+// Package replicate copies snapshots between datasets with zfs send and
+// receive, incrementally from the newest snapshot both datasets share or a
+// bookmark of it.
+package replicate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+	"github.com/jsirianni/zfssnap/zfs"
+)
+
+// Mode describes the stream sent by a replication.
+type Mode string
+
+const (
+	// ModeFull sends a full stream into a new target dataset.
+	ModeFull Mode = "full"
+
+	// ModeIncremental sends the changes since the newest common snapshot.
+	ModeIncremental Mode = "incremental"
+
+	// ModeUpToDate sends nothing; the target already has the newest snapshot.
+	ModeUpToDate Mode = "up-to-date"
+)
+
+// Option configures a Replicator.
+type Option func(*Replicator)
+
+// WithRunner sets the Runner that executes commands. If not provided,
+// zfs.ExecRunner is used.
+func WithRunner(r zfs.PipeRunner) Option { return func(rp *Replicator) { rp.runner = r } }
+
+// WithSource sets the transport and zfs binary of the source side. If not
+// provided, the source is local and uses zfs.DefaultZFSBinary.
+func WithSource(t Transport, zfsPath string) Option {
+	return func(rp *Replicator) { rp.sourceTransport, rp.sourceZFSPath = t, zfsPath }
+}
+
+// WithTarget sets the transport and zfs binary of the target side. If not
+// provided, the target is local and uses zfs.DefaultZFSBinary.
+func WithTarget(t Transport, zfsPath string) Option {
+	return func(rp *Replicator) { rp.targetTransport, rp.targetZFSPath = t, zfsPath }
+}
+
+// WithTimeout sets the timeout of the zfs commands that inspect either side.
+// The stream itself is not subject to it; use the context to bound it.
+func WithTimeout(d time.Duration) Option { return func(rp *Replicator) { rp.timeout = d } }
+
+// Replicator replicates snapshots from a source dataset to a target dataset.
+type Replicator struct {
+	runner          zfs.PipeRunner
+	sourceTransport Transport
+	targetTransport Transport
+	sourceZFSPath   string
+	targetZFSPath   string
+	timeout         time.Duration
+
+	source *zfs.Snapshot
+	target *zfs.Snapshot
+}
+
+// New creates a Replicator with options.
+func New(opts ...Option) *Replicator {
+	r := &Replicator{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(r)
+		}
+	}
+	if r.runner == nil {
+		r.runner = zfs.ExecRunner{}
+	}
+	if r.sourceTransport == nil {
+		r.sourceTransport = Local{}
+	}
+	if r.targetTransport == nil {
+		r.targetTransport = Local{}
+	}
+
+	r.source = zfs.NewSnapshot(
+		zfs.WithZFSPath(r.sourceZFSPath),
+		zfs.WithTimeout(r.timeout),
+		zfs.WithRunner(transportRunner{transport: r.sourceTransport, runner: r.runner}),
+	)
+	r.target = zfs.NewSnapshot(
+		zfs.WithZFSPath(r.targetZFSPath),
+		zfs.WithTimeout(r.timeout),
+		zfs.WithRunner(transportRunner{transport: r.targetTransport, runner: r.runner}),
+	)
+	return r
+}
+
+// Options controls a replication.
+type Options struct {
+	// Intermediates replicates every snapshot between the common snapshot
+	// and the newest source snapshot (-I). Otherwise only the newest is
+	// replicated (-i), and a full stream sends only the newest snapshot.
+	Intermediates bool
+
+	// Force rolls the target back to the common snapshot, discarding changes
+	// made to it since, before receiving (receive -F).
+	Force bool
+
+	// DiscardPartial discards an interrupted receive into the target instead
+	// of resuming it.
+	DiscardPartial bool
+
+	// Bookmark bookmarks the newest replicated snapshot in the source
+	// dataset, with the name of the snapshot. The next replication can be
+	// sent from the bookmark after the snapshot has been destroyed, so the
+	// snapshot can be pruned from the source.
+	Bookmark bool
+
+	// DryRun plans the replication without sending anything.
+	DryRun bool
+}
+
+// Result describes a replication.
+type Result struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Mode   Mode   `json:"mode"`
+
+	// Resumed is the resume token of an interrupted receive that was
+	// completed before replicating.
+	Resumed string `json:"resumed,omitempty"`
+
+	// Discarded is the resume token of an interrupted receive that was
+	// discarded.
+	Discarded string `json:"discarded,omitempty"`
+
+	// ResumeToken is the resume token of an interrupted receive found by a
+	// dry run, which a real run would resume or discard.
+	ResumeToken string `json:"resume_token,omitempty"`
+
+	// Common is the newest snapshot or bookmark of the source whose
+	// snapshot the target also has.
+	Common string `json:"common,omitempty"`
+
+	// Snapshot is the newest source snapshot, which the target has after
+	// the replication.
+	Snapshot string `json:"snapshot"`
+
+	// Bookmark is the bookmark of Snapshot kept in the source.
+	Bookmark string `json:"bookmark,omitempty"`
+
+	DryRun bool `json:"dry_run"`
+}
+
+// Replicate sends the snapshots of source that target does not have into
+// target. An interrupted receive into target is resumed first unless
+// opts.DiscardPartial is set. The target is created by a full stream when it
+// does not exist and is never mounted.
+func (r *Replicator) Replicate(ctx context.Context, source, target string, opts Options) (*Result, error) {
+	source = strings.TrimSpace(source)
+	target = strings.TrimSpace(target)
+	if !zfs.IsValidDatasetName(source) || strings.Contains(source, "@") {
+		return nil, fmt.Errorf("invalid source dataset name format: %s", source)
+	}
+	if !zfs.IsValidDatasetName(target) || strings.Contains(target, "@") {
+		return nil, fmt.Errorf("invalid target dataset name format: %s", target)
+	}
+	result := &Result{Source: source, Target: target, DryRun: opts.DryRun}
+
+	token, exists, err := r.resumeToken(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		switch {
+		case opts.DryRun:
+			result.ResumeToken = token
+		case opts.DiscardPartial:
+			if err := r.target.AbortReceive(ctx, target); err != nil {
+				return nil, err
+			}
+			result.Discarded = token
+		default:
+			if err := r.transfer(ctx, "", zfs.SendOptions{ResumeToken: token}, target, opts); err != nil {
+				return nil, fmt.Errorf("resume receive into %s: %w", target, err)
+			}
+			result.Resumed = token
+			exists = true
+		}
+	}
+
+	snapshots, err := r.source.ListDetailed(ctx, zfs.ListOptions{Dataset: source})
+	if err != nil {
+		return nil, fmt.Errorf("list snapshots of %s: %w", source, err)
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("no snapshots to replicate in %s", source)
+	}
+	sortByCreation(snapshots)
+	newest := snapshots[len(snapshots)-1]
+	result.Snapshot = newest.Name
+
+	bookmarks, err := r.source.Bookmarks(ctx, zfs.ListOptions{Dataset: source})
+	if err != nil {
+		return nil, fmt.Errorf("list bookmarks of %s: %w", source, err)
+	}
+
+	if !exists {
+		result.Mode = ModeFull
+		if opts.DryRun {
+			return result, nil
+		}
+		if err := r.replicateFull(ctx, snapshots, target, opts); err != nil {
+			return nil, err
+		}
+		return r.finish(ctx, result, newest, bookmarks, opts)
+	}
+
+	targetSnapshots, err := r.target.ListDetailed(ctx, zfs.ListOptions{Dataset: target})
+	if err != nil {
+		return nil, fmt.Errorf("list snapshots of %s: %w", target, err)
+	}
+	common := newestCommon(snapshots, bookmarks, targetSnapshots)
+	if common == nil {
+		return nil, fmt.Errorf("%s and %s have no snapshot in common; destroy %s or replicate into a new dataset", source, target, target)
+	}
+	result.Common = common.name
+
+	if common.guid == newest.GUID {
+		result.Mode = ModeUpToDate
+		if opts.DryRun {
+			return result, nil
+		}
+		return r.finish(ctx, result, newest, bookmarks, opts)
+	}
+	result.Mode = ModeIncremental
+	if opts.DryRun {
+		return result, nil
+	}
+	if err := r.replicateIncremental(ctx, snapshots, common, target, opts); err != nil {
+		return nil, err
+	}
+	return r.finish(ctx, result, newest, bookmarks, opts)
+}
+
+// finish bookmarks the newest replicated snapshot when opts.Bookmark is set.
+func (r *Replicator) finish(ctx context.Context, result *Result, newest *model.Snapshot, bookmarks []*model.Bookmark, opts Options) (*Result, error) {
+	if !opts.Bookmark {
+		return result, nil
+	}
+
+	_, snapName, _ := strings.Cut(newest.Name, "@")
+	name := newest.Dataset + "#" + snapName
+	for _, b := range bookmarks {
+		if b.Name != name {
+			continue
+		}
+		if b.GUID != newest.GUID {
+			return nil, fmt.Errorf("bookmark %s already exists for another snapshot", name)
+		}
+		result.Bookmark = name
+		return result, nil
+	}
+
+	if err := r.source.Bookmark(ctx, newest.Name, name); err != nil {
+		return nil, err
+	}
+	result.Bookmark = name
+	return result, nil
+}
+
+// replicateFull creates target from the source snapshots, which are sorted
+// by creation.
+func (r *Replicator) replicateFull(ctx context.Context, snapshots []*model.Snapshot, target string, opts Options) error {
+	newest := snapshots[len(snapshots)-1]
+	if !opts.Intermediates || len(snapshots) == 1 {
+		return r.transfer(ctx, newest.Name, zfs.SendOptions{}, target, opts)
+	}
+
+	oldest := snapshots[0]
+	if err := r.transfer(ctx, oldest.Name, zfs.SendOptions{}, target, opts); err != nil {
+		return err
+	}
+	return r.transfer(ctx, newest.Name, zfs.SendOptions{From: oldest.Name, Intermediates: true}, target, opts)
+}
+
+// replicateIncremental sends the source snapshots newer than common into
+// target. A bookmark cannot be the source of an intermediate stream, so with
+// opts.Intermediates the first snapshot after a bookmark is sent on its own.
+func (r *Replicator) replicateIncremental(ctx context.Context, snapshots []*model.Snapshot, common *base, target string, opts Options) error {
+	newest := snapshots[len(snapshots)-1]
+	if !common.bookmark || !opts.Intermediates {
+		send := zfs.SendOptions{From: common.name, Intermediates: opts.Intermediates}
+		return r.transfer(ctx, newest.Name, send, target, opts)
+	}
+
+	next := newest
+	for _, snap := range snapshots {
+		if snap.Creation.After(common.creation) {
+			next = snap
+			break
+		}
+	}
+	if err := r.transfer(ctx, next.Name, zfs.SendOptions{From: common.name}, target, opts); err != nil {
+		return err
+	}
+	if next == newest {
+		return nil
+	}
+	return r.transfer(ctx, newest.Name, zfs.SendOptions{From: next.Name, Intermediates: true}, target, opts)
+}
+
+// transfer pipes the send of snapshot on the source into a resumable
+// receive into target.
+func (r *Replicator) transfer(ctx context.Context, snapshot string, send zfs.SendOptions, target string, opts Options) error {
+	from, err := r.source.SendCommand(snapshot, send)
+	if err != nil {
+		return err
+	}
+	to, err := r.target.ReceiveCommand(target, zfs.ReceiveOptions{
+		Resumable: true,
+		Force:     opts.Force,
+		Unmounted: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	res, err := r.runner.Pipe(ctx, r.sourceTransport.Command(from), r.targetTransport.Command(to))
+	if err != nil {
+		return fmt.Errorf("%s | %s failed: %w", strings.Join(from, " "), strings.Join(to, " "), err)
+	}
+	if res.ExitCode != 0 {
+		err := zfs.NewError(slices.Concat(from, []string{"|"}, to), string(res.Stderr), res.ExitCode)
+		return fmt.Errorf("%s | %s failed: %w", strings.Join(from, " "), strings.Join(to, " "), err)
+	}
+	return nil
+}
+
+// resumeToken returns the resume token of target and whether it exists.
+func (r *Replicator) resumeToken(ctx context.Context, target string) (string, bool, error) {
+	token, err := r.target.ResumeToken(ctx, target)
+	if err != nil {
+		if errors.Is(err, zfs.ErrDatasetNotFound) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return token, true, nil
+}
+
+// base is a snapshot or bookmark of the source that an incremental stream
+// can be sent from.
+type base struct {
+	name     string
+	guid     uint64
+	creation time.Time
+	bookmark bool
+}
+
+// newestCommon returns the newest snapshot or bookmark of the source whose
+// snapshot the target has, by GUID, or nil if they have none in common.
+// source is sorted by creation. A snapshot is preferred over a bookmark of
+// the same age.
+func newestCommon(source []*model.Snapshot, bookmarks []*model.Bookmark, target []*model.Snapshot) *base {
+	guids := make(map[uint64]bool, len(target))
+	for _, snap := range target {
+		guids[snap.GUID] = true
+	}
+
+	var common *base
+	for i := len(source) - 1; i >= 0; i-- {
+		if source[i].GUID != 0 && guids[source[i].GUID] {
+			common = &base{name: source[i].Name, guid: source[i].GUID, creation: source[i].Creation}
+			break
+		}
+	}
+	for _, b := range bookmarks {
+		if b.GUID == 0 || !guids[b.GUID] {
+			continue
+		}
+		if common == nil || b.Creation.After(common.creation) {
+			common = &base{name: b.Name, guid: b.GUID, creation: b.Creation, bookmark: true}
+		}
+	}
+	return common
+}
+
+// sortByCreation sorts snapshots oldest first. Snapshots with the same
+// creation time keep the order zfs listed them in.
+func sortByCreation(snapshots []*model.Snapshot) {
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].Creation.Before(snapshots[j].Creation)
+	})
+}
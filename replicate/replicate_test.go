@@ -0,0 +1,302 @@
+package replicate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jsirianni/zfssnap/testutil"
+)
+
+const detailedColumns = "name,creation,used,referenced,clones,defer_destroy,logicalused,logicalreferenced,guid,userrefs,written,type"
+
+// listOutput returns `zfs list -H -p` output for snapshots given as
+// name, creation and guid.
+func listOutput(snapshots ...[3]string) string {
+	var b strings.Builder
+	for _, s := range snapshots {
+		fmt.Fprintf(&b, "%s\t%s\t0\t0\t-\toff\t0\t0\t%s\t0\t0\tsnapshot\n", s[0], s[1], s[2])
+	}
+	return b.String()
+}
+
+func expectResumeToken(runner *testutil.FakeRunner, prefix []string, dataset, token string, exitCode int) {
+	argv := append(append([]string(nil), prefix...), "zfs", "get", "-H", "-o", "value", "receive_resume_token", dataset)
+	if exitCode != 0 {
+		runner.Expect(argv...).Return("", fmt.Sprintf("cannot open '%s': dataset does not exist\n", dataset), exitCode)
+		return
+	}
+	runner.Expect(argv...).Return(token+"\n", "", 0)
+}
+
+func expectList(runner *testutil.FakeRunner, prefix []string, dataset, out string) {
+	argv := append(append([]string(nil), prefix...), "zfs", "list", "-H", "-p", "-t", "snapshot", "-o", detailedColumns, "-d", "1", dataset)
+	runner.Expect(argv...).Return(out, "", 0)
+}
+
+func expectBookmarks(runner *testutil.FakeRunner, dataset, out string) {
+	runner.Expect("zfs", "list", "-H", "-p", "-t", "bookmark", "-o", "name,creation,guid,createtxg", "-d", "1", dataset).Return(out, "", 0)
+}
+
+func TestReplicate(t *testing.T) {
+	ctx := context.Background()
+	source := listOutput(
+		[3]string{"tank/data@a", "100", "1"},
+		[3]string{"tank/data@c", "300", "3"},
+		[3]string{"tank/data@b", "200", "2"},
+	)
+
+	t.Run("full", func(t *testing.T) {
+		runner := testutil.NewFakeRunner(t)
+		expectResumeToken(runner, nil, "backup/data", "", 1)
+		expectList(runner, nil, "tank/data", source)
+		expectBookmarks(runner, "tank/data", "")
+		runner.Expect("zfs", "send", "tank/data@c", "|", "zfs", "receive", "-s", "-u", "backup/data")
+
+		result, err := New(WithRunner(runner)).Replicate(ctx, "tank/data", "backup/data", Options{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Mode != ModeFull || result.Snapshot != "tank/data@c" || result.Common != "" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("full with intermediates", func(t *testing.T) {
+		runner := testutil.NewFakeRunner(t)
+		expectResumeToken(runner, nil, "backup/data", "", 1)
+		expectList(runner, nil, "tank/data", source)
+		expectBookmarks(runner, "tank/data", "")
+		runner.Expect("zfs", "send", "tank/data@a", "|", "zfs", "receive", "-s", "-u", "backup/data")
+		runner.Expect("zfs", "send", "-I", "tank/data@a", "tank/data@c", "|", "zfs", "receive", "-s", "-u", "backup/data")
+
+		if _, err := New(WithRunner(runner)).Replicate(ctx, "tank/data", "backup/data", Options{Intermediates: true}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("incremental from newest common snapshot", func(t *testing.T) {
+		runner := testutil.NewFakeRunner(t)
+		expectResumeToken(runner, nil, "backup/data", "-", 0)
+		expectList(runner, nil, "tank/data", source)
+		expectBookmarks(runner, "tank/data", "")
+		// Names differ on the target; snapshots are matched by GUID.
+		expectList(runner, nil, "backup/data", listOutput(
+			[3]string{"backup/data@a", "100", "1"},
+			[3]string{"backup/data@renamed", "200", "2"},
+		))
+		runner.Expect("zfs", "send", "-i", "tank/data@b", "tank/data@c", "|", "zfs", "receive", "-s", "-F", "-u", "backup/data")
+
+		result, err := New(WithRunner(runner)).Replicate(ctx, "tank/data", "backup/data", Options{Force: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Mode != ModeIncremental || result.Common != "tank/data@b" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("up to date", func(t *testing.T) {
+		runner := testutil.NewFakeRunner(t)
+		expectResumeToken(runner, nil, "backup/data", "-", 0)
+		expectList(runner, nil, "tank/data", source)
+		expectBookmarks(runner, "tank/data", "")
+		expectList(runner, nil, "backup/data", listOutput([3]string{"backup/data@c", "300", "3"}))
+
+		result, err := New(WithRunner(runner)).Replicate(ctx, "tank/data", "backup/data", Options{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Mode != ModeUpToDate {
+			t.Errorf("Expected up-to-date, got %+v", result)
+		}
+	})
+
+	t.Run("no common snapshot", func(t *testing.T) {
+		runner := testutil.NewFakeRunner(t)
+		expectResumeToken(runner, nil, "backup/data", "-", 0)
+		expectList(runner, nil, "tank/data", source)
+		expectBookmarks(runner, "tank/data", "")
+		expectList(runner, nil, "backup/data", listOutput([3]string{"backup/data@other", "300", "9"}))
+
+		_, err := New(WithRunner(runner)).Replicate(ctx, "tank/data", "backup/data", Options{})
+		if err == nil || !strings.Contains(err.Error(), "no snapshot in common") {
+			t.Errorf("Expected no common snapshot error, got %v", err)
+		}
+	})
+
+	t.Run("resumes interrupted receive", func(t *testing.T) {
+		runner := testutil.NewFakeRunner(t)
+		expectResumeToken(runner, nil, "backup/data", "1-abc-def", 0)
+		runner.Expect("zfs", "send", "-t", "1-abc-def", "|", "zfs", "receive", "-s", "-u", "backup/data")
+		expectList(runner, nil, "tank/data", source)
+		expectBookmarks(runner, "tank/data", "")
+		expectList(runner, nil, "backup/data", listOutput(
+			[3]string{"backup/data@a", "100", "1"},
+			[3]string{"backup/data@b", "200", "2"},
+		))
+		runner.Expect("zfs", "send", "-i", "tank/data@b", "tank/data@c", "|", "zfs", "receive", "-s", "-u", "backup/data")
+
+		result, err := New(WithRunner(runner)).Replicate(ctx, "tank/data", "backup/data", Options{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Resumed != "1-abc-def" || result.Mode != ModeIncremental {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("discards interrupted receive", func(t *testing.T) {
+		runner := testutil.NewFakeRunner(t)
+		expectResumeToken(runner, nil, "backup/data", "1-abc-def", 0)
+		runner.Expect("zfs", "receive", "-A", "backup/data")
+		expectList(runner, nil, "tank/data", source)
+		expectBookmarks(runner, "tank/data", "")
+		expectList(runner, nil, "backup/data", listOutput([3]string{"backup/data@c", "300", "3"}))
+
+		result, err := New(WithRunner(runner)).Replicate(ctx, "tank/data", "backup/data", Options{DiscardPartial: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Discarded != "1-abc-def" || result.Resumed != "" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		runner := testutil.NewFakeRunner(t)
+		expectResumeToken(runner, nil, "backup/data", "-", 0)
+		expectList(runner, nil, "tank/data", source)
+		expectBookmarks(runner, "tank/data", "")
+		expectList(runner, nil, "backup/data", listOutput([3]string{"backup/data@a", "100", "1"}))
+
+		result, err := New(WithRunner(runner)).Replicate(ctx, "tank/data", "backup/data", Options{DryRun: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !result.DryRun || result.Mode != ModeIncremental || result.Common != "tank/data@a" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("dry run with interrupted receive", func(t *testing.T) {
+		runner := testutil.NewFakeRunner(t)
+		expectResumeToken(runner, nil, "backup/data", "1-abc-def", 0)
+		expectList(runner, nil, "tank/data", source)
+		expectBookmarks(runner, "tank/data", "")
+		expectList(runner, nil, "backup/data", listOutput([3]string{"backup/data@a", "100", "1"}))
+
+		result, err := New(WithRunner(runner)).Replicate(ctx, "tank/data", "backup/data", Options{DryRun: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.ResumeToken != "1-abc-def" || result.Resumed != "" || result.Discarded != "" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("ssh target", func(t *testing.T) {
+		ssh := []string{"ssh", "-o", "BatchMode=yes", "-p", "2222", "-i", "/root/.ssh/backup", "--", "backup@nas"}
+		runner := testutil.NewFakeRunner(t)
+		expectResumeToken(runner, ssh, "backup/data", "", 1)
+		expectList(runner, nil, "tank/data", source)
+		expectBookmarks(runner, "tank/data", "")
+		runner.Expect(append([]string{"zfs", "send", "tank/data@c", "|"}, append(ssh, "zfs", "receive", "-s", "-u", "backup/data")...)...)
+
+		r := New(
+			WithRunner(runner),
+			WithTarget(SSH{Host: "backup@nas", Port: 2222, IdentityFile: "/root/.ssh/backup"}, ""),
+		)
+		if _, err := r.Replicate(ctx, "tank/data", "backup/data", Options{}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("failed stream", func(t *testing.T) {
+		runner := testutil.NewFakeRunner(t)
+		expectResumeToken(runner, nil, "backup/data", "", 1)
+		expectList(runner, nil, "tank/data", source)
+		expectBookmarks(runner, "tank/data", "")
+		runner.Expect("zfs", "send", "tank/data@c", "|", "zfs", "receive", "-s", "-u", "backup/data").
+			Return("", "cannot receive: connection reset\n", 1)
+
+		_, err := New(WithRunner(runner)).Replicate(ctx, "tank/data", "backup/data", Options{})
+		if err == nil || !strings.Contains(err.Error(), "connection reset") {
+			t.Errorf("Expected stream error, got %v", err)
+		}
+	})
+}
+
+func TestReplicateBookmarks(t *testing.T) {
+	ctx := context.Background()
+	// a and b were pruned from the source after being replicated; only
+	// bookmarks of them are left.
+	source := listOutput(
+		[3]string{"tank/data@c", "300", "3"},
+		[3]string{"tank/data@d", "400", "4"},
+	)
+	bookmarks := "tank/data#a\t100\t1\t10\ntank/data#b\t200\t2\t20\n"
+	target := listOutput(
+		[3]string{"backup/data@a", "100", "1"},
+		[3]string{"backup/data@b", "200", "2"},
+	)
+
+	t.Run("incremental from bookmark", func(t *testing.T) {
+		runner := testutil.NewFakeRunner(t)
+		expectResumeToken(runner, nil, "backup/data", "-", 0)
+		expectList(runner, nil, "tank/data", source)
+		expectBookmarks(runner, "tank/data", bookmarks)
+		expectList(runner, nil, "backup/data", target)
+		runner.Expect("zfs", "send", "-i", "tank/data#b", "tank/data@d", "|", "zfs", "receive", "-s", "-u", "backup/data")
+		runner.Expect("zfs", "bookmark", "tank/data@d", "tank/data#d")
+
+		result, err := New(WithRunner(runner)).Replicate(ctx, "tank/data", "backup/data", Options{Bookmark: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Common != "tank/data#b" || result.Bookmark != "tank/data#d" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("intermediates from bookmark", func(t *testing.T) {
+		runner := testutil.NewFakeRunner(t)
+		expectResumeToken(runner, nil, "backup/data", "-", 0)
+		expectList(runner, nil, "tank/data", source)
+		expectBookmarks(runner, "tank/data", bookmarks)
+		expectList(runner, nil, "backup/data", target)
+		runner.Expect("zfs", "send", "-i", "tank/data#b", "tank/data@c", "|", "zfs", "receive", "-s", "-u", "backup/data")
+		runner.Expect("zfs", "send", "-I", "tank/data@c", "tank/data@d", "|", "zfs", "receive", "-s", "-u", "backup/data")
+
+		if _, err := New(WithRunner(runner)).Replicate(ctx, "tank/data", "backup/data", Options{Intermediates: true}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("existing bookmark is kept", func(t *testing.T) {
+		runner := testutil.NewFakeRunner(t)
+		expectResumeToken(runner, nil, "backup/data", "-", 0)
+		expectList(runner, nil, "tank/data", source)
+		expectBookmarks(runner, "tank/data", bookmarks+"tank/data#d\t400\t4\t40\n")
+		expectList(runner, nil, "backup/data", listOutput([3]string{"backup/data@d", "400", "4"}))
+
+		result, err := New(WithRunner(runner)).Replicate(ctx, "tank/data", "backup/data", Options{Bookmark: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Mode != ModeUpToDate || result.Bookmark != "tank/data#d" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+}
+
+func TestSSHCommand(t *testing.T) {
+	ssh := SSH{Host: "nas", Options: []string{"StrictHostKeyChecking=yes"}, Binary: "/usr/bin/ssh"}
+	got := ssh.Command([]string{"zfs", "send", "-t", "1-abc", "pool/my data@it's"})
+	want := []string{"/usr/bin/ssh", "-o", "BatchMode=yes", "-o", "StrictHostKeyChecking=yes", "--", "nas",
+		"zfs", "send", "-t", "1-abc", `'pool/my data@it'\''s'`}
+	if strings.Join(got, "\x00") != strings.Join(want, "\x00") {
+		t.Errorf("Unexpected command:\n got: %q\nwant: %q", got, want)
+	}
+}
@@ -0,0 +1,91 @@
+package replicate
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/jsirianni/zfssnap/zfs"
+)
+
+// Transport determines where the zfs commands of one side of a replication
+// run.
+type Transport interface {
+	// Command returns the argv that runs argv through the transport.
+	Command(argv []string) []string
+}
+
+// Local is a Transport that runs commands on this host.
+type Local struct{}
+
+// Command implements Transport.
+func (Local) Command(argv []string) []string { return argv }
+
+// DefaultSSHBinary is the ssh binary used when SSH.Binary is empty.
+const DefaultSSHBinary = "ssh"
+
+// SSH is a Transport that runs commands on a remote host with ssh. The
+// remote user must be able to run zfs non-interactively.
+type SSH struct {
+	// Host is the remote host, optionally as user@host.
+	Host string
+
+	// Port is the remote port. Zero uses the ssh default.
+	Port int
+
+	// IdentityFile is the private key used to authenticate (-i).
+	IdentityFile string
+
+	// Options are passed to ssh as -o options, e.g. "StrictHostKeyChecking=yes".
+	Options []string
+
+	// Binary is the path to ssh. If empty, DefaultSSHBinary is used.
+	Binary string
+}
+
+// Command implements Transport. ssh joins its arguments into a single
+// remote shell command, so each argument is quoted.
+func (s SSH) Command(argv []string) []string {
+	binary := s.Binary
+	if binary == "" {
+		binary = DefaultSSHBinary
+	}
+
+	// BatchMode fails instead of prompting for a password, which would hang
+	// on the stream.
+	cmd := []string{binary, "-o", "BatchMode=yes"}
+	if s.Port > 0 {
+		cmd = append(cmd, "-p", strconv.Itoa(s.Port))
+	}
+	if s.IdentityFile != "" {
+		cmd = append(cmd, "-i", s.IdentityFile)
+	}
+	for _, opt := range s.Options {
+		cmd = append(cmd, "-o", opt)
+	}
+	cmd = append(cmd, "--", s.Host)
+	for _, arg := range argv {
+		cmd = append(cmd, shellQuote(arg))
+	}
+	return cmd
+}
+
+// shellQuote quotes s for a POSIX shell. Arguments made only of characters
+// that are safe in dataset names are left as they are.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789@%+=:,./_-") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// transportRunner is a zfs.Runner that runs commands through a Transport.
+type transportRunner struct {
+	transport Transport
+	runner    zfs.Runner
+}
+
+// Run implements zfs.Runner.
+func (r transportRunner) Run(ctx context.Context, argv []string) (zfs.Result, error) {
+	return r.runner.Run(ctx, r.transport.Command(argv))
+}
@@ -0,0 +1,151 @@
+// Package retention provides Sanoid-style snapshot retention planning.
+package retention
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+)
+
+// Policy defines how many snapshots to keep per dataset. For each period,
+// the newest snapshot in each of the last N periods that contain a snapshot
+// is kept. Periods are calendar aligned in UTC; weeks follow ISO 8601.
+type Policy struct {
+	// Latest keeps the N most recent snapshots regardless of age.
+	Latest int `json:"latest"`
+
+	// Hourly keeps the newest snapshot of each of the last N hours.
+	Hourly int `json:"hourly"`
+
+	// Daily keeps the newest snapshot of each of the last N days.
+	Daily int `json:"daily"`
+
+	// Weekly keeps the newest snapshot of each of the last N weeks.
+	Weekly int `json:"weekly"`
+
+	// Monthly keeps the newest snapshot of each of the last N months.
+	Monthly int `json:"monthly"`
+
+	// Yearly keeps the newest snapshot of each of the last N years.
+	Yearly int `json:"yearly"`
+}
+
+// Validate returns an error if the policy has negative counts or would
+// keep no snapshots at all, which would destroy every snapshot.
+func (p Policy) Validate() error {
+	counts := []struct {
+		name string
+		n    int
+	}{
+		{"latest", p.Latest},
+		{"hourly", p.Hourly},
+		{"daily", p.Daily},
+		{"weekly", p.Weekly},
+		{"monthly", p.Monthly},
+		{"yearly", p.Yearly},
+	}
+	keeps := false
+	for _, c := range counts {
+		if c.n < 0 {
+			return fmt.Errorf("%s must not be negative", c.name)
+		}
+		// The counts are not summed, so that large counts cannot overflow.
+		keeps = keeps || c.n > 0
+	}
+	if !keeps {
+		return fmt.Errorf("policy keeps no snapshots")
+	}
+	return nil
+}
+
+// Decision records whether a snapshot is kept and why.
+type Decision struct {
+	Snapshot *model.Snapshot `json:"snapshot"`
+
+	// Reasons lists each rule that retained the snapshot, e.g. "daily 2025-01-02".
+	// It is empty for snapshots that will be destroyed.
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Plan is the result of applying a Policy to a set of snapshots.
+type Plan struct {
+	Keep    []Decision `json:"keep"`
+	Destroy []Decision `json:"destroy"`
+}
+
+// period describes a retention bucket: how many to keep and how to key a time.
+type period struct {
+	name  string
+	count int
+	key   func(t time.Time) string
+}
+
+// Apply builds a retention plan. Snapshots are grouped by Dataset and
+// evaluated newest first by Creation. The returned plan lists datasets in
+// name order and snapshots newest first within each dataset.
+func Apply(snapshots []*model.Snapshot, policy Policy) *Plan {
+	periods := []period{
+		{name: "hourly", count: policy.Hourly, key: func(t time.Time) string { return t.Format("2006-01-02T15") }},
+		{name: "daily", count: policy.Daily, key: func(t time.Time) string { return t.Format("2006-01-02") }},
+		{name: "weekly", count: policy.Weekly, key: func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{name: "monthly", count: policy.Monthly, key: func(t time.Time) string { return t.Format("2006-01") }},
+		{name: "yearly", count: policy.Yearly, key: func(t time.Time) string { return t.Format("2006") }},
+	}
+
+	byDataset := make(map[string][]*model.Snapshot)
+	for _, s := range snapshots {
+		if s == nil {
+			continue
+		}
+		byDataset[s.Dataset] = append(byDataset[s.Dataset], s)
+	}
+	datasets := make([]string, 0, len(byDataset))
+	for d := range byDataset {
+		datasets = append(datasets, d)
+	}
+	sort.Strings(datasets)
+
+	plan := &Plan{Keep: []Decision{}, Destroy: []Decision{}}
+	for _, dataset := range datasets {
+		group := byDataset[dataset]
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].Creation.After(group[j].Creation)
+		})
+
+		reasons := make([][]string, len(group))
+		for i := 0; i < policy.Latest && i < len(group); i++ {
+			reasons[i] = append(reasons[i], fmt.Sprintf("latest %d", i+1))
+		}
+		for _, p := range periods {
+			if p.count <= 0 {
+				continue
+			}
+			seen := make(map[string]bool)
+			for i, s := range group {
+				if len(seen) >= p.count {
+					break
+				}
+				k := p.key(s.Creation.UTC())
+				if seen[k] {
+					continue
+				}
+				seen[k] = true
+				reasons[i] = append(reasons[i], p.name+" "+k)
+			}
+		}
+
+		for i, s := range group {
+			if len(reasons[i]) > 0 {
+				plan.Keep = append(plan.Keep, Decision{Snapshot: s, Reasons: reasons[i]})
+				continue
+			}
+			plan.Destroy = append(plan.Destroy, Decision{Snapshot: s})
+		}
+	}
+	return plan
+}
@@ -0,0 +1,192 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+)
+
+func snapshotAt(name string, creation time.Time) *model.Snapshot {
+	dataset := name
+	for i := range name {
+		if name[i] == '@' {
+			dataset = name[:i]
+			break
+		}
+	}
+	return &model.Snapshot{Name: name, Dataset: dataset, Creation: creation}
+}
+
+func names(decisions []Decision) []string {
+	out := make([]string, 0, len(decisions))
+	for _, d := range decisions {
+		out = append(out, d.Snapshot.Name)
+	}
+	return out
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      Policy
+		expectError bool
+		message     string
+	}{
+		{
+			name:   "daily only",
+			policy: Policy{Daily: 7},
+		},
+		{
+			name:   "latest only",
+			policy: Policy{Latest: 1},
+		},
+		{
+			name:        "empty policy",
+			policy:      Policy{},
+			expectError: true,
+		},
+		{
+			name:        "negative count",
+			policy:      Policy{Daily: 7, Hourly: -1},
+			expectError: true,
+		},
+		{
+			name:        "several negative counts",
+			policy:      Policy{Yearly: -1, Weekly: -1, Hourly: -1, Latest: -1},
+			expectError: true,
+			message:     "latest must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			// The first invalid count is reported on every run.
+			for i := 0; tt.message != "" && i < 20; i++ {
+				if err := tt.policy.Validate(); err == nil || err.Error() != tt.message {
+					t.Fatalf("Expected %q, got %v", tt.message, err)
+				}
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	base := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		snapshots       []*model.Snapshot
+		policy          Policy
+		expectedKeep    []string
+		expectedDestroy []string
+	}{
+		{
+			name: "hourly keeps newest per hour",
+			snapshots: []*model.Snapshot{
+				snapshotAt("pool/data@a", base.Add(-2*time.Hour)),
+				snapshotAt("pool/data@b", base.Add(-90*time.Minute)),
+				snapshotAt("pool/data@c", base.Add(-60*time.Minute)),
+				snapshotAt("pool/data@d", base.Add(-15*time.Minute)),
+				snapshotAt("pool/data@e", base),
+			},
+			policy:          Policy{Hourly: 2},
+			expectedKeep:    []string{"pool/data@e", "pool/data@d"},
+			expectedDestroy: []string{"pool/data@c", "pool/data@b", "pool/data@a"},
+		},
+		{
+			name: "daily skips days without snapshots",
+			snapshots: []*model.Snapshot{
+				snapshotAt("pool/data@d1", base.AddDate(0, 0, -9)),
+				snapshotAt("pool/data@d5", base.AddDate(0, 0, -5)),
+				snapshotAt("pool/data@d9", base.AddDate(0, 0, -1)),
+			},
+			policy:          Policy{Daily: 2},
+			expectedKeep:    []string{"pool/data@d9", "pool/data@d5"},
+			expectedDestroy: []string{"pool/data@d1"},
+		},
+		{
+			name: "latest and monthly combine",
+			snapshots: []*model.Snapshot{
+				snapshotAt("pool/data@nov", time.Date(2024, 11, 30, 0, 0, 0, 0, time.UTC)),
+				snapshotAt("pool/data@dec1", time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)),
+				snapshotAt("pool/data@dec2", time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)),
+				snapshotAt("pool/data@jan", base),
+			},
+			policy:          Policy{Latest: 1, Monthly: 2},
+			expectedKeep:    []string{"pool/data@jan", "pool/data@dec2"},
+			expectedDestroy: []string{"pool/data@dec1", "pool/data@nov"},
+		},
+		{
+			name: "datasets are evaluated independently",
+			snapshots: []*model.Snapshot{
+				snapshotAt("pool/b@old", base.Add(-time.Hour)),
+				snapshotAt("pool/a@old", base.Add(-time.Hour)),
+				snapshotAt("pool/b@new", base),
+				snapshotAt("pool/a@new", base),
+			},
+			policy:          Policy{Latest: 1},
+			expectedKeep:    []string{"pool/a@new", "pool/b@new"},
+			expectedDestroy: []string{"pool/a@old", "pool/b@old"},
+		},
+		{
+			name:            "no snapshots",
+			snapshots:       nil,
+			policy:          Policy{Daily: 7},
+			expectedKeep:    []string{},
+			expectedDestroy: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := Apply(tt.snapshots, tt.policy)
+			if keep := names(plan.Keep); !equalStrings(keep, tt.expectedKeep) {
+				t.Errorf("Expected keep %v, got %v", tt.expectedKeep, keep)
+			}
+			if destroy := names(plan.Destroy); !equalStrings(destroy, tt.expectedDestroy) {
+				t.Errorf("Expected destroy %v, got %v", tt.expectedDestroy, destroy)
+			}
+		})
+	}
+}
+
+func TestApplyReasons(t *testing.T) {
+	snap := snapshotAt("pool/data@only", time.Date(2025, 1, 6, 8, 30, 0, 0, time.UTC))
+
+	plan := Apply([]*model.Snapshot{snap}, Policy{Latest: 1, Hourly: 1, Daily: 1, Weekly: 1, Monthly: 1, Yearly: 1})
+	if len(plan.Keep) != 1 {
+		t.Fatalf("Expected 1 kept snapshot, got %d", len(plan.Keep))
+	}
+
+	expected := []string{
+		"latest 1",
+		"hourly 2025-01-06T08",
+		"daily 2025-01-06",
+		"weekly 2025-W02",
+		"monthly 2025-01",
+		"yearly 2025",
+	}
+	if !equalStrings(plan.Keep[0].Reasons, expected) {
+		t.Errorf("Expected reasons %v, got %v", expected, plan.Keep[0].Reasons)
+	}
+}
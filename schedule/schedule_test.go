@@ -0,0 +1,150 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{name: "empty", spec: ""},
+		{name: "too few fields", spec: "* * * *"},
+		{name: "too many fields", spec: "* * * * * *"},
+		{name: "minute out of range", spec: "60 * * * *"},
+		{name: "month out of range", spec: "0 0 1 13 *"},
+		{name: "bad step", spec: "*/0 * * * *"},
+		{name: "inverted range", spec: "30-10 * * * *"},
+		{name: "not a number", spec: "a * * * *"},
+		{name: "bad interval", spec: "@every soon"},
+		{name: "interval too short", spec: "@every 30s"},
+		{name: "unknown descriptor", spec: "@fortnightly"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.spec); err == nil {
+				t.Errorf("Expected error for %q but got none", tt.spec)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	// Wednesday
+	from := time.Date(2025, 1, 15, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		spec     string
+		expected time.Time
+	}{
+		{
+			name:     "every minute",
+			spec:     "* * * * *",
+			expected: time.Date(2025, 1, 15, 10, 8, 0, 0, time.UTC),
+		},
+		{
+			name:     "hourly",
+			spec:     "@hourly",
+			expected: time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "daily",
+			spec:     "@daily",
+			expected: time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekly on sunday",
+			spec:     "@weekly",
+			expected: time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "monthly",
+			spec:     "@monthly",
+			expected: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "yearly",
+			spec:     "@yearly",
+			expected: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "step",
+			spec:     "*/15 * * * *",
+			expected: time.Date(2025, 1, 15, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			name:     "list",
+			spec:     "5,40 * * * *",
+			expected: time.Date(2025, 1, 15, 10, 40, 0, 0, time.UTC),
+		},
+		{
+			name:     "range with step",
+			spec:     "0 9-17/4 * * *",
+			expected: time.Date(2025, 1, 15, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekdays",
+			spec:     "30 2 * * 1-5",
+			expected: time.Date(2025, 1, 16, 2, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "sunday as seven",
+			spec:     "0 0 * * 7",
+			expected: time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "day of month or day of week",
+			spec:     "0 0 20 * 5",
+			expected: time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "interval aligned",
+			spec:     "@every 15m",
+			expected: time.Date(2025, 1, 15, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			name:     "interval not dividing an hour",
+			spec:     "@every 7m",
+			expected: time.Date(2025, 1, 15, 10, 14, 0, 0, time.UTC),
+		},
+		{
+			name:     "interval not dividing a day",
+			spec:     "@every 5h",
+			expected: time.Date(2025, 1, 15, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "never matches",
+			spec:     "0 0 31 2 *",
+			expected: time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if next := s.Next(from); !next.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, next)
+			}
+		})
+	}
+}
+
+func TestNextIsStrictlyAfter(t *testing.T) {
+	s, err := Parse("0 * * * *")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	on := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	expected := time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC)
+	if next := s.Next(on); !next.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, next)
+	}
+}
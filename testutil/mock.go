@@ -6,24 +6,56 @@ import (
 	"time"
 
 	"github.com/jsirianni/zfssnap/model"
+	"github.com/jsirianni/zfssnap/zfs"
 )
 
 // MockSnapshotter is a mock implementation of Snapshotter for testing.
 type MockSnapshotter struct {
-	ListFunc   func(ctx context.Context) ([]string, error)
-	GetFunc    func(ctx context.Context, name string) (*model.Snapshot, error)
-	CreateFunc func(ctx context.Context, name, dataset string) error
-	DeleteFunc func(ctx context.Context, name string) error
+	ListFunc           func(ctx context.Context, opts zfs.ListOptions) ([]string, error)
+	ListDetailedFunc   func(ctx context.Context, opts zfs.ListOptions) ([]*model.Snapshot, error)
+	GetFunc            func(ctx context.Context, name string) (*model.Snapshot, error)
+	CreateFunc         func(ctx context.Context, dataset, name string, opts zfs.CreateOptions) error
+	DeleteFunc         func(ctx context.Context, name string, opts zfs.DeleteOptions) (*zfs.DeleteResult, error)
+	HoldFunc           func(ctx context.Context, name, tag string, opts zfs.HoldOptions) error
+	ReleaseFunc        func(ctx context.Context, name, tag string, opts zfs.HoldOptions) error
+	HoldsFunc          func(ctx context.Context, name string, opts zfs.HoldOptions) ([]*model.Hold, error)
+	RollbackFunc       func(ctx context.Context, name string, opts zfs.RollbackOptions) error
+	CopyFunc           func(ctx context.Context, snapshot, target string) error
+	CloneFunc          func(ctx context.Context, snapshot, target string, opts zfs.CloneOptions) error
+	PromoteFunc        func(ctx context.Context, dataset string) error
+	BookmarkFunc       func(ctx context.Context, snapshot, bookmark string) error
+	BookmarksFunc      func(ctx context.Context, opts zfs.ListOptions) ([]*model.Bookmark, error)
+	DeleteBookmarkFunc func(ctx context.Context, name string) error
+	DiffFunc           func(ctx context.Context, from, to string) ([]*model.Change, error)
 }
 
+// Compile-time checks that MockSnapshotter implements the zfs interfaces.
+var (
+	_ zfs.Snapshotter = (*MockSnapshotter)(nil)
+	_ zfs.Holder      = (*MockSnapshotter)(nil)
+	_ zfs.Rollbacker  = (*MockSnapshotter)(nil)
+	_ zfs.Copier      = (*MockSnapshotter)(nil)
+	_ zfs.Cloner      = (*MockSnapshotter)(nil)
+	_ zfs.Bookmarker  = (*MockSnapshotter)(nil)
+	_ zfs.Differ      = (*MockSnapshotter)(nil)
+)
+
 // List implements Snapshotter.List.
-func (m *MockSnapshotter) List(ctx context.Context) ([]string, error) {
+func (m *MockSnapshotter) List(ctx context.Context, opts zfs.ListOptions) ([]string, error) {
 	if m.ListFunc != nil {
-		return m.ListFunc(ctx)
+		return m.ListFunc(ctx, opts)
 	}
 	return []string{}, nil
 }
 
+// ListDetailed implements Snapshotter.ListDetailed.
+func (m *MockSnapshotter) ListDetailed(ctx context.Context, opts zfs.ListOptions) ([]*model.Snapshot, error) {
+	if m.ListDetailedFunc != nil {
+		return m.ListDetailedFunc(ctx, opts)
+	}
+	return []*model.Snapshot{}, nil
+}
+
 // Get implements Snapshotter.Get.
 func (m *MockSnapshotter) Get(ctx context.Context, name string) (*model.Snapshot, error) {
 	if m.GetFunc != nil {
@@ -33,32 +65,126 @@ func (m *MockSnapshotter) Get(ctx context.Context, name string) (*model.Snapshot
 }
 
 // Create implements Snapshotter.Create.
-func (m *MockSnapshotter) Create(ctx context.Context, name, dataset string) error {
+func (m *MockSnapshotter) Create(ctx context.Context, dataset, name string, opts zfs.CreateOptions) error {
 	if m.CreateFunc != nil {
-		return m.CreateFunc(ctx, name, dataset)
+		return m.CreateFunc(ctx, dataset, name, opts)
 	}
 	return nil
 }
 
 // Delete implements Snapshotter.Delete.
-func (m *MockSnapshotter) Delete(ctx context.Context, name string) error {
+func (m *MockSnapshotter) Delete(ctx context.Context, name string, opts zfs.DeleteOptions) (*zfs.DeleteResult, error) {
 	if m.DeleteFunc != nil {
-		return m.DeleteFunc(ctx, name)
+		return m.DeleteFunc(ctx, name, opts)
+	}
+	return &zfs.DeleteResult{Destroyed: []string{name}}, nil
+}
+
+// Hold implements Holder.Hold.
+func (m *MockSnapshotter) Hold(ctx context.Context, name, tag string, opts zfs.HoldOptions) error {
+	if m.HoldFunc != nil {
+		return m.HoldFunc(ctx, name, tag, opts)
+	}
+	return nil
+}
+
+// Release implements Holder.Release.
+func (m *MockSnapshotter) Release(ctx context.Context, name, tag string, opts zfs.HoldOptions) error {
+	if m.ReleaseFunc != nil {
+		return m.ReleaseFunc(ctx, name, tag, opts)
 	}
 	return nil
 }
 
+// Holds implements Holder.Holds.
+func (m *MockSnapshotter) Holds(ctx context.Context, name string, opts zfs.HoldOptions) ([]*model.Hold, error) {
+	if m.HoldsFunc != nil {
+		return m.HoldsFunc(ctx, name, opts)
+	}
+	return []*model.Hold{}, nil
+}
+
+// Rollback implements Rollbacker.Rollback.
+func (m *MockSnapshotter) Rollback(ctx context.Context, name string, opts zfs.RollbackOptions) error {
+	if m.RollbackFunc != nil {
+		return m.RollbackFunc(ctx, name, opts)
+	}
+	return nil
+}
+
+// Copy implements Copier.Copy.
+func (m *MockSnapshotter) Copy(ctx context.Context, snapshot, target string) error {
+	if m.CopyFunc != nil {
+		return m.CopyFunc(ctx, snapshot, target)
+	}
+	return nil
+}
+
+// Clone implements Cloner.Clone.
+func (m *MockSnapshotter) Clone(ctx context.Context, snapshot, target string, opts zfs.CloneOptions) error {
+	if m.CloneFunc != nil {
+		return m.CloneFunc(ctx, snapshot, target, opts)
+	}
+	return nil
+}
+
+// Promote implements Cloner.Promote.
+func (m *MockSnapshotter) Promote(ctx context.Context, dataset string) error {
+	if m.PromoteFunc != nil {
+		return m.PromoteFunc(ctx, dataset)
+	}
+	return nil
+}
+
+// Bookmark implements Bookmarker.Bookmark.
+func (m *MockSnapshotter) Bookmark(ctx context.Context, snapshot, bookmark string) error {
+	if m.BookmarkFunc != nil {
+		return m.BookmarkFunc(ctx, snapshot, bookmark)
+	}
+	return nil
+}
+
+// Bookmarks implements Bookmarker.Bookmarks.
+func (m *MockSnapshotter) Bookmarks(ctx context.Context, opts zfs.ListOptions) ([]*model.Bookmark, error) {
+	if m.BookmarksFunc != nil {
+		return m.BookmarksFunc(ctx, opts)
+	}
+	return []*model.Bookmark{}, nil
+}
+
+// DeleteBookmark implements Bookmarker.DeleteBookmark.
+func (m *MockSnapshotter) DeleteBookmark(ctx context.Context, name string) error {
+	if m.DeleteBookmarkFunc != nil {
+		return m.DeleteBookmarkFunc(ctx, name)
+	}
+	return nil
+}
+
+// Diff implements Differ.Diff.
+func (m *MockSnapshotter) Diff(ctx context.Context, from, to string) ([]*model.Change, error) {
+	if m.DiffFunc != nil {
+		return m.DiffFunc(ctx, from, to)
+	}
+	return []*model.Change{}, nil
+}
+
 // NewMockSnapshotter creates a new MockSnapshotter with default implementations.
 func NewMockSnapshotter() *MockSnapshotter {
 	return &MockSnapshotter{}
 }
 
 // WithListFunc sets the List function for the mock.
-func (m *MockSnapshotter) WithListFunc(fn func(ctx context.Context) ([]string, error)) *MockSnapshotter {
+func (m *MockSnapshotter) WithListFunc(fn func(ctx context.Context, opts zfs.ListOptions) ([]string, error)) *MockSnapshotter {
 	m.ListFunc = fn
 	return m
 }
 
+// WithListDetailedFunc sets the ListDetailed function for the mock.
+func (m *MockSnapshotter) WithListDetailedFunc(fn func(ctx context.Context, opts zfs.ListOptions) ([]*model.Snapshot, error)) *MockSnapshotter {
+	m.ListDetailedFunc = fn
+	return m
+}
+
 // WithGetFunc sets the Get function for the mock.
 func (m *MockSnapshotter) WithGetFunc(fn func(ctx context.Context, name string) (*model.Snapshot, error)) *MockSnapshotter {
 	m.GetFunc = fn
@@ -66,17 +192,117 @@ func (m *MockSnapshotter) WithGetFunc(fn func(ctx context.Context, name string)
 }
 
 // WithCreateFunc sets the Create function for the mock.
-func (m *MockSnapshotter) WithCreateFunc(fn func(ctx context.Context, name, dataset string) error) *MockSnapshotter {
+func (m *MockSnapshotter) WithCreateFunc(fn func(ctx context.Context, dataset, name string, opts zfs.CreateOptions) error) *MockSnapshotter {
 	m.CreateFunc = fn
 	return m
 }
 
 // WithDeleteFunc sets the Delete function for the mock.
-func (m *MockSnapshotter) WithDeleteFunc(fn func(ctx context.Context, name string) error) *MockSnapshotter {
+func (m *MockSnapshotter) WithDeleteFunc(fn func(ctx context.Context, name string, opts zfs.DeleteOptions) (*zfs.DeleteResult, error)) *MockSnapshotter {
 	m.DeleteFunc = fn
 	return m
 }
 
+// WithHoldFunc sets the Hold function for the mock.
+func (m *MockSnapshotter) WithHoldFunc(fn func(ctx context.Context, name, tag string, opts zfs.HoldOptions) error) *MockSnapshotter {
+	m.HoldFunc = fn
+	return m
+}
+
+// WithReleaseFunc sets the Release function for the mock.
+func (m *MockSnapshotter) WithReleaseFunc(fn func(ctx context.Context, name, tag string, opts zfs.HoldOptions) error) *MockSnapshotter {
+	m.ReleaseFunc = fn
+	return m
+}
+
+// WithHoldsFunc sets the Holds function for the mock.
+func (m *MockSnapshotter) WithHoldsFunc(fn func(ctx context.Context, name string, opts zfs.HoldOptions) ([]*model.Hold, error)) *MockSnapshotter {
+	m.HoldsFunc = fn
+	return m
+}
+
+// WithRollbackFunc sets the Rollback function for the mock.
+func (m *MockSnapshotter) WithRollbackFunc(fn func(ctx context.Context, name string, opts zfs.RollbackOptions) error) *MockSnapshotter {
+	m.RollbackFunc = fn
+	return m
+}
+
+// WithCopyFunc sets the Copy function for the mock.
+func (m *MockSnapshotter) WithCopyFunc(fn func(ctx context.Context, snapshot, target string) error) *MockSnapshotter {
+	m.CopyFunc = fn
+	return m
+}
+
+// WithCloneFunc sets the Clone function for the mock.
+func (m *MockSnapshotter) WithCloneFunc(fn func(ctx context.Context, snapshot, target string, opts zfs.CloneOptions) error) *MockSnapshotter {
+	m.CloneFunc = fn
+	return m
+}
+
+// WithPromoteFunc sets the Promote function for the mock.
+func (m *MockSnapshotter) WithPromoteFunc(fn func(ctx context.Context, dataset string) error) *MockSnapshotter {
+	m.PromoteFunc = fn
+	return m
+}
+
+// WithBookmarkFunc sets the Bookmark function for the mock.
+func (m *MockSnapshotter) WithBookmarkFunc(fn func(ctx context.Context, snapshot, bookmark string) error) *MockSnapshotter {
+	m.BookmarkFunc = fn
+	return m
+}
+
+// WithBookmarksFunc sets the Bookmarks function for the mock.
+func (m *MockSnapshotter) WithBookmarksFunc(fn func(ctx context.Context, opts zfs.ListOptions) ([]*model.Bookmark, error)) *MockSnapshotter {
+	m.BookmarksFunc = fn
+	return m
+}
+
+// WithDeleteBookmarkFunc sets the DeleteBookmark function for the mock.
+func (m *MockSnapshotter) WithDeleteBookmarkFunc(fn func(ctx context.Context, name string) error) *MockSnapshotter {
+	m.DeleteBookmarkFunc = fn
+	return m
+}
+
+// WithDiffFunc sets the Diff function for the mock.
+func (m *MockSnapshotter) WithDiffFunc(fn func(ctx context.Context, from, to string) ([]*model.Change, error)) *MockSnapshotter {
+	m.DiffFunc = fn
+	return m
+}
+
+// MockDatasets is a mock implementation of zfs.Datasets for testing.
+type MockDatasets struct {
+	ListFunc     func(ctx context.Context, root string) ([]*model.Dataset, error)
+	GetFunc      func(ctx context.Context, name string) (*model.Dataset, error)
+	ChildrenFunc func(ctx context.Context, name string) ([]*model.Dataset, error)
+}
+
+// Compile-time check that MockDatasets implements zfs.Datasets.
+var _ zfs.Datasets = (*MockDatasets)(nil)
+
+// List implements Datasets.List.
+func (m *MockDatasets) List(ctx context.Context, root string) ([]*model.Dataset, error) {
+	if m.ListFunc != nil {
+		return m.ListFunc(ctx, root)
+	}
+	return []*model.Dataset{}, nil
+}
+
+// Get implements Datasets.Get.
+func (m *MockDatasets) Get(ctx context.Context, name string) (*model.Dataset, error) {
+	if m.GetFunc != nil {
+		return m.GetFunc(ctx, name)
+	}
+	return &model.Dataset{Name: name, Type: "filesystem"}, nil
+}
+
+// Children implements Datasets.Children.
+func (m *MockDatasets) Children(ctx context.Context, name string) ([]*model.Dataset, error) {
+	if m.ChildrenFunc != nil {
+		return m.ChildrenFunc(ctx, name)
+	}
+	return []*model.Dataset{}, nil
+}
+
 // TestData contains real ZFS command outputs for testing.
 type TestData struct {
 	ListOutput []string
@@ -128,9 +354,16 @@ func NewTestData() *TestData {
 // CreateMockSnapshotter creates a MockSnapshotter with test data.
 func (td *TestData) CreateMockSnapshotter() *MockSnapshotter {
 	return NewMockSnapshotter().
-		WithListFunc(func(_ context.Context) ([]string, error) {
+		WithListFunc(func(_ context.Context, _ zfs.ListOptions) ([]string, error) {
 			return td.ListOutput, nil
 		}).
+		WithListDetailedFunc(func(_ context.Context, _ zfs.ListOptions) ([]*model.Snapshot, error) {
+			snapshots := make([]*model.Snapshot, 0, len(td.ListOutput))
+			for _, name := range td.ListOutput {
+				snapshots = append(snapshots, td.GetOutput[name])
+			}
+			return snapshots, nil
+		}).
 		WithGetFunc(func(_ context.Context, name string) (*model.Snapshot, error) {
 			if snapshot, exists := td.GetOutput[name]; exists {
 				return snapshot, nil
@@ -0,0 +1,106 @@
+package testutil
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jsirianni/zfssnap/zfs"
+)
+
+// FakeRunner is a scripted zfs.Runner. Each expected command is replayed in
+// order: Run asserts the argv matches the next expectation and returns its
+// canned result. Every command run is recorded. Unconsumed expectations fail
+// the test when it finishes.
+type FakeRunner struct {
+	t testing.TB
+
+	mu       sync.Mutex
+	expected []*FakeCall
+	calls    [][]string
+}
+
+// FakeCall is an expected command and the result returned for it.
+type FakeCall struct {
+	argv   []string
+	result zfs.Result
+	err    error
+}
+
+// Compile-time check that FakeRunner implements zfs.PipeRunner.
+var _ zfs.PipeRunner = (*FakeRunner)(nil)
+
+// NewFakeRunner creates a FakeRunner that reports failures to t.
+func NewFakeRunner(t testing.TB) *FakeRunner {
+	f := &FakeRunner{t: t}
+	t.Cleanup(f.assertDone)
+	return f
+}
+
+// Expect adds an expected command. By default it succeeds with no output.
+func (f *FakeRunner) Expect(argv ...string) *FakeCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	call := &FakeCall{argv: argv}
+	f.expected = append(f.expected, call)
+	return call
+}
+
+// Return sets the output and exit code of the call.
+func (c *FakeCall) Return(stdout, stderr string, exitCode int) *FakeCall {
+	c.result = zfs.Result{Stdout: []byte(stdout), Stderr: []byte(stderr), ExitCode: exitCode}
+	return c
+}
+
+// Fail makes the call fail to run with err, e.g. a missing binary or timeout.
+func (c *FakeCall) Fail(err error) *FakeCall {
+	c.err = err
+	return c
+}
+
+// Run implements zfs.Runner.
+func (f *FakeRunner) Run(_ context.Context, argv []string) (zfs.Result, error) {
+	f.t.Helper()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, append([]string(nil), argv...))
+	if len(f.expected) == 0 {
+		f.t.Errorf("unexpected command: %s", strings.Join(argv, " "))
+		return zfs.Result{ExitCode: 1}, nil
+	}
+
+	call := f.expected[0]
+	f.expected = f.expected[1:]
+	if strings.Join(argv, "\x00") != strings.Join(call.argv, "\x00") {
+		f.t.Errorf("unexpected command:\n got: %s\nwant: %s", strings.Join(argv, " "), strings.Join(call.argv, " "))
+	}
+	return call.result, call.err
+}
+
+// Pipe implements zfs.PipeRunner. The pipeline is matched and recorded as a
+// single command: the argv of from, "|", then the argv of to.
+func (f *FakeRunner) Pipe(ctx context.Context, from, to []string) (zfs.Result, error) {
+	f.t.Helper()
+
+	argv := append(append(append([]string(nil), from...), "|"), to...)
+	return f.Run(ctx, argv)
+}
+
+// Calls returns the argv of every command run so far.
+func (f *FakeRunner) Calls() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]string(nil), f.calls...)
+}
+
+func (f *FakeRunner) assertDone() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, call := range f.expected {
+		f.t.Errorf("expected command was not run: %s", strings.Join(call.argv, " "))
+	}
+}
@@ -0,0 +1,1271 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+	"github.com/jsirianni/zfssnap/zfs"
+)
+
+// Simulator is a stateful in-memory ZFS backend. It models pools, datasets,
+// snapshots, holds, clones, file changes and space accounting closely
+// enough to exercise the CLI, retention and scheduling code without a real
+// pool. Errors use the same wording as the zfs command line tool and are
+// classified like the errors of zfs.Snapshot.
+//
+// Space is tracked as extents of data that are born and freed at a
+// transaction group (txg), like blocks in ZFS. A snapshot references every
+// extent that was live at its txg. Its used space is the data referenced by
+// that snapshot alone, which is what destroying it would reclaim.
+type Simulator struct {
+	clock *Clock
+
+	mu       sync.Mutex
+	txg      uint64
+	guid     uint64
+	datasets map[string]*simDataset
+}
+
+type simDataset struct {
+	name     string
+	creation time.Time
+
+	// origin is the snapshot a clone was created from.
+	origin string
+
+	properties map[string]string
+
+	extents   []*simExtent
+	snapshots []*simSnapshot
+	bookmarks []*simBookmark
+
+	// changes are the file changes recorded by Change, in order.
+	changes []simChange
+}
+
+// simChange is a file change made at a txg.
+type simChange struct {
+	txg    uint64
+	change model.Change
+}
+
+type simExtent struct {
+	size  uint64
+	born  uint64
+	freed uint64
+
+	// shared extents belong to the origin of a clone and are never
+	// charged to the clone.
+	shared bool
+}
+
+type simSnapshot struct {
+	dataset      *simDataset
+	name         string
+	txg          uint64
+	creation     time.Time
+	guid         uint64
+	holds        map[string]time.Time
+	clones       []string
+	deferDestroy bool
+	properties   map[string]string
+}
+
+type simBookmark struct {
+	name     string
+	txg      uint64
+	creation time.Time
+	guid     uint64
+}
+
+// Compile-time checks that Simulator implements the zfs interfaces.
+var (
+	_ zfs.Snapshotter = (*Simulator)(nil)
+	_ zfs.Holder      = (*Simulator)(nil)
+	_ zfs.Rollbacker  = (*Simulator)(nil)
+	_ zfs.Copier      = (*Simulator)(nil)
+	_ zfs.Cloner      = (*Simulator)(nil)
+	_ zfs.Bookmarker  = (*Simulator)(nil)
+	_ zfs.Differ      = (*Simulator)(nil)
+	_ zfs.Datasets    = simDatasets{}
+)
+
+// NewSimulator creates an empty Simulator. Snapshot creation times are taken
+// from clock; a nil clock starts at the current time.
+func NewSimulator(clock *Clock) *Simulator {
+	if clock == nil {
+		clock = NewClock(time.Now())
+	}
+	return &Simulator{
+		clock:    clock,
+		guid:     1 << 60,
+		datasets: make(map[string]*simDataset),
+	}
+}
+
+// Clock returns the clock used for snapshot creation times.
+func (s *Simulator) Clock() *Clock {
+	return s.clock
+}
+
+// CreateDataset creates a dataset, or a pool when name has a single
+// component. Missing parents are created, like `zfs create -p`.
+func (s *Simulator) CreateDataset(name string) error {
+	if !zfs.IsValidDatasetName(name) {
+		return zfsError("cannot create '%s': invalid dataset name", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parts := strings.Split(name, "/")
+	for i := range parts {
+		ds := strings.Join(parts[:i+1], "/")
+		if _, ok := s.datasets[ds]; !ok {
+			s.datasets[ds] = &simDataset{name: ds, creation: s.clock.Now()}
+		}
+	}
+	return nil
+}
+
+// Write adds size bytes of new data to a dataset.
+func (s *Simulator) Write(dataset string, size uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ds, err := s.dataset(dataset)
+	if err != nil {
+		return err
+	}
+	s.txg++
+	ds.extents = append(ds.extents, &simExtent{size: size, born: s.txg})
+	return nil
+}
+
+// Change records changes to the files of a dataset, which Diff reports
+// between the snapshots taken before and after them. A change without a
+// change time is stamped with the clock.
+func (s *Simulator) Change(dataset string, changes ...*model.Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ds, err := s.dataset(dataset)
+	if err != nil {
+		return err
+	}
+	s.txg++
+	for _, c := range changes {
+		change := *c
+		if change.Time.IsZero() {
+			change.Time = s.clock.Now()
+		}
+		ds.changes = append(ds.changes, simChange{txg: s.txg, change: change})
+	}
+	return nil
+}
+
+// Free deletes size bytes of the oldest live data from a dataset. Data that
+// is still referenced by a snapshot is kept until that snapshot is destroyed.
+func (s *Simulator) Free(dataset string, size uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ds, err := s.dataset(dataset)
+	if err != nil {
+		return err
+	}
+	if live := ds.referenced(0); live < size {
+		return zfsError("cannot free %d bytes from '%s': only %d bytes referenced", size, dataset, live)
+	}
+
+	s.txg++
+	remaining := size
+	for _, e := range ds.extents {
+		if remaining == 0 {
+			break
+		}
+		if e.freed != 0 {
+			continue
+		}
+		if e.size > remaining {
+			// Split the extent so only the freed part is marked.
+			e.size -= remaining
+			ds.extents = append(ds.extents, &simExtent{size: remaining, born: e.born, freed: s.txg, shared: e.shared})
+			remaining = 0
+			break
+		}
+		e.freed = s.txg
+		remaining -= e.size
+	}
+	s.collect(ds)
+	return nil
+}
+
+// Hold implements zfs.Holder. A recursive hold is atomic: if the tag
+// already exists on any of the snapshots, none of them is held.
+func (s *Simulator) Hold(ctx context.Context, name, tag string, opts zfs.HoldOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	targets, err := s.holdTargets(name, opts.Recursive)
+	if err != nil {
+		return zfsError("cannot hold snapshot '%s': dataset does not exist", name)
+	}
+	for _, snap := range targets {
+		if _, ok := snap.holds[tag]; ok {
+			return zfsError("cannot hold snapshot '%s': tag already exists on this dataset", snap.fullName())
+		}
+	}
+	now := s.clock.Now()
+	for _, snap := range targets {
+		snap.holds[tag] = now
+	}
+	return nil
+}
+
+// Release implements zfs.Holder. A snapshot marked for deferred destruction
+// is destroyed once its last hold is released.
+func (s *Simulator) Release(ctx context.Context, name, tag string, opts zfs.HoldOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	targets, err := s.holdTargets(name, opts.Recursive)
+	if err != nil {
+		return zfsError("cannot release hold from snapshot '%s': dataset does not exist", name)
+	}
+	for _, snap := range targets {
+		if _, ok := snap.holds[tag]; !ok {
+			return zfsError("cannot release hold from snapshot '%s': no such tag on this dataset", snap.fullName())
+		}
+	}
+	var destroy []*simSnapshot
+	for _, snap := range targets {
+		delete(snap.holds, tag)
+		if snap.deferDestroy && !snap.busy() {
+			destroy = append(destroy, snap)
+		}
+	}
+	s.destroy(destroy)
+	return nil
+}
+
+// Holds implements zfs.Holder. Holds are ordered by snapshot and tag.
+func (s *Simulator) Holds(ctx context.Context, name string, opts zfs.HoldOptions) ([]*model.Hold, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	targets, err := s.holdTargets(name, opts.Recursive)
+	if err != nil {
+		return nil, err
+	}
+	holds := []*model.Hold{}
+	for _, snap := range targets {
+		tags := make([]string, 0, len(snap.holds))
+		for tag := range snap.holds {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			holds = append(holds, &model.Hold{Snapshot: snap.fullName(), Tag: tag, Timestamp: snap.holds[tag]})
+		}
+	}
+	return holds, nil
+}
+
+// Clone implements zfs.Cloner. The snapshot cannot be destroyed while the
+// clone exists.
+func (s *Simulator) Clone(ctx context.Context, snapshot, target string, opts zfs.CloneOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !zfs.IsValidDatasetName(target) {
+		return zfsError("cannot create '%s': invalid dataset name", target)
+	}
+	if err := validateProperties(opts.Properties); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap, err := s.snapshot(snapshot)
+	if err != nil {
+		return zfsError("cannot open '%s': dataset does not exist", snapshot)
+	}
+	if _, ok := s.datasets[target]; ok {
+		return zfsError("cannot create '%s': dataset already exists", target)
+	}
+	parent, _, ok := cutLast(target, "/")
+	if !ok || (s.datasets[parent] == nil && !opts.CreateParents) {
+		return zfsError("cannot create '%s': parent does not exist", target)
+	}
+	for ds := parent; s.datasets[ds] == nil; ds, _, _ = cutLast(ds, "/") {
+		s.datasets[ds] = &simDataset{name: ds, creation: s.clock.Now()}
+	}
+
+	s.txg++
+	clone := &simDataset{name: target, creation: s.clock.Now(), origin: snapshot, properties: copyProperties(opts.Properties)}
+	for _, e := range snap.dataset.extents {
+		if e.live(snap.txg) {
+			clone.extents = append(clone.extents, &simExtent{size: e.size, born: s.txg, shared: true})
+		}
+	}
+	s.datasets[target] = clone
+	snap.clones = append(snap.clones, target)
+	sort.Strings(snap.clones)
+	return nil
+}
+
+// Promote implements zfs.Cloner. The origin snapshot and every snapshot
+// before it move from the origin dataset to the clone, and the origin
+// dataset becomes a clone of the moved origin snapshot.
+//
+// Space accounting is approximate: data of the origin snapshot that the
+// clone freed before it was promoted is counted as live in the clone again.
+func (s *Simulator) Promote(ctx context.Context, dataset string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clone, err := s.dataset(dataset)
+	if err != nil {
+		return err
+	}
+	if clone.origin == "" {
+		return zfsError("cannot promote '%s': not a cloned filesystem", dataset)
+	}
+	originSnap, err := s.snapshot(clone.origin)
+	if err != nil {
+		return err
+	}
+	origin := originSnap.dataset
+
+	var moved, kept []*simSnapshot
+	for _, snap := range origin.snapshots {
+		if snap.txg <= originSnap.txg {
+			moved = append(moved, snap)
+		} else {
+			kept = append(kept, snap)
+		}
+	}
+	for _, snap := range moved {
+		if clone.find(snap.name) != nil {
+			return zfsError("cannot promote '%s': snapshot name conflict: %s", dataset, snap.name)
+		}
+	}
+
+	// The clone takes over the history up to the origin snapshot; the origin
+	// dataset keeps what changed after it on top of a shared copy.
+	var cloneExtents, originExtents []*simExtent
+	for _, e := range clone.extents {
+		if !e.shared {
+			cloneExtents = append(cloneExtents, e)
+		}
+	}
+	for _, e := range origin.extents {
+		switch {
+		case e.born > originSnap.txg:
+			originExtents = append(originExtents, e)
+		case e.live(originSnap.txg):
+			originExtents = append(originExtents, &simExtent{size: e.size, born: originSnap.txg, freed: e.freed, shared: true})
+			cloneExtents = append(cloneExtents, &simExtent{size: e.size, born: e.born, shared: e.shared})
+		default:
+			cloneExtents = append(cloneExtents, e)
+		}
+	}
+	clone.extents = cloneExtents
+	origin.extents = originExtents
+
+	for _, snap := range moved {
+		snap.dataset = clone
+		for _, name := range snap.clones {
+			if ds := s.datasets[name]; ds != nil {
+				ds.origin = clone.name + "@" + snap.name
+			}
+		}
+	}
+	clone.snapshots = append(moved, clone.snapshots...)
+	origin.snapshots = kept
+
+	originSnap.clones = removeString(originSnap.clones, clone.name)
+	originSnap.clones = append(originSnap.clones, origin.name)
+	sort.Strings(originSnap.clones)
+	clone.origin, origin.origin = origin.origin, clone.name+"@"+originSnap.name
+	s.txg++
+	return nil
+}
+
+// Exists reports whether a dataset or snapshot exists.
+func (s *Simulator) Exists(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if strings.Contains(name, "@") {
+		_, err := s.snapshot(name)
+		return err == nil
+	}
+	_, ok := s.datasets[name]
+	return ok
+}
+
+// Property returns a property set on a snapshot or clone when it was
+// created.
+func (s *Simulator) Property(name, property string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var props map[string]string
+	if strings.Contains(name, "@") {
+		snap, err := s.snapshot(name)
+		if err != nil {
+			return "", false
+		}
+		props = snap.properties
+	} else {
+		ds, ok := s.datasets[name]
+		if !ok {
+			return "", false
+		}
+		props = ds.properties
+	}
+	value, ok := props[property]
+	return value, ok
+}
+
+// simDatasets implements zfs.Datasets for a Simulator.
+type simDatasets struct {
+	s *Simulator
+}
+
+// Datasets returns the zfs.Datasets of the simulator. Datasets have no
+// available space or quota, and are mounted at /<name> unless a mountpoint
+// property was set.
+func (s *Simulator) Datasets() zfs.Datasets {
+	return simDatasets{s: s}
+}
+
+// List implements zfs.Datasets.
+func (d simDatasets) List(ctx context.Context, root string) ([]*model.Dataset, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	d.s.mu.Lock()
+	defer d.s.mu.Unlock()
+
+	datasets, err := d.s.scope(root, true)
+	if err != nil {
+		return nil, err
+	}
+	found := make([]*model.Dataset, 0, len(datasets))
+	for _, ds := range datasets {
+		found = append(found, d.s.datasetModel(ds))
+	}
+	return found, nil
+}
+
+// Get implements zfs.Datasets.
+func (d simDatasets) Get(ctx context.Context, name string) (*model.Dataset, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	d.s.mu.Lock()
+	defer d.s.mu.Unlock()
+
+	ds, err := d.s.dataset(name)
+	if err != nil {
+		return nil, err
+	}
+	return d.s.datasetModel(ds), nil
+}
+
+// Children implements zfs.Datasets.
+func (d simDatasets) Children(ctx context.Context, name string) ([]*model.Dataset, error) {
+	found, err := d.List(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	children := []*model.Dataset{}
+	for _, ds := range found {
+		if parent, _, ok := cutLast(ds.Name, "/"); ok && parent == name {
+			children = append(children, ds)
+		}
+	}
+	return children, nil
+}
+
+// datasetModel returns the model of ds. Its used space includes the data
+// held by its snapshots and by its descendents.
+func (s *Simulator) datasetModel(ds *simDataset) *model.Dataset {
+	pool, _, _ := strings.Cut(ds.name, "/")
+	m := &model.Dataset{
+		Name:       ds.name,
+		Pool:       pool,
+		Type:       "filesystem",
+		Creation:   ds.creation,
+		Referenced: ds.referenced(0),
+		Mountpoint: "/" + ds.name,
+		Origin:     ds.origin,
+	}
+	for name, other := range s.datasets {
+		if name != ds.name && !strings.HasPrefix(name, ds.name+"/") {
+			continue
+		}
+		for _, e := range other.extents {
+			if !e.shared {
+				m.Used += e.size
+			}
+		}
+	}
+	for prop, value := range ds.properties {
+		switch {
+		case prop == "mountpoint":
+			m.Mountpoint = value
+		case strings.Contains(prop, ":"):
+			if m.Properties == nil {
+				m.Properties = make(map[string]string)
+			}
+			m.Properties[prop] = value
+		}
+	}
+	return m
+}
+
+// List implements zfs.Snapshotter.
+func (s *Simulator) List(ctx context.Context, opts zfs.ListOptions) ([]string, error) {
+	snapshots, err := s.ListDetailed(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(snapshots))
+	for _, snap := range snapshots {
+		names = append(names, snap.Name)
+	}
+	return names, nil
+}
+
+// ListDetailed implements zfs.Snapshotter. Snapshots are ordered by dataset
+// name and then by creation, as zfs list orders them.
+func (s *Simulator) ListDetailed(ctx context.Context, opts zfs.ListOptions) ([]*model.Snapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	datasets, err := s.scope(opts.Dataset, opts.Recursive)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := []*model.Snapshot{}
+	for _, ds := range datasets {
+		for _, snap := range ds.snapshots {
+			snapshots = append(snapshots, snap.model())
+		}
+	}
+	return snapshots, nil
+}
+
+// Create implements zfs.Snapshotter. A recursive snapshot is atomic: either
+// every dataset in the tree is snapshotted or none is.
+func (s *Simulator) Create(ctx context.Context, dataset, name string, opts zfs.CreateOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	full := dataset + "@" + name
+	if !zfs.IsValidSnapshotName(full) {
+		return zfs.NewArgumentError("invalid snapshot name format: %s", full)
+	}
+	if err := validateProperties(opts.Properties); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	datasets, err := s.scope(dataset, opts.Recursive)
+	if err != nil {
+		return err
+	}
+	for _, ds := range datasets {
+		if ds.find(name) != nil {
+			return zfsError("cannot create snapshot '%s@%s': dataset already exists", ds.name, name)
+		}
+	}
+
+	s.txg++
+	now := s.clock.Now()
+	for _, ds := range datasets {
+		s.guid++
+		ds.snapshots = append(ds.snapshots, &simSnapshot{
+			dataset:    ds,
+			name:       name,
+			txg:        s.txg,
+			creation:   now,
+			guid:       s.guid,
+			holds:      make(map[string]time.Time),
+			properties: copyProperties(opts.Properties),
+		})
+	}
+	return nil
+}
+
+// Delete implements zfs.Snapshotter. Snapshots with holds or clones cannot
+// be destroyed unless opts.Defer is set, in which case they are destroyed
+// once the last hold is released.
+func (s *Simulator) Delete(ctx context.Context, name string, opts zfs.DeleteOptions) (*zfs.DeleteResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !zfs.IsValidSnapshotName(name) {
+		return nil, zfs.NewArgumentError("invalid snapshot name format: %s (must contain @)", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dataset, snapName, _ := strings.Cut(name, "@")
+	datasets, err := s.scope(dataset, opts.Recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []*simSnapshot
+	for _, ds := range datasets {
+		if snap := ds.find(snapName); snap != nil {
+			targets = append(targets, snap)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, zfsError("could not find any snapshots to destroy; check snapshot names")
+	}
+
+	var destroy, deferred []*simSnapshot
+	for _, snap := range targets {
+		switch {
+		case !snap.busy():
+			destroy = append(destroy, snap)
+		case opts.Defer:
+			deferred = append(deferred, snap)
+		case len(snap.clones) > 0:
+			return nil, zfsError("cannot destroy '%s': snapshot has dependent clones", snap.fullName())
+		default:
+			return nil, zfsError("cannot destroy snapshot %s: dataset is busy", snap.fullName())
+		}
+	}
+
+	result := &zfs.DeleteResult{Destroyed: make([]string, 0, len(targets))}
+	for _, snap := range targets {
+		result.Destroyed = append(result.Destroyed, snap.fullName())
+	}
+	for _, ds := range datasets {
+		result.Reclaimed += ds.reclaimable(destroy)
+	}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	for _, snap := range deferred {
+		snap.deferDestroy = true
+	}
+	s.destroy(destroy)
+	return result, nil
+}
+
+// Get implements zfs.Snapshotter.
+func (s *Simulator) Get(ctx context.Context, name string) (*model.Snapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !zfs.IsValidSnapshotName(name) {
+		return nil, zfs.NewArgumentError("invalid snapshot name format: %s (must contain @)", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap, err := s.snapshot(name)
+	if err != nil {
+		return nil, err
+	}
+	return snap.model(), nil
+}
+
+// Rollback implements zfs.Rollbacker. Data written after the snapshot is
+// discarded and data freed after it is restored.
+func (s *Simulator) Rollback(ctx context.Context, name string, opts zfs.RollbackOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !zfs.IsValidSnapshotName(name) {
+		return zfs.NewArgumentError("invalid snapshot name format: %s (must contain @)", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target, err := s.snapshot(name)
+	if err != nil {
+		return err
+	}
+	ds := target.dataset
+
+	var newer []*simSnapshot
+	for _, snap := range ds.snapshots {
+		if snap.txg > target.txg {
+			newer = append(newer, snap)
+		}
+	}
+	var keptBookmarks []*simBookmark
+	for _, b := range ds.bookmarks {
+		if b.txg <= target.txg {
+			keptBookmarks = append(keptBookmarks, b)
+		}
+	}
+	newerBookmarks := len(keptBookmarks) < len(ds.bookmarks)
+	if (len(newer) > 0 || newerBookmarks) && !opts.DestroyNewer && !opts.DestroyClones {
+		return zfsError("cannot rollback to '%s': more recent snapshots or bookmarks exist", name)
+	}
+	for _, snap := range newer {
+		if len(snap.clones) > 0 && !opts.DestroyClones {
+			return zfsError("cannot rollback to '%s': clones of previous snapshots exist", name)
+		}
+		if len(snap.holds) > 0 {
+			return zfsError("cannot destroy snapshot %s: dataset is busy", snap.fullName())
+		}
+	}
+
+	for _, snap := range newer {
+		for _, clone := range snap.clones {
+			s.destroyDataset(clone)
+		}
+		snap.clones = nil
+	}
+	s.destroy(newer)
+	ds.bookmarks = keptBookmarks
+
+	s.txg++
+	kept := ds.extents[:0]
+	for _, e := range ds.extents {
+		if e.born > target.txg {
+			continue
+		}
+		if e.freed > target.txg {
+			e.freed = 0
+		}
+		kept = append(kept, e)
+	}
+	ds.extents = kept
+
+	changes := ds.changes[:0]
+	for _, c := range ds.changes {
+		if c.txg <= target.txg {
+			changes = append(changes, c)
+		}
+	}
+	ds.changes = changes
+	return nil
+}
+
+// Diff implements zfs.Differ with the changes recorded by Change.
+func (s *Simulator) Diff(ctx context.Context, from, to string) ([]*model.Change, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !zfs.IsValidSnapshotName(from) {
+		return nil, zfs.NewArgumentError("invalid snapshot name format: %s (must contain @)", from)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap, err := s.snapshot(from)
+	if err != nil {
+		return nil, err
+	}
+	ds := snap.dataset
+	until := uint64(math.MaxUint64)
+	switch {
+	case to == "" || to == ds.name:
+	case !strings.Contains(to, "@"):
+		return nil, zfsError("cannot diff '%s': not an earlier snapshot from the same fs", from)
+	default:
+		later, err := s.snapshot(to)
+		if err != nil {
+			return nil, err
+		}
+		if later.dataset != ds || later.txg <= snap.txg {
+			return nil, zfsError("cannot diff '%s': not an earlier snapshot from the same fs", from)
+		}
+		until = later.txg
+	}
+
+	changes := []*model.Change{}
+	for _, c := range ds.changes {
+		if c.txg > snap.txg && c.txg <= until {
+			change := c.change
+			changes = append(changes, &change)
+		}
+	}
+	return changes, nil
+}
+
+// Bookmark implements zfs.Bookmarker.
+func (s *Simulator) Bookmark(ctx context.Context, snapshot, bookmark string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !zfs.IsValidBookmarkName(bookmark) {
+		return zfs.NewArgumentError("invalid bookmark name format: %s (must contain #)", bookmark)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap, err := s.snapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	dataset, name, _ := strings.Cut(bookmark, "#")
+	if dataset != snap.dataset.name {
+		return zfsError("cannot create bookmark '%s': must be in the same dataset as the snapshot", bookmark)
+	}
+	if snap.dataset.findBookmark(name) != nil {
+		return zfsError("cannot create bookmark '%s': bookmark exists", bookmark)
+	}
+	snap.dataset.bookmarks = append(snap.dataset.bookmarks, &simBookmark{
+		name:     name,
+		txg:      snap.txg,
+		creation: snap.creation,
+		guid:     snap.guid,
+	})
+	sort.SliceStable(snap.dataset.bookmarks, func(i, j int) bool {
+		return snap.dataset.bookmarks[i].txg < snap.dataset.bookmarks[j].txg
+	})
+	return nil
+}
+
+// Bookmarks implements zfs.Bookmarker. Bookmarks are ordered by dataset
+// name and then by the creation of their snapshots.
+func (s *Simulator) Bookmarks(ctx context.Context, opts zfs.ListOptions) ([]*model.Bookmark, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	datasets, err := s.scope(opts.Dataset, opts.Recursive)
+	if err != nil {
+		return nil, err
+	}
+	bookmarks := []*model.Bookmark{}
+	for _, ds := range datasets {
+		for _, b := range ds.bookmarks {
+			bookmarks = append(bookmarks, &model.Bookmark{
+				Name:      ds.name + "#" + b.name,
+				Dataset:   ds.name,
+				Creation:  b.creation,
+				GUID:      b.guid,
+				CreateTXG: b.txg,
+			})
+		}
+	}
+	return bookmarks, nil
+}
+
+// DeleteBookmark implements zfs.Bookmarker.
+func (s *Simulator) DeleteBookmark(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !zfs.IsValidBookmarkName(name) {
+		return zfs.NewArgumentError("invalid bookmark name format: %s (must contain #)", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dataset, short, _ := strings.Cut(name, "#")
+	ds, err := s.dataset(dataset)
+	if err != nil {
+		return err
+	}
+	for i, b := range ds.bookmarks {
+		if b.name == short {
+			ds.bookmarks = append(ds.bookmarks[:i], ds.bookmarks[i+1:]...)
+			return nil
+		}
+	}
+	return zfsError("cannot destroy '%s': bookmark does not exist", name)
+}
+
+// Copy implements zfs.Copier. The new dataset holds a copy of the snapshot's
+// data and a snapshot with the same name, creation time and guid, as after
+// `zfs send | zfs receive`.
+func (s *Simulator) Copy(ctx context.Context, snapshot, target string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !zfs.IsValidDatasetName(target) {
+		return zfs.NewArgumentError("invalid dataset name format: %s", target)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap, err := s.snapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	if _, ok := s.datasets[target]; ok {
+		return zfsError("cannot receive new filesystem stream: destination '%s' exists", target)
+	}
+	if parent, _, ok := cutLast(target, "/"); !ok || s.datasets[parent] == nil {
+		return zfsError("cannot receive new filesystem stream: parent of '%s' does not exist", target)
+	}
+
+	s.txg++
+	ds := &simDataset{name: target, creation: s.clock.Now()}
+	for _, e := range snap.dataset.extents {
+		if e.live(snap.txg) {
+			ds.extents = append(ds.extents, &simExtent{size: e.size, born: s.txg})
+		}
+	}
+	ds.snapshots = append(ds.snapshots, &simSnapshot{
+		dataset:  ds,
+		name:     snap.name,
+		txg:      s.txg,
+		creation: snap.creation,
+		guid:     snap.guid,
+		holds:    make(map[string]time.Time),
+	})
+	s.datasets[target] = ds
+	return nil
+}
+
+func (s *Simulator) dataset(name string) (*simDataset, error) {
+	ds, ok := s.datasets[name]
+	if !ok {
+		return nil, zfsError("cannot open '%s': dataset does not exist", name)
+	}
+	return ds, nil
+}
+
+func (s *Simulator) snapshot(name string) (*simSnapshot, error) {
+	dataset, snapName, ok := strings.Cut(name, "@")
+	if !ok {
+		return nil, zfsError("cannot open '%s': dataset does not exist", name)
+	}
+	ds, ok := s.datasets[dataset]
+	if !ok {
+		return nil, zfsError("cannot open '%s': dataset does not exist", name)
+	}
+	snap := ds.find(snapName)
+	if snap == nil {
+		return nil, zfsError("cannot open '%s': dataset does not exist", name)
+	}
+	return snap, nil
+}
+
+// holdTargets returns the snapshot name, and with recursive the snapshots
+// of the same name in descendent datasets.
+func (s *Simulator) holdTargets(name string, recursive bool) ([]*simSnapshot, error) {
+	if !recursive {
+		snap, err := s.snapshot(name)
+		if err != nil {
+			return nil, err
+		}
+		return []*simSnapshot{snap}, nil
+	}
+
+	dataset, snapName, _ := strings.Cut(name, "@")
+	datasets, err := s.scope(dataset, true)
+	if err != nil {
+		return nil, err
+	}
+	var targets []*simSnapshot
+	for _, ds := range datasets {
+		if snap := ds.find(snapName); snap != nil {
+			targets = append(targets, snap)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, zfsError("cannot open '%s': dataset does not exist", name)
+	}
+	return targets, nil
+}
+
+// scope returns the datasets selected by a dataset name and recursive flag,
+// sorted by name. An empty name selects every dataset.
+func (s *Simulator) scope(dataset string, recursive bool) ([]*simDataset, error) {
+	var datasets []*simDataset
+	if dataset == "" {
+		for _, ds := range s.datasets {
+			datasets = append(datasets, ds)
+		}
+	} else {
+		if !zfs.IsValidDatasetName(dataset) {
+			return nil, zfs.NewArgumentError("invalid dataset name format: %s", dataset)
+		}
+		root, err := s.dataset(dataset)
+		if err != nil {
+			return nil, err
+		}
+		datasets = append(datasets, root)
+		if recursive {
+			for name, ds := range s.datasets {
+				if strings.HasPrefix(name, dataset+"/") {
+					datasets = append(datasets, ds)
+				}
+			}
+		}
+	}
+	sort.Slice(datasets, func(i, j int) bool { return datasets[i].name < datasets[j].name })
+	return datasets, nil
+}
+
+// destroy removes snapshots and the data only they referenced.
+func (s *Simulator) destroy(snapshots []*simSnapshot) {
+	for _, snap := range snapshots {
+		ds := snap.dataset
+		for i, existing := range ds.snapshots {
+			if existing == snap {
+				ds.snapshots = append(ds.snapshots[:i], ds.snapshots[i+1:]...)
+				break
+			}
+		}
+		s.collect(ds)
+	}
+}
+
+// destroyDataset removes a dataset with its descendents, snapshots and
+// clones, and detaches it from the snapshot it was cloned from.
+func (s *Simulator) destroyDataset(name string) {
+	for dsName, ds := range s.datasets {
+		if dsName != name && !strings.HasPrefix(dsName, name+"/") {
+			continue
+		}
+		for _, snap := range ds.snapshots {
+			for _, clone := range snap.clones {
+				s.destroyDataset(clone)
+			}
+		}
+		delete(s.datasets, dsName)
+
+		if ds.origin == "" {
+			continue
+		}
+		origin, err := s.snapshot(ds.origin)
+		if err != nil {
+			continue
+		}
+		origin.clones = removeString(origin.clones, dsName)
+		if origin.deferDestroy && !origin.busy() {
+			s.destroy([]*simSnapshot{origin})
+		}
+	}
+}
+
+// collect drops extents that are no longer referenced.
+func (s *Simulator) collect(ds *simDataset) {
+	kept := ds.extents[:0]
+	for _, e := range ds.extents {
+		if ds.referencedBy(e, nil) {
+			kept = append(kept, e)
+		}
+	}
+	ds.extents = kept
+}
+
+func (ds *simDataset) find(name string) *simSnapshot {
+	for _, snap := range ds.snapshots {
+		if snap.name == name {
+			return snap
+		}
+	}
+	return nil
+}
+
+// referenced returns the data live at txg, or live now when txg is 0.
+func (ds *simDataset) findBookmark(name string) *simBookmark {
+	for _, b := range ds.bookmarks {
+		if b.name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+func (ds *simDataset) referenced(txg uint64) uint64 {
+	var total uint64
+	for _, e := range ds.extents {
+		if (txg == 0 && e.freed == 0) || (txg != 0 && e.live(txg)) {
+			total += e.size
+		}
+	}
+	return total
+}
+
+// referencedBy reports whether the extent is referenced by the dataset or by
+// any of its snapshots other than those in excluded.
+func (ds *simDataset) referencedBy(e *simExtent, excluded []*simSnapshot) bool {
+	if e.freed == 0 {
+		return true
+	}
+	for _, snap := range ds.snapshots {
+		if e.live(snap.txg) && !containsSnapshot(excluded, snap) {
+			return true
+		}
+	}
+	return false
+}
+
+// reclaimable returns the space that destroying snapshots would free in ds.
+func (ds *simDataset) reclaimable(snapshots []*simSnapshot) uint64 {
+	var mine []*simSnapshot
+	for _, snap := range snapshots {
+		if snap.dataset == ds {
+			mine = append(mine, snap)
+		}
+	}
+	if len(mine) == 0 {
+		return 0
+	}
+
+	var total uint64
+	for _, e := range ds.extents {
+		if e.shared || ds.referencedBy(e, mine) {
+			continue
+		}
+		total += e.size
+	}
+	return total
+}
+
+// written returns the data born after the previous snapshot and at or
+// before txg.
+func (ds *simDataset) written(txg uint64) uint64 {
+	var prev uint64
+	for _, snap := range ds.snapshots {
+		if snap.txg < txg && snap.txg > prev {
+			prev = snap.txg
+		}
+	}
+	var total uint64
+	for _, e := range ds.extents {
+		if !e.shared && e.born > prev && e.born <= txg {
+			total += e.size
+		}
+	}
+	return total
+}
+
+// live reports whether the extent existed at txg.
+func (e *simExtent) live(txg uint64) bool {
+	return e.born <= txg && (e.freed == 0 || e.freed > txg)
+}
+
+func (snap *simSnapshot) fullName() string {
+	return snap.dataset.name + "@" + snap.name
+}
+
+func (snap *simSnapshot) busy() bool {
+	return len(snap.holds) > 0 || len(snap.clones) > 0
+}
+
+func (snap *simSnapshot) model() *model.Snapshot {
+	ds := snap.dataset
+	used := ds.reclaimable([]*simSnapshot{snap})
+	referenced := ds.referenced(snap.txg)
+	var clones []string
+	if len(snap.clones) > 0 {
+		clones = append(clones, snap.clones...)
+	}
+	return &model.Snapshot{
+		Name:              snap.fullName(),
+		Dataset:           ds.name,
+		Creation:          snap.creation,
+		Used:              used,
+		Referenced:        referenced,
+		Clones:            clones,
+		DeferDestroy:      snap.deferDestroy,
+		LogicalUsed:       used,
+		LogicalReferenced: referenced,
+		GUID:              snap.guid,
+		UserRefs:          uint64(len(snap.holds)),
+		Written:           ds.written(snap.txg),
+		Type:              "snapshot",
+	}
+}
+
+func containsSnapshot(snapshots []*simSnapshot, snap *simSnapshot) bool {
+	for _, s := range snapshots {
+		if s == snap {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(values []string, value string) []string {
+	for i, v := range values {
+		if v == value {
+			return append(values[:i], values[i+1:]...)
+		}
+	}
+	return values
+}
+
+func validateProperties(props map[string]string) error {
+	for k := range props {
+		if strings.TrimSpace(k) == "" || strings.ContainsAny(k, "= \t") {
+			return zfs.NewArgumentError("invalid property name: %q", k)
+		}
+	}
+	return nil
+}
+
+func copyProperties(props map[string]string) map[string]string {
+	copied := make(map[string]string, len(props))
+	for k, v := range props {
+		copied[k] = v
+	}
+	return copied
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// zfsError returns the error the zfs package reports for a zfs command that
+// failed with message on stderr.
+func zfsError(format string, args ...any) error {
+	return zfs.NewError(nil, fmt.Sprintf(format, args...)+"\n", 1)
+}
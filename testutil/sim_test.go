@@ -0,0 +1,500 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+	"github.com/jsirianni/zfssnap/zfs"
+)
+
+func newTestSimulator(t *testing.T, datasets ...string) *Simulator {
+	t.Helper()
+	sim := NewSimulator(NewClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+	for _, ds := range datasets {
+		if err := sim.CreateDataset(ds); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	return sim
+}
+
+func TestSimulatorCreateAndList(t *testing.T) {
+	ctx := context.Background()
+	sim := newTestSimulator(t, "pool/data/child", "pool/other")
+
+	if err := sim.Create(ctx, "pool/data", "first", zfs.CreateOptions{Recursive: true}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sim.Clock().Advance(time.Hour)
+	if err := sim.Create(ctx, "pool/other", "second", zfs.CreateOptions{Properties: map[string]string{"com.example:reason": "test"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     zfs.ListOptions
+		expected []string
+	}{
+		{
+			name:     "all",
+			expected: []string{"pool/data@first", "pool/data/child@first", "pool/other@second"},
+		},
+		{
+			name:     "dataset only",
+			opts:     zfs.ListOptions{Dataset: "pool/data"},
+			expected: []string{"pool/data@first"},
+		},
+		{
+			name:     "recursive",
+			opts:     zfs.ListOptions{Dataset: "pool/data", Recursive: true},
+			expected: []string{"pool/data@first", "pool/data/child@first"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, err := sim.List(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+
+	info, err := sim.Get(ctx, "pool/other@second")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !info.Creation.Equal(time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected creation from clock, got %v", info.Creation)
+	}
+	if value, ok := sim.Property("pool/other@second", "com.example:reason"); !ok || value != "test" {
+		t.Errorf("Expected property to be set, got %q", value)
+	}
+
+	if _, err := sim.List(ctx, zfs.ListOptions{Dataset: "pool/missing"}); err == nil || !strings.Contains(err.Error(), "dataset does not exist") {
+		t.Errorf("Expected missing dataset error, got %v", err)
+	}
+}
+
+func TestSimulatorRecursiveCreateIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	sim := newTestSimulator(t, "pool/data/child")
+
+	if err := sim.Create(ctx, "pool/data/child", "snap", zfs.CreateOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err := sim.Create(ctx, "pool/data", "snap", zfs.CreateOptions{Recursive: true})
+	if err == nil || !strings.Contains(err.Error(), "cannot create snapshot 'pool/data/child@snap': dataset already exists") {
+		t.Fatalf("Expected already exists error, got %v", err)
+	}
+	if sim.Exists("pool/data@snap") {
+		t.Error("Expected no snapshot of the parent after a failed recursive create")
+	}
+}
+
+func TestSimulatorSpaceAccounting(t *testing.T) {
+	ctx := context.Background()
+	sim := newTestSimulator(t, "pool/data")
+
+	mustSim(t, sim.Write("pool/data", 1000))
+	mustSim(t, sim.Create(ctx, "pool/data", "a", zfs.CreateOptions{}))
+	mustSim(t, sim.Write("pool/data", 200))
+	mustSim(t, sim.Create(ctx, "pool/data", "b", zfs.CreateOptions{}))
+	mustSim(t, sim.Free("pool/data", 1100))
+
+	a, err := sim.Get(ctx, "pool/data@a")
+	mustSim(t, err)
+	b, err := sim.Get(ctx, "pool/data@b")
+	mustSim(t, err)
+
+	if a.Referenced != 1000 || a.Written != 1000 || a.Used != 0 {
+		t.Errorf("Unexpected accounting for a: referenced=%d written=%d used=%d", a.Referenced, a.Written, a.Used)
+	}
+	if b.Referenced != 1200 || b.Written != 200 || b.Used != 100 {
+		t.Errorf("Unexpected accounting for b: referenced=%d written=%d used=%d", b.Referenced, b.Written, b.Used)
+	}
+
+	// Destroying both reclaims the data shared between them as well.
+	result, err := sim.Delete(ctx, "pool/data@a", zfs.DeleteOptions{DryRun: true})
+	mustSim(t, err)
+	if result.Reclaimed != 0 || !sim.Exists("pool/data@a") {
+		t.Errorf("Unexpected dry run result: %+v", result)
+	}
+	result, err = sim.Delete(ctx, "pool/data@a", zfs.DeleteOptions{})
+	mustSim(t, err)
+	if result.Reclaimed != 0 {
+		t.Errorf("Expected nothing reclaimed for a, got %d", result.Reclaimed)
+	}
+	result, err = sim.Delete(ctx, "pool/data@b", zfs.DeleteOptions{})
+	mustSim(t, err)
+	if result.Reclaimed != 1100 {
+		t.Errorf("Expected 1100 bytes reclaimed for b, got %d", result.Reclaimed)
+	}
+}
+
+func TestSimulatorHolds(t *testing.T) {
+	ctx := context.Background()
+	sim := newTestSimulator(t, "pool/data")
+	mustSim(t, sim.Create(ctx, "pool/data", "snap", zfs.CreateOptions{}))
+	mustSim(t, sim.Hold(ctx, "pool/data@snap", "keep", zfs.HoldOptions{}))
+
+	if err := sim.Hold(ctx, "pool/data@snap", "keep", zfs.HoldOptions{}); err == nil {
+		t.Error("Expected duplicate tag error")
+	}
+	if _, err := sim.Delete(ctx, "pool/data@snap", zfs.DeleteOptions{}); err == nil || !strings.Contains(err.Error(), "dataset is busy") {
+		t.Fatalf("Expected busy error, got %v", err)
+	}
+
+	info, err := sim.Get(ctx, "pool/data@snap")
+	mustSim(t, err)
+	if info.UserRefs != 1 {
+		t.Errorf("Expected 1 user ref, got %d", info.UserRefs)
+	}
+
+	if _, err := sim.Delete(ctx, "pool/data@snap", zfs.DeleteOptions{Defer: true}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info, err = sim.Get(ctx, "pool/data@snap")
+	mustSim(t, err)
+	if !info.DeferDestroy {
+		t.Error("Expected snapshot to be marked for deferred destroy")
+	}
+
+	mustSim(t, sim.Release(ctx, "pool/data@snap", "keep", zfs.HoldOptions{}))
+	if sim.Exists("pool/data@snap") {
+		t.Error("Expected snapshot to be destroyed when the last hold was released")
+	}
+}
+
+func TestSimulatorClones(t *testing.T) {
+	ctx := context.Background()
+	sim := newTestSimulator(t, "pool/data")
+	mustSim(t, sim.Write("pool/data", 500))
+	mustSim(t, sim.Create(ctx, "pool/data", "snap", zfs.CreateOptions{}))
+	mustSim(t, sim.Clone(ctx, "pool/data@snap", "pool/clone", zfs.CloneOptions{}))
+
+	if err := sim.Clone(ctx, "pool/data@snap", "pool/clone", zfs.CloneOptions{}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected already exists error, got %v", err)
+	}
+	if err := sim.Clone(ctx, "pool/data@snap", "missing/clone", zfs.CloneOptions{}); err == nil || !strings.Contains(err.Error(), "parent does not exist") {
+		t.Errorf("Expected parent error, got %v", err)
+	}
+
+	info, err := sim.Get(ctx, "pool/data@snap")
+	mustSim(t, err)
+	if len(info.Clones) != 1 || info.Clones[0] != "pool/clone" {
+		t.Errorf("Expected clone to be recorded, got %v", info.Clones)
+	}
+	if _, err := sim.Delete(ctx, "pool/data@snap", zfs.DeleteOptions{}); err == nil || !strings.Contains(err.Error(), "dependent clones") {
+		t.Errorf("Expected dependent clones error, got %v", err)
+	}
+
+	mustSim(t, sim.Create(ctx, "pool/clone", "snap", zfs.CreateOptions{}))
+	cloneSnap, err := sim.Get(ctx, "pool/clone@snap")
+	mustSim(t, err)
+	if cloneSnap.Referenced != 500 || cloneSnap.Written != 0 {
+		t.Errorf("Expected clone to share origin data, got referenced=%d written=%d", cloneSnap.Referenced, cloneSnap.Written)
+	}
+}
+
+func TestSimulatorDeleteRecursive(t *testing.T) {
+	ctx := context.Background()
+	sim := newTestSimulator(t, "pool/data/a", "pool/data/b")
+	mustSim(t, sim.Create(ctx, "pool/data", "snap", zfs.CreateOptions{Recursive: true}))
+	mustSim(t, sim.Hold(ctx, "pool/data/b@snap", "keep", zfs.HoldOptions{}))
+
+	if _, err := sim.Delete(ctx, "pool/data@snap", zfs.DeleteOptions{Recursive: true}); err == nil {
+		t.Fatal("Expected error for held child")
+	}
+	if !sim.Exists("pool/data/a@snap") {
+		t.Error("Expected a failed recursive destroy to destroy nothing")
+	}
+
+	mustSim(t, sim.Release(ctx, "pool/data/b@snap", "keep", zfs.HoldOptions{}))
+	result, err := sim.Delete(ctx, "pool/data@snap", zfs.DeleteOptions{Recursive: true})
+	mustSim(t, err)
+	if len(result.Destroyed) != 3 {
+		t.Errorf("Expected 3 destroyed snapshots, got %v", result.Destroyed)
+	}
+
+	if _, err := sim.Delete(ctx, "pool/data@snap", zfs.DeleteOptions{}); err == nil || !strings.Contains(err.Error(), "could not find any snapshots") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+func mustSim(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestSimulatorRollback(t *testing.T) {
+	ctx := context.Background()
+	sim := newTestSimulator(t, "pool/data")
+	mustSim(t, sim.Write("pool/data", 1000))
+	mustSim(t, sim.Create(ctx, "pool/data", "a", zfs.CreateOptions{}))
+	mustSim(t, sim.Write("pool/data", 300))
+	mustSim(t, sim.Free("pool/data", 500))
+	mustSim(t, sim.Create(ctx, "pool/data", "b", zfs.CreateOptions{}))
+	mustSim(t, sim.Clone(ctx, "pool/data@b", "pool/clone", zfs.CloneOptions{}))
+	mustSim(t, sim.Write("pool/data", 50))
+
+	if err := sim.Rollback(ctx, "pool/data@a", zfs.RollbackOptions{}); err == nil || !strings.Contains(err.Error(), "more recent snapshots") {
+		t.Fatalf("Expected more recent snapshots error, got %v", err)
+	}
+	if err := sim.Rollback(ctx, "pool/data@a", zfs.RollbackOptions{DestroyNewer: true}); err == nil || !strings.Contains(err.Error(), "clones of previous snapshots") {
+		t.Fatalf("Expected clones error, got %v", err)
+	}
+	mustSim(t, sim.Rollback(ctx, "pool/data@a", zfs.RollbackOptions{DestroyClones: true}))
+
+	if sim.Exists("pool/data@b") || sim.Exists("pool/clone") {
+		t.Error("Expected newer snapshot and its clone to be destroyed")
+	}
+
+	// The rolled back dataset references exactly what the snapshot did.
+	mustSim(t, sim.Create(ctx, "pool/data", "c", zfs.CreateOptions{}))
+	c, err := sim.Get(ctx, "pool/data@c")
+	mustSim(t, err)
+	if c.Referenced != 1000 || c.Written != 0 {
+		t.Errorf("Expected rolled back state, got referenced=%d written=%d", c.Referenced, c.Written)
+	}
+}
+
+func TestSimulatorCopy(t *testing.T) {
+	ctx := context.Background()
+	sim := newTestSimulator(t, "pool/data", "backup")
+	mustSim(t, sim.Write("pool/data", 700))
+	mustSim(t, sim.Create(ctx, "pool/data", "snap", zfs.CreateOptions{}))
+	mustSim(t, sim.Copy(ctx, "pool/data@snap", "backup/data"))
+
+	if err := sim.Copy(ctx, "pool/data@snap", "backup/data"); err == nil || !strings.Contains(err.Error(), "exists") {
+		t.Errorf("Expected destination exists error, got %v", err)
+	}
+
+	src, err := sim.Get(ctx, "pool/data@snap")
+	mustSim(t, err)
+	dst, err := sim.Get(ctx, "backup/data@snap")
+	mustSim(t, err)
+	if dst.GUID != src.GUID || !dst.Creation.Equal(src.Creation) || dst.Referenced != 700 {
+		t.Errorf("Expected received snapshot to match source, got %+v", dst)
+	}
+}
+
+func TestSimulatorPromote(t *testing.T) {
+	ctx := context.Background()
+	sim := newTestSimulator(t, "pool/db")
+	mustSim(t, sim.Write("pool/db", 1000))
+	mustSim(t, sim.Create(ctx, "pool/db", "a", zfs.CreateOptions{}))
+	mustSim(t, sim.Write("pool/db", 100))
+	mustSim(t, sim.Create(ctx, "pool/db", "b", zfs.CreateOptions{}))
+	mustSim(t, sim.Write("pool/db", 10))
+	mustSim(t, sim.Create(ctx, "pool/db", "c", zfs.CreateOptions{}))
+	mustSim(t, sim.Clone(ctx, "pool/db@b", "pool/test/db", zfs.CloneOptions{
+		CreateParents: true,
+		Properties:    map[string]string{"mountpoint": "/srv/test"},
+	}))
+	mustSim(t, sim.Write("pool/test/db", 50))
+	mustSim(t, sim.Create(ctx, "pool/test/db", "d", zfs.CreateOptions{}))
+
+	if value, ok := sim.Property("pool/test/db", "mountpoint"); !ok || value != "/srv/test" {
+		t.Errorf("Expected clone property, got %q", value)
+	}
+	if err := sim.Promote(ctx, "pool/db"); err == nil || !strings.Contains(err.Error(), "not a cloned filesystem") {
+		t.Errorf("Expected not a clone error, got %v", err)
+	}
+
+	mustSim(t, sim.Promote(ctx, "pool/test/db"))
+
+	names, err := sim.List(ctx, zfs.ListOptions{})
+	mustSim(t, err)
+	expected := "pool/db@c,pool/test/db@a,pool/test/db@b,pool/test/db@d"
+	if strings.Join(names, ",") != expected {
+		t.Errorf("Expected %s, got %v", expected, names)
+	}
+
+	b, err := sim.Get(ctx, "pool/test/db@b")
+	mustSim(t, err)
+	if len(b.Clones) != 1 || b.Clones[0] != "pool/db" || b.Referenced != 1100 {
+		t.Errorf("Expected former origin to be a clone of the moved snapshot, got %+v", b)
+	}
+	d, err := sim.Get(ctx, "pool/test/db@d")
+	mustSim(t, err)
+	if d.Referenced != 1150 || d.Written != 50 {
+		t.Errorf("Unexpected accounting for d: referenced=%d written=%d", d.Referenced, d.Written)
+	}
+	c, err := sim.Get(ctx, "pool/db@c")
+	mustSim(t, err)
+	if c.Referenced != 1110 || c.Written != 10 {
+		t.Errorf("Unexpected accounting for c: referenced=%d written=%d", c.Referenced, c.Written)
+	}
+
+	// The former origin can now be destroyed without losing the clone.
+	if _, err := sim.Delete(ctx, "pool/test/db@b", zfs.DeleteOptions{}); err == nil || !strings.Contains(err.Error(), "dependent clones") {
+		t.Errorf("Expected dependent clones error, got %v", err)
+	}
+}
+
+func TestSimulatorBookmarks(t *testing.T) {
+	ctx := context.Background()
+	sim := newTestSimulator(t, "pool/data")
+	mustSim(t, sim.Create(ctx, "pool/data", "a", zfs.CreateOptions{}))
+	mustSim(t, sim.Create(ctx, "pool/data", "b", zfs.CreateOptions{}))
+	mustSim(t, sim.Bookmark(ctx, "pool/data@a", "pool/data#a"))
+	mustSim(t, sim.Bookmark(ctx, "pool/data@b", "pool/data#b"))
+
+	if err := sim.Bookmark(ctx, "pool/data@a", "pool/data#a"); err == nil || !strings.Contains(err.Error(), "bookmark exists") {
+		t.Errorf("Expected bookmark exists error, got %v", err)
+	}
+	if err := sim.Bookmark(ctx, "pool/data@a", "pool#a"); err == nil {
+		t.Error("Expected error for bookmark in another dataset")
+	}
+
+	// The bookmark outlives its snapshot and keeps its guid.
+	a, err := sim.Get(ctx, "pool/data@a")
+	mustSim(t, err)
+	_, err = sim.Delete(ctx, "pool/data@a", zfs.DeleteOptions{})
+	mustSim(t, err)
+	bookmarks, err := sim.Bookmarks(ctx, zfs.ListOptions{Dataset: "pool/data"})
+	mustSim(t, err)
+	if len(bookmarks) != 2 || bookmarks[0].Name != "pool/data#a" || bookmarks[0].GUID != a.GUID {
+		t.Fatalf("Unexpected bookmarks: %+v", bookmarks)
+	}
+
+	// Rolling back destroys newer bookmarks along with newer snapshots.
+	mustSim(t, sim.Create(ctx, "pool/data", "c", zfs.CreateOptions{}))
+	mustSim(t, sim.Bookmark(ctx, "pool/data@c", "pool/data#c"))
+	_, err = sim.Delete(ctx, "pool/data@c", zfs.DeleteOptions{})
+	mustSim(t, err)
+	if err := sim.Rollback(ctx, "pool/data@b", zfs.RollbackOptions{}); err == nil {
+		t.Error("Expected rollback past a bookmark to require DestroyNewer")
+	}
+	mustSim(t, sim.Rollback(ctx, "pool/data@b", zfs.RollbackOptions{DestroyNewer: true}))
+
+	mustSim(t, sim.DeleteBookmark(ctx, "pool/data#a"))
+	if err := sim.DeleteBookmark(ctx, "pool/data#a"); err == nil {
+		t.Error("Expected error deleting a missing bookmark")
+	}
+	bookmarks, err = sim.Bookmarks(ctx, zfs.ListOptions{})
+	mustSim(t, err)
+	if len(bookmarks) != 1 || bookmarks[0].Name != "pool/data#b" {
+		t.Errorf("Unexpected bookmarks: %+v", bookmarks)
+	}
+}
+
+func TestSimulatorDatasets(t *testing.T) {
+	ctx := context.Background()
+	sim := newTestSimulator(t, "pool/data/child")
+	mustSim(t, sim.Write("pool/data", 1000))
+	mustSim(t, sim.Write("pool/data/child", 500))
+	mustSim(t, sim.Create(ctx, "pool/data", "a", zfs.CreateOptions{}))
+	mustSim(t, sim.Free("pool/data", 200))
+	mustSim(t, sim.Clone(ctx, "pool/data@a", "pool/clone", zfs.CloneOptions{
+		Properties: map[string]string{"mountpoint": "/srv/clone", "com.example:owner": "qa"},
+	}))
+
+	d := sim.Datasets()
+	all, err := d.List(ctx, "")
+	mustSim(t, err)
+	var names []string
+	for _, ds := range all {
+		names = append(names, ds.Name)
+	}
+	if strings.Join(names, ",") != "pool,pool/clone,pool/data,pool/data/child" {
+		t.Errorf("Unexpected datasets: %v", names)
+	}
+
+	data, err := d.Get(ctx, "pool/data")
+	mustSim(t, err)
+	// The freed 200 bytes are still held by the snapshot.
+	if data.Used != 1500 || data.Referenced != 800 || data.Pool != "pool" || data.Mountpoint != "/pool/data" {
+		t.Errorf("Unexpected dataset: %+v", data)
+	}
+
+	clone, err := d.Get(ctx, "pool/clone")
+	mustSim(t, err)
+	if clone.Origin != "pool/data@a" || clone.Mountpoint != "/srv/clone" || clone.Properties["com.example:owner"] != "qa" || clone.Used != 0 {
+		t.Errorf("Unexpected clone: %+v", clone)
+	}
+
+	children, err := d.Children(ctx, "pool")
+	mustSim(t, err)
+	if len(children) != 2 || children[0].Name != "pool/clone" || children[1].Name != "pool/data" {
+		t.Errorf("Unexpected children: %+v", children)
+	}
+	if _, err := d.Get(ctx, "pool/missing"); err == nil {
+		t.Error("Expected error for missing dataset")
+	}
+}
+
+func TestSimulatorDiff(t *testing.T) {
+	ctx := context.Background()
+	sim := newTestSimulator(t, "pool/data", "pool/other")
+	added := &model.Change{Type: model.ChangeAdded, FileType: model.FileTypeFile, Path: "/pool/data/a"}
+	modified := &model.Change{Type: model.ChangeModified, FileType: model.FileTypeFile, Path: "/pool/data/a"}
+	removed := &model.Change{Type: model.ChangeRemoved, FileType: model.FileTypeFile, Path: "/pool/data/b"}
+
+	mustSim(t, sim.Create(ctx, "pool/data", "a", zfs.CreateOptions{}))
+	mustSim(t, sim.Change("pool/data", added))
+	mustSim(t, sim.Create(ctx, "pool/data", "b", zfs.CreateOptions{}))
+	sim.Clock().Advance(time.Hour)
+	mustSim(t, sim.Change("pool/data", modified, removed))
+	mustSim(t, sim.Create(ctx, "pool/other", "a", zfs.CreateOptions{}))
+
+	tests := []struct {
+		name     string
+		from, to string
+		expected []model.ChangeType
+	}{
+		{name: "between snapshots", from: "pool/data@a", to: "pool/data@b", expected: []model.ChangeType{model.ChangeAdded}},
+		{name: "live dataset", from: "pool/data@a", expected: []model.ChangeType{model.ChangeAdded, model.ChangeModified, model.ChangeRemoved}},
+		{name: "named dataset", from: "pool/data@b", to: "pool/data", expected: []model.ChangeType{model.ChangeModified, model.ChangeRemoved}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := sim.Diff(ctx, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var types []model.ChangeType
+			for _, c := range changes {
+				types = append(types, c.Type)
+			}
+			if fmt.Sprint(types) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, types)
+			}
+		})
+	}
+
+	changes, err := sim.Diff(ctx, "pool/data@b", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !changes[0].Time.Equal(time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the change to be stamped with the clock, got %v", changes[0].Time)
+	}
+
+	for _, args := range [][2]string{
+		{"pool/data@b", "pool/data@a"},
+		{"pool/data@a", "pool/other@a"},
+		{"pool/data@a", "pool/other"},
+		{"pool/data@missing", ""},
+	} {
+		if _, err := sim.Diff(ctx, args[0], args[1]); err == nil {
+			t.Errorf("Diff(%s, %s): expected error", args[0], args[1])
+		}
+	}
+
+	// Rolling back discards the changes made after the snapshot.
+	mustSim(t, sim.Rollback(ctx, "pool/data@b", zfs.RollbackOptions{}))
+	if changes, err := sim.Diff(ctx, "pool/data@b", ""); err != nil || len(changes) != 0 {
+		t.Errorf("Expected no changes after rollback, got %v, %v", changes, err)
+	}
+}
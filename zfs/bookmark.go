@@ -0,0 +1,96 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+)
+
+// bookmarkProperties are the properties queried to populate model.Bookmark.
+var bookmarkProperties = []string{"name", "creation", "guid", "createtxg"}
+
+// Compile-time check that Snapshot implements Bookmarker.
+var _ Bookmarker = (*Snapshot)(nil)
+
+// Bookmark creates the bookmark of snapshot using `zfs bookmark`. The
+// bookmark must be in the dataset of the snapshot.
+func (c *Snapshot) Bookmark(ctx context.Context, snapshot, bookmark string) error {
+	snapshot = strings.TrimSpace(snapshot)
+	bookmark = strings.TrimSpace(bookmark)
+	if !IsValidSnapshotName(snapshot) {
+		return NewArgumentError("invalid snapshot name format: %s (must contain @)", snapshot)
+	}
+	if !IsValidBookmarkName(bookmark) {
+		return NewArgumentError("invalid bookmark name format: %s (must contain #)", bookmark)
+	}
+	dataset, _, _ := strings.Cut(snapshot, "@")
+	if bookmarkDataset, _, _ := strings.Cut(bookmark, "#"); bookmarkDataset != dataset {
+		return fmt.Errorf("bookmark %s must be in the dataset of snapshot %s", bookmark, snapshot)
+	}
+
+	if _, err := c.run(ctx, "bookmark", snapshot, bookmark); err != nil {
+		return fmt.Errorf("zfs bookmark %s failed: %w", bookmark, err)
+	}
+	return nil
+}
+
+// Bookmarks returns bookmarks with their properties using `zfs list`.
+func (c *Snapshot) Bookmarks(ctx context.Context, opts ListOptions) ([]*model.Bookmark, error) {
+	scope, err := opts.args()
+	if err != nil {
+		return nil, err
+	}
+	args := append([]string{"list", "-H", "-p", "-t", "bookmark", "-o", strings.Join(bookmarkProperties, ",")}, scope...)
+
+	out, err := c.run(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("zfs list failed: %w", err)
+	}
+	return parseBookmarks(out)
+}
+
+// DeleteBookmark destroys a bookmark using `zfs destroy`.
+func (c *Snapshot) DeleteBookmark(ctx context.Context, name string) error {
+	name = strings.TrimSpace(name)
+	if !IsValidBookmarkName(name) {
+		return NewArgumentError("invalid bookmark name format: %s (must contain #)", name)
+	}
+
+	if _, err := c.run(ctx, "destroy", name); err != nil {
+		return fmt.Errorf("zfs destroy %s failed: %w", name, err)
+	}
+	return nil
+}
+
+// parseBookmarks parses `zfs list -H -p` output whose columns are
+// bookmarkProperties, in order.
+func parseBookmarks(out string) ([]*model.Bookmark, error) {
+	bookmarks := []*model.Bookmark{}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != len(bookmarkProperties) {
+			return nil, fmt.Errorf("unexpected zfs list output: expected %d columns, got %d: %q", len(bookmarkProperties), len(fields), line)
+		}
+
+		dataset, _, _ := strings.Cut(fields[0], "#")
+		b := &model.Bookmark{Name: fields[0], Dataset: dataset}
+		if v, err := parseUint(fields[1]); err == nil && v <= math.MaxInt64 {
+			b.Creation = time.Unix(int64(v), 0).UTC()
+		}
+		if v, err := parseUint(fields[2]); err == nil {
+			b.GUID = v
+		}
+		if v, err := parseUint(fields[3]); err == nil {
+			b.CreateTXG = v
+		}
+		bookmarks = append(bookmarks, b)
+	}
+	return bookmarks, nil
+}
@@ -0,0 +1,63 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CloneOptions controls how a clone is created.
+type CloneOptions struct {
+	// Properties are set on the clone at creation time (-o property=value),
+	// overriding the values inherited from the origin.
+	Properties map[string]string
+
+	// CreateParents creates missing parent datasets of the clone (-p).
+	CreateParents bool
+}
+
+// Compile-time check that Snapshot implements Cloner.
+var _ Cloner = (*Snapshot)(nil)
+
+// Clone creates the dataset target from a snapshot using `zfs clone`.
+func (c *Snapshot) Clone(ctx context.Context, snapshot, target string, opts CloneOptions) error {
+	snapshot = strings.TrimSpace(snapshot)
+	target = strings.TrimSpace(target)
+	if !IsValidSnapshotName(snapshot) {
+		return NewArgumentError("invalid snapshot name format: %s (must contain @)", snapshot)
+	}
+	if !IsValidDatasetName(target) {
+		return NewArgumentError("invalid dataset name format: %s", target)
+	}
+
+	args := []string{"clone"}
+	if opts.CreateParents {
+		args = append(args, "-p")
+	}
+	propArgs, err := propertyArgs(opts.Properties)
+	if err != nil {
+		return err
+	}
+	args = append(args, propArgs...)
+	args = append(args, snapshot, target)
+
+	if _, err := c.run(ctx, args...); err != nil {
+		return fmt.Errorf("zfs clone %s %s failed: %w", snapshot, target, err)
+	}
+	return nil
+}
+
+// Promote makes a clone independent of its origin using `zfs promote`. The
+// origin snapshot and the snapshots before it move to the clone, and the
+// former origin dataset becomes a clone of it.
+func (c *Snapshot) Promote(ctx context.Context, dataset string) error {
+	dataset = strings.TrimSpace(dataset)
+	if !IsValidDatasetName(dataset) {
+		return NewArgumentError("invalid dataset name format: %s", dataset)
+	}
+
+	if _, err := c.run(ctx, "promote", dataset); err != nil {
+		return fmt.Errorf("zfs promote %s failed: %w", dataset, err)
+	}
+	return nil
+}
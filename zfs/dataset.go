@@ -0,0 +1,159 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+)
+
+// datasetTypes are the dataset types returned by Datasets.
+const datasetTypes = "filesystem,volume"
+
+// datasets implements Datasets with the zfs CLI of a Snapshot.
+type datasets struct {
+	c *Snapshot
+}
+
+// Compile-time check that datasets implements Datasets.
+var _ Datasets = datasets{}
+
+// Datasets returns a Datasets that runs zfs with the binary, timeout and
+// Runner of c.
+func (c *Snapshot) Datasets() Datasets {
+	return datasets{c: c}
+}
+
+// List implements Datasets.
+func (d datasets) List(ctx context.Context, root string) ([]*model.Dataset, error) {
+	root = strings.TrimSpace(root)
+	if root == "" {
+		return d.get(ctx)
+	}
+	if !IsValidDatasetName(root) {
+		return nil, NewArgumentError("invalid dataset name format: %s", root)
+	}
+	return d.get(ctx, "-r", root)
+}
+
+// Get implements Datasets.
+func (d datasets) Get(ctx context.Context, name string) (*model.Dataset, error) {
+	name = strings.TrimSpace(name)
+	if !IsValidDatasetName(name) {
+		return nil, NewArgumentError("invalid dataset name format: %s", name)
+	}
+
+	found, err := d.get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrDatasetNotFound, name)
+	}
+	return found[0], nil
+}
+
+// Children implements Datasets.
+func (d datasets) Children(ctx context.Context, name string) ([]*model.Dataset, error) {
+	name = strings.TrimSpace(name)
+	if !IsValidDatasetName(name) {
+		return nil, NewArgumentError("invalid dataset name format: %s", name)
+	}
+
+	found, err := d.get(ctx, "-d", "1", name)
+	if err != nil {
+		return nil, err
+	}
+	children := make([]*model.Dataset, 0, len(found))
+	for _, ds := range found {
+		if ds.Name != name {
+			children = append(children, ds)
+		}
+	}
+	return children, nil
+}
+
+// get queries every property of the datasets selected by scope in a single
+// `zfs get` invocation. User properties cannot be listed by `zfs list`
+// without knowing their names.
+func (d datasets) get(ctx context.Context, scope ...string) ([]*model.Dataset, error) {
+	args := append([]string{"get", "-H", "-p", "-o", "name,property,value", "-t", datasetTypes, "all"}, scope...)
+
+	out, err := d.c.run(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("zfs get failed: %w", err)
+	}
+	return parseDatasets(out)
+}
+
+// parseDatasets parses `zfs get -H -p -o name,property,value` output into
+// datasets in the order zfs reports them.
+func parseDatasets(out string) ([]*model.Dataset, error) {
+	found := []*model.Dataset{}
+	var current *model.Dataset
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected zfs get output: %q", line)
+		}
+
+		if current == nil || current.Name != fields[0] {
+			pool, _, _ := strings.Cut(fields[0], "/")
+			current = &model.Dataset{Name: fields[0], Pool: pool}
+			found = append(found, current)
+		}
+		setDatasetProperty(current, fields[1], fields[2])
+	}
+	return found, nil
+}
+
+// setDatasetProperty sets the model.Dataset field for a parsable (-p)
+// property value. Native properties without a field are ignored.
+func setDatasetProperty(ds *model.Dataset, prop, val string) {
+	if strings.Contains(prop, ":") {
+		if ds.Properties == nil {
+			ds.Properties = make(map[string]string)
+		}
+		ds.Properties[prop] = val
+		return
+	}
+
+	switch prop {
+	case "type":
+		ds.Type = val
+	case "creation":
+		if v, err := parseUint(val); err == nil && v <= math.MaxInt64 {
+			ds.Creation = time.Unix(int64(v), 0).UTC()
+		}
+	case "used":
+		if v, err := parseUint(val); err == nil {
+			ds.Used = v
+		}
+	case "available":
+		if v, err := parseUint(val); err == nil {
+			ds.Available = v
+		}
+	case "referenced":
+		if v, err := parseUint(val); err == nil {
+			ds.Referenced = v
+		}
+	case "mountpoint":
+		if val != "-" {
+			ds.Mountpoint = val
+		}
+	case "quota":
+		if v, err := parseUint(val); err == nil {
+			ds.Quota = v
+		}
+	case "origin":
+		if val != "-" {
+			ds.Origin = val
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+)
+
+// diffChangeTypes maps the change column of `zfs diff` to model.ChangeType.
+var diffChangeTypes = map[string]model.ChangeType{
+	"+": model.ChangeAdded,
+	"-": model.ChangeRemoved,
+	"M": model.ChangeModified,
+	"R": model.ChangeRenamed,
+}
+
+// diffFileTypes maps the file type column of `zfs diff -F` to model.FileType.
+var diffFileTypes = map[string]model.FileType{
+	"F": model.FileTypeFile,
+	"/": model.FileTypeDirectory,
+	"@": model.FileTypeSymlink,
+	"B": model.FileTypeBlockDevice,
+	"C": model.FileTypeCharacterDevice,
+	"|": model.FileTypeFIFO,
+	"=": model.FileTypeSocket,
+	">": model.FileTypeDoor,
+	"P": model.FileTypeEventPort,
+}
+
+// Compile-time check that Snapshot implements Differ.
+var _ Differ = (*Snapshot)(nil)
+
+// Diff returns the changes between snapshot from and the later snapshot or
+// dataset to using `zfs diff -H -F -t`. When to is empty, from is compared
+// to its live dataset.
+func (c *Snapshot) Diff(ctx context.Context, from, to string) ([]*model.Change, error) {
+	from = strings.TrimSpace(from)
+	to = strings.TrimSpace(to)
+	if !IsValidSnapshotName(from) {
+		return nil, NewArgumentError("invalid snapshot name format: %s (must contain @)", from)
+	}
+	args := []string{"diff", "-H", "-F", "-t", from}
+	if to != "" {
+		if !IsValidSnapshotName(to) && !IsValidDatasetName(to) {
+			return nil, NewArgumentError("invalid snapshot or dataset name format: %s", to)
+		}
+		args = append(args, to)
+	}
+
+	out, err := c.run(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("zfs diff %s failed: %w", from, err)
+	}
+	return parseDiff(out)
+}
+
+// parseDiff parses `zfs diff -H -F -t` output. Each line holds the change
+// time, change, file type and path, and for renames the new path.
+func parseDiff(out string) ([]*model.Change, error) {
+	changes := []*model.Change{}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 && len(fields) != 5 {
+			return nil, fmt.Errorf("unexpected zfs diff output: %q", line)
+		}
+
+		changeType, ok := diffChangeTypes[fields[1]]
+		if !ok {
+			return nil, fmt.Errorf("unexpected zfs diff change %q: %q", fields[1], line)
+		}
+		if (changeType == model.ChangeRenamed) != (len(fields) == 5) {
+			return nil, fmt.Errorf("unexpected zfs diff output: %q", line)
+		}
+		fileType, ok := diffFileTypes[fields[2]]
+		if !ok {
+			return nil, fmt.Errorf("unexpected zfs diff file type %q: %q", fields[2], line)
+		}
+		ts, err := parseDiffTime(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected zfs diff time %q: %w", fields[0], err)
+		}
+
+		change := &model.Change{
+			Time:     ts,
+			Type:     changeType,
+			FileType: fileType,
+			Path:     unescapeDiffPath(fields[3]),
+		}
+		if len(fields) == 5 {
+			change.NewPath = unescapeDiffPath(fields[4])
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// parseDiffTime parses a `zfs diff -t` timestamp: seconds since the epoch
+// with a fraction of up to nine digits.
+func parseDiffTime(s string) (time.Time, error) {
+	secStr, nsecStr, _ := strings.Cut(strings.TrimSpace(s), ".")
+	sec, err := parseUint(secStr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if sec > math.MaxInt64 {
+		return time.Time{}, fmt.Errorf("timestamp out of range")
+	}
+	var nsec uint64
+	if nsecStr != "" {
+		if len(nsecStr) > 9 {
+			return time.Time{}, fmt.Errorf("fraction out of range")
+		}
+		if nsec, err = parseUint(nsecStr + strings.Repeat("0", 9-len(nsecStr))); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Unix(int64(sec), int64(nsec)).UTC(), nil
+}
+
+// unescapeDiffPath decodes the escapes zfs diff writes for bytes that are
+// not printable ASCII, spaces and backslashes: a backslash followed by four
+// octal digits, e.g. "\0040" for a space.
+func unescapeDiffPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+5 <= len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+5], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 4
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
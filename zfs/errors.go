@@ -0,0 +1,164 @@
+package zfs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors classifying a failed zfs command. Test for them with errors.Is on
+// the errors returned by this package.
+var (
+	// ErrSnapshotExists means the snapshot to create already exists.
+	ErrSnapshotExists = errors.New("snapshot already exists")
+
+	// ErrDatasetNotFound means a filesystem or volume does not exist.
+	ErrDatasetNotFound = errors.New("dataset not found")
+
+	// ErrSnapshotNotFound means a snapshot does not exist.
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+
+	// ErrHoldNotFound means a snapshot has no hold with the tag to release.
+	ErrHoldNotFound = errors.New("hold not found")
+
+	// ErrBusy means a dataset or snapshot is in use, for example mounted,
+	// cloned or held.
+	ErrBusy = errors.New("dataset is busy")
+
+	// ErrHeld means a snapshot could not be destroyed because it has user
+	// holds, or already has a hold with the tag to place. It also matches
+	// ErrBusy.
+	ErrHeld = fmt.Errorf("snapshot is held: %w", ErrBusy)
+
+	// ErrPermissionDenied means the user may not run the operation.
+	ErrPermissionDenied = errors.New("permission denied")
+
+	// ErrTimeout means the command did not finish within its deadline. It
+	// also matches context.DeadlineExceeded.
+	ErrTimeout = errors.New("timed out")
+
+	// ErrInvalidArgument means a name or option was rejected before
+	// running zfs.
+	ErrInvalidArgument = errors.New("invalid argument")
+)
+
+// argumentError is a name or option rejected before running zfs.
+type argumentError struct {
+	msg string
+}
+
+// NewArgumentError returns an error matching ErrInvalidArgument, with a
+// message formatted as by fmt.Sprintf.
+func NewArgumentError(format string, args ...any) error {
+	return &argumentError{msg: fmt.Sprintf(format, args...)}
+}
+
+// Error implements error.
+func (e *argumentError) Error() string {
+	return e.msg
+}
+
+// Is matches ErrInvalidArgument.
+func (e *argumentError) Is(target error) bool {
+	return target == ErrInvalidArgument
+}
+
+// Error is a zfs command that exited with a non-zero status or timed out.
+// It matches the classifying error for its stderr with errors.Is, if any.
+type Error struct {
+	// Argv is the command that was run.
+	Argv []string
+
+	// Stderr is the raw standard error of the command.
+	Stderr string
+
+	// ExitCode is the exit status, or 0 when the command did not exit.
+	ExitCode int
+
+	// Err is the error that stopped the command before it exited, such as
+	// context.DeadlineExceeded.
+	Err error
+
+	kind error
+}
+
+// NewError returns the Error of a command that exited with exitCode,
+// classified by its stderr.
+func NewError(argv []string, stderr string, exitCode int) *Error {
+	return &Error{
+		Argv:     argv,
+		Stderr:   stderr,
+		ExitCode: exitCode,
+		kind:     classify(stderr),
+	}
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("exit status %d: %s", e.ExitCode, strings.TrimSpace(e.Stderr))
+}
+
+// Unwrap returns the classifying error and Err.
+func (e *Error) Unwrap() []error {
+	var errs []error
+	for _, err := range []error{e.kind, e.Err} {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// classify returns the error matching a zfs error message, or nil. Messages
+// have the form "cannot <operation> '<name>': <reason>".
+func classify(stderr string) error {
+	msg := strings.ToLower(stderr)
+	snapshot := strings.Contains(errorSubject(stderr), "@")
+	switch {
+	case strings.Contains(msg, "permission denied"):
+		return ErrPermissionDenied
+	case strings.Contains(msg, "could not find any snapshots"):
+		return ErrSnapshotNotFound
+	case strings.Contains(msg, "dataset does not exist"):
+		if snapshot {
+			return ErrSnapshotNotFound
+		}
+		return ErrDatasetNotFound
+	case strings.Contains(msg, "dataset already exists") && snapshot:
+		return ErrSnapshotExists
+	case strings.Contains(msg, "tag already exists"):
+		return ErrHeld
+	case strings.Contains(msg, "cannot destroy") && strings.Contains(msg, "is busy") && snapshot:
+		// zfs reports a snapshot with user holds as busy. Clones are
+		// reported as dependent clones instead.
+		return ErrHeld
+	case strings.Contains(msg, "no such tag"):
+		return ErrHoldNotFound
+	case strings.Contains(msg, "is busy"), strings.Contains(msg, "dependent clones"):
+		return ErrBusy
+	}
+	return nil
+}
+
+// errorSubject returns the name a zfs error message is about: the first
+// quoted name, or else the last word before the reason, as in "cannot
+// destroy snapshot pool/fs@daily: dataset is busy".
+func errorSubject(stderr string) string {
+	if _, rest, ok := strings.Cut(stderr, "'"); ok {
+		if name, _, ok := strings.Cut(rest, "'"); ok {
+			return name
+		}
+	}
+	before, _, ok := strings.Cut(stderr, ": ")
+	if !ok {
+		return ""
+	}
+	words := strings.Fields(before)
+	if len(words) == 0 {
+		return ""
+	}
+	return words[len(words)-1]
+}
@@ -0,0 +1,61 @@
+package zfs_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jsirianni/zfssnap/zfs"
+)
+
+func TestNewError(t *testing.T) {
+	tests := []struct {
+		stderr   string
+		expected error
+	}{
+		{stderr: "cannot create snapshot 'pool/data@daily': dataset already exists\n", expected: zfs.ErrSnapshotExists},
+		{stderr: "cannot open 'pool/missing': dataset does not exist\n", expected: zfs.ErrDatasetNotFound},
+		{stderr: "cannot open 'pool/data@missing': dataset does not exist\n", expected: zfs.ErrSnapshotNotFound},
+		{stderr: "could not find any snapshots to destroy; check snapshot names.\n", expected: zfs.ErrSnapshotNotFound},
+		{stderr: "cannot destroy snapshot pool/data@daily: dataset is busy\n", expected: zfs.ErrHeld},
+		{stderr: "cannot destroy 'pool/data': dataset is busy\n", expected: zfs.ErrBusy},
+		{stderr: "cannot destroy 'pool/data@daily': snapshot has dependent clones\nuse '-R' to destroy the following datasets:\npool/clone\n", expected: zfs.ErrBusy},
+		{stderr: "cannot hold snapshot 'pool/data@daily': tag already exists on this dataset\n", expected: zfs.ErrHeld},
+		{stderr: "cannot release hold from snapshot 'pool/data@daily': no such tag on this dataset\n", expected: zfs.ErrHoldNotFound},
+		{stderr: "cannot create snapshot 'pool/data@daily': permission denied\n", expected: zfs.ErrPermissionDenied},
+		{stderr: "cannot create 'pool/clone': dataset already exists\n", expected: nil},
+		{stderr: "internal error: out of memory\n", expected: nil},
+	}
+
+	classes := []error{
+		zfs.ErrSnapshotExists, zfs.ErrDatasetNotFound, zfs.ErrSnapshotNotFound, zfs.ErrHoldNotFound,
+		zfs.ErrBusy, zfs.ErrHeld, zfs.ErrPermissionDenied, zfs.ErrTimeout,
+	}
+	for _, tt := range tests {
+		err := zfs.NewError([]string{"zfs"}, tt.stderr, 1)
+		for _, class := range classes {
+			// ErrHeld is also busy.
+			want := class == tt.expected || (class == zfs.ErrBusy && tt.expected == zfs.ErrHeld)
+			if errors.Is(err, class) != want {
+				t.Errorf("%q: expected errors.Is(%v) to be %v", tt.stderr, class, want)
+			}
+		}
+	}
+
+	err := zfs.NewError([]string{"zfs", "snapshot", "pool/data@daily"}, "cannot create snapshot 'pool/data@daily': dataset already exists\n", 1)
+	if expected := "exit status 1: cannot create snapshot 'pool/data@daily': dataset already exists"; err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestNewArgumentError(t *testing.T) {
+	err := zfs.NewArgumentError("invalid snapshot name: %s", "pool@")
+	if err.Error() != "invalid snapshot name: pool@" {
+		t.Errorf("Unexpected message: %q", err.Error())
+	}
+	if !errors.Is(err, zfs.ErrInvalidArgument) {
+		t.Errorf("Expected %v to match ErrInvalidArgument", err)
+	}
+	if errors.Is(errors.New("invalid snapshot name: pool@"), zfs.ErrInvalidArgument) {
+		t.Error("Expected an untyped error not to match ErrInvalidArgument")
+	}
+}
@@ -0,0 +1,117 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+)
+
+// HoldOptions controls hold, release and holds operations.
+type HoldOptions struct {
+	// Recursive applies the operation to the snapshot with the same name in
+	// all descendent datasets (-r).
+	Recursive bool
+}
+
+// Compile-time check that Snapshot implements Holder.
+var _ Holder = (*Snapshot)(nil)
+
+// Hold places a user hold with the given tag on a snapshot using `zfs hold`.
+func (c *Snapshot) Hold(ctx context.Context, name, tag string, opts HoldOptions) error {
+	args, err := holdArgs("hold", name, tag, opts)
+	if err != nil {
+		return err
+	}
+	if _, err := c.run(ctx, args...); err != nil {
+		return fmt.Errorf("zfs hold %s failed: %w", name, err)
+	}
+	return nil
+}
+
+// Release removes the user hold with the given tag from a snapshot using
+// `zfs release`.
+func (c *Snapshot) Release(ctx context.Context, name, tag string, opts HoldOptions) error {
+	args, err := holdArgs("release", name, tag, opts)
+	if err != nil {
+		return err
+	}
+	if _, err := c.run(ctx, args...); err != nil {
+		return fmt.Errorf("zfs release %s failed: %w", name, err)
+	}
+	return nil
+}
+
+// Holds lists the user holds on a snapshot using `zfs holds`.
+func (c *Snapshot) Holds(ctx context.Context, name string, opts HoldOptions) ([]*model.Hold, error) {
+	name = strings.TrimSpace(name)
+	if !IsValidSnapshotName(name) {
+		return nil, NewArgumentError("invalid snapshot name format: %s (must contain @)", name)
+	}
+
+	args := []string{"holds", "-H", "-p"}
+	if opts.Recursive {
+		args = append(args, "-r")
+	}
+	args = append(args, name)
+
+	stdout, err := c.run(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("zfs holds %s failed: %w", name, err)
+	}
+	return parseHolds(stdout)
+}
+
+func holdArgs(subcommand, name, tag string, opts HoldOptions) ([]string, error) {
+	name = strings.TrimSpace(name)
+	if !IsValidSnapshotName(name) {
+		return nil, NewArgumentError("invalid snapshot name format: %s (must contain @)", name)
+	}
+	if err := validateHoldTag(tag); err != nil {
+		return nil, err
+	}
+
+	args := []string{subcommand}
+	if opts.Recursive {
+		args = append(args, "-r")
+	}
+	return append(args, tag, name), nil
+}
+
+// validateHoldTag rejects tags zfs would refuse or parse as an option.
+func validateHoldTag(tag string) error {
+	if strings.TrimSpace(tag) == "" {
+		return fmt.Errorf("hold tag is required")
+	}
+	if strings.HasPrefix(tag, "-") {
+		return NewArgumentError("invalid hold tag %q: must not start with '-'", tag)
+	}
+	if len(tag) > 255 {
+		return NewArgumentError("invalid hold tag %q: longer than 255 characters", tag)
+	}
+	return nil
+}
+
+// parseHolds parses `zfs holds -H -p` output. Lines are of the form:
+// <snapshot>\t<tag>\t<timestamp>
+func parseHolds(out string) ([]*model.Hold, error) {
+	holds := []*model.Hold{}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected zfs holds output: %q", line)
+		}
+		hold := &model.Hold{Snapshot: fields[0], Tag: fields[1]}
+		if v, err := parseUint(strings.TrimSpace(fields[2])); err == nil && v <= math.MaxInt64 {
+			hold.Timestamp = time.Unix(int64(v), 0).UTC()
+		}
+		holds = append(holds, hold)
+	}
+	return holds, nil
+}
@@ -0,0 +1,73 @@
+package zfs
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestHoldValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		snapshot      string
+		tag           string
+		errorContains string
+	}{
+		{
+			name:          "not a snapshot",
+			snapshot:      "pool/data",
+			tag:           "keep",
+			errorContains: "invalid snapshot name format",
+		},
+		{
+			name:          "empty tag",
+			snapshot:      "pool/data@daily",
+			tag:           " ",
+			errorContains: "hold tag is required",
+		},
+		{
+			name:          "tag looks like a flag",
+			snapshot:      "pool/data@daily",
+			tag:           "-r",
+			errorContains: "must not start with '-'",
+		},
+		{
+			name:          "tag too long",
+			snapshot:      "pool/data@daily",
+			tag:           strings.Repeat("a", 256),
+			errorContains: "longer than 255 characters",
+		},
+	}
+
+	s := NewSnapshot()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, err := range []error{
+				s.Hold(context.Background(), tt.snapshot, tt.tag, HoldOptions{}),
+				s.Release(context.Background(), tt.snapshot, tt.tag, HoldOptions{}),
+			} {
+				if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorContains, err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseHolds(t *testing.T) {
+	holds, err := parseHolds("pool/data@daily\tkeep\t1736935200\npool/data@daily\tbackup tool\t1736935260\n\n")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(holds) != 2 || holds[1].Tag != "backup tool" || holds[1].Timestamp.Unix() != 1736935260 {
+		t.Errorf("Unexpected holds: %+v", holds)
+	}
+
+	if holds, err := parseHolds(""); err != nil || len(holds) != 0 {
+		t.Errorf("Expected no holds, got %v, %v", holds, err)
+	}
+
+	if _, err := parseHolds("pool/data@daily\tkeep\n"); err == nil {
+		t.Error("Expected error for malformed line")
+	}
+}
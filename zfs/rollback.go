@@ -0,0 +1,85 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// RollbackOptions controls how a dataset is rolled back.
+type RollbackOptions struct {
+	// DestroyNewer destroys snapshots and bookmarks newer than the target
+	// snapshot (-r). zfs refuses to roll back past newer snapshots without it.
+	DestroyNewer bool
+
+	// DestroyClones also destroys clones of the newer snapshots (-R). It
+	// implies DestroyNewer.
+	DestroyClones bool
+}
+
+// Compile-time checks that Snapshot implements Rollbacker and Copier.
+var (
+	_ Rollbacker = (*Snapshot)(nil)
+	_ Copier     = (*Snapshot)(nil)
+)
+
+// Rollback rolls the dataset of the named snapshot back to that snapshot
+// using `zfs rollback`. Changes made since the snapshot are discarded.
+func (c *Snapshot) Rollback(ctx context.Context, name string, opts RollbackOptions) error {
+	name = strings.TrimSpace(name)
+	if !IsValidSnapshotName(name) {
+		return NewArgumentError("invalid snapshot name format: %s (must contain @)", name)
+	}
+
+	args := []string{"rollback"}
+	switch {
+	case opts.DestroyClones:
+		args = append(args, "-R")
+	case opts.DestroyNewer:
+		args = append(args, "-r")
+	}
+	args = append(args, name)
+
+	if _, err := c.run(ctx, args...); err != nil {
+		return fmt.Errorf("zfs rollback %s failed: %w", name, err)
+	}
+	return nil
+}
+
+// Copy receives a full copy of a snapshot into the new dataset target using
+// `zfs send | zfs receive`. The target is not mounted. The copy is not
+// subject to the default timeout since its duration depends on the size of
+// the snapshot; use ctx to bound it.
+func (c *Snapshot) Copy(ctx context.Context, snapshot, target string) error {
+	snapshot = strings.TrimSpace(snapshot)
+	target = strings.TrimSpace(target)
+	if !IsValidSnapshotName(snapshot) {
+		return NewArgumentError("invalid snapshot name format: %s (must contain @)", snapshot)
+	}
+	if !IsValidDatasetName(target) {
+		return NewArgumentError("invalid dataset name format: %s", target)
+	}
+
+	pipe, ok := c.Runner.(PipeRunner)
+	if !ok {
+		return fmt.Errorf("copy %s: runner %T cannot pipe commands", snapshot, c.Runner)
+	}
+	send, err := c.SendCommand(snapshot, SendOptions{})
+	if err != nil {
+		return err
+	}
+	receive, err := c.ReceiveCommand(target, ReceiveOptions{Unmounted: true})
+	if err != nil {
+		return err
+	}
+	res, err := pipe.Pipe(ctx, send, receive)
+	if err != nil {
+		return fmt.Errorf("zfs send %s | zfs receive %s failed: %w", snapshot, target, err)
+	}
+	if res.ExitCode != 0 {
+		err := NewError(slices.Concat(send, []string{"|"}, receive), string(res.Stderr), res.ExitCode)
+		return fmt.Errorf("zfs send %s | zfs receive %s failed: %w", snapshot, target, err)
+	}
+	return nil
+}
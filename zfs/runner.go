@@ -0,0 +1,128 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Runner executes commands on behalf of Snapshot. It is the seam used to
+// run Snapshot against something other than the local zfs binary, such as
+// a scripted fake in tests.
+type Runner interface {
+	// Run executes argv[0] with the remaining arguments. A non-zero exit
+	// status is reported in Result.ExitCode rather than as an error; the
+	// error is reserved for failures to run the command at all.
+	Run(ctx context.Context, argv []string) (Result, error)
+}
+
+// PipeRunner is a Runner that can also connect the output of one command to
+// the input of another, as in `zfs send | zfs receive`.
+type PipeRunner interface {
+	Runner
+
+	// Pipe executes from and to concurrently with the stdout of from
+	// connected to the stdin of to. The Result holds the stdout of to, the
+	// stderr of both commands and the first non-zero exit status.
+	Pipe(ctx context.Context, from, to []string) (Result, error)
+}
+
+// Result is the outcome of a command executed by a Runner.
+type Result struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+}
+
+// ExecRunner is a Runner that executes commands with os/exec.
+type ExecRunner struct{}
+
+// Compile-time check that ExecRunner implements PipeRunner.
+var _ PipeRunner = ExecRunner{}
+
+// Run implements Runner.
+func (ExecRunner) Run(ctx context.Context, argv []string) (Result, error) {
+	if len(argv) == 0 {
+		return Result{}, fmt.Errorf("command is required")
+	}
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...) // #nosec G204 -- argv is built by this package
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	result := Result{Stdout: stdout.Bytes(), Stderr: stderr.Bytes()}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return result, ctxErr
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+		return result, nil
+	}
+	return result, err
+}
+
+// Pipe implements PipeRunner.
+func (ExecRunner) Pipe(ctx context.Context, from, to []string) (Result, error) {
+	if len(from) == 0 || len(to) == 0 {
+		return Result{}, fmt.Errorf("command is required")
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return Result{}, err
+	}
+
+	src := exec.CommandContext(ctx, from[0], from[1:]...) // #nosec G204 -- argv is built by this package
+	dst := exec.CommandContext(ctx, to[0], to[1:]...)     // #nosec G204 -- argv is built by this package
+	var srcStderr, stdout, dstStderr bytes.Buffer
+	src.Stdout = w
+	src.Stderr = &srcStderr
+	dst.Stdin = r
+	dst.Stdout = &stdout
+	dst.Stderr = &dstStderr
+
+	if err := dst.Start(); err != nil {
+		_ = r.Close()
+		_ = w.Close()
+		return Result{}, err
+	}
+	srcStartErr := src.Start()
+
+	// The children hold their own copies of the pipe; closing ours lets the
+	// reader see EOF once the writer exits.
+	_ = r.Close()
+	_ = w.Close()
+
+	var srcErr error
+	if srcStartErr == nil {
+		srcErr = src.Wait()
+	}
+	dstErr := dst.Wait()
+
+	result := Result{Stdout: stdout.Bytes(), Stderr: append(srcStderr.Bytes(), dstStderr.Bytes()...)}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return result, ctxErr
+	}
+	if srcStartErr != nil {
+		return result, srcStartErr
+	}
+
+	for _, err := range []error{srcErr, dstErr} {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,660 @@
+package zfs_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jsirianni/zfssnap/model"
+	"github.com/jsirianni/zfssnap/testutil"
+	"github.com/jsirianni/zfssnap/zfs"
+)
+
+const detailedColumns = "name,creation,used,referenced,clones,defer_destroy,logicalused,logicalreferenced,guid,userrefs,written,type"
+
+func newFakeSnapshot(t *testing.T) (*zfs.Snapshot, *testutil.FakeRunner) {
+	runner := testutil.NewFakeRunner(t)
+	return zfs.NewSnapshot(zfs.WithZFSPath("/sbin/zfs"), zfs.WithRunner(runner)), runner
+}
+
+func TestExecRunner(t *testing.T) {
+	ctx := context.Background()
+
+	res, err := zfs.ExecRunner{}.Run(ctx, []string{"sh", "-c", "echo out; echo err >&2; exit 3"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(res.Stdout) != "out\n" || string(res.Stderr) != "err\n" || res.ExitCode != 3 {
+		t.Errorf("Unexpected result: %q %q %d", res.Stdout, res.Stderr, res.ExitCode)
+	}
+
+	if _, err := (zfs.ExecRunner{}).Run(ctx, []string{"/nonexistent/zfs"}); err == nil {
+		t.Error("Expected error for missing binary")
+	}
+	if _, err := (zfs.ExecRunner{}).Run(ctx, nil); err == nil {
+		t.Error("Expected error for empty argv")
+	}
+}
+
+func TestSnapshotListRunner(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          zfs.ListOptions
+		argv          []string
+		stdout        string
+		stderr        string
+		exitCode      int
+		expected      []string
+		errorContains string
+	}{
+		{
+			name:     "all snapshots",
+			argv:     []string{"/sbin/zfs", "list", "-H", "-t", "snapshot", "-o", "name"},
+			stdout:   "zroot/var/mail@test2\nzroot/var/tmp@test\n",
+			expected: []string{"zroot/var/mail@test2", "zroot/var/tmp@test"},
+		},
+		{
+			name:     "no snapshots",
+			argv:     []string{"/sbin/zfs", "list", "-H", "-t", "snapshot", "-o", "name"},
+			stdout:   "",
+			expected: []string{},
+		},
+		{
+			name:     "recursive dataset",
+			opts:     zfs.ListOptions{Dataset: "zroot/var", Recursive: true},
+			argv:     []string{"/sbin/zfs", "list", "-H", "-t", "snapshot", "-o", "name", "-r", "zroot/var"},
+			stdout:   "zroot/var@daily\nzroot/var/tmp@daily\n",
+			expected: []string{"zroot/var@daily", "zroot/var/tmp@daily"},
+		},
+		{
+			name:          "missing dataset",
+			opts:          zfs.ListOptions{Dataset: "zroot/missing"},
+			argv:          []string{"/sbin/zfs", "list", "-H", "-t", "snapshot", "-o", "name", "-d", "1", "zroot/missing"},
+			stderr:        "cannot open 'zroot/missing': dataset does not exist\n",
+			exitCode:      1,
+			errorContains: "zfs list failed: exit status 1: cannot open 'zroot/missing': dataset does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, runner := newFakeSnapshot(t)
+			runner.Expect(tt.argv...).Return(tt.stdout, tt.stderr, tt.exitCode)
+
+			names, err := s.List(context.Background(), tt.opts)
+			if tt.errorContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errorContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestSnapshotListDetailedRunner(t *testing.T) {
+	s, runner := newFakeSnapshot(t)
+	runner.Expect("/sbin/zfs", "list", "-H", "-p", "-t", "snapshot", "-o", detailedColumns, "-d", "1", "zroot/var/tmp").
+		Return("zroot/var/tmp@test\t1754526169\t65536\t114688\t-\toff\t-\t48128\t16532700914722816504\t0\t114688\tsnapshot\n", "", 0)
+
+	snapshots, err := s.ListDetailed(context.Background(), zfs.ListOptions{Dataset: "zroot/var/tmp"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := testutil.NewTestData().GetOutput["zroot/var/tmp@test"]
+	if len(snapshots) != 1 {
+		t.Fatalf("Expected 1 snapshot, got %d", len(snapshots))
+	}
+	got := snapshots[0]
+	if got.Name != expected.Name || got.Used != expected.Used || got.GUID != expected.GUID || got.LogicalReferenced != expected.LogicalReferenced {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestSnapshotGetRunner(t *testing.T) {
+	argv := []string{"/sbin/zfs", "get", "-H", "-p", "-o", "name,property,value", detailedColumns, "zroot/var/tmp@test"}
+
+	t.Run("parses properties", func(t *testing.T) {
+		s, runner := newFakeSnapshot(t)
+		runner.Expect(argv...).Return(strings.Join([]string{
+			"zroot/var/tmp@test\tname\tzroot/var/tmp@test",
+			"zroot/var/tmp@test\tcreation\t1754526169",
+			"zroot/var/tmp@test\tused\t65536",
+			"zroot/var/tmp@test\treferenced\t114688",
+			"zroot/var/tmp@test\tclones\t",
+			"zroot/var/tmp@test\tdefer_destroy\toff",
+			"zroot/var/tmp@test\tlogicalused\t-",
+			"zroot/var/tmp@test\tlogicalreferenced\t48128",
+			"zroot/var/tmp@test\tguid\t16532700914722816504",
+			"zroot/var/tmp@test\tuserrefs\t0",
+			"zroot/var/tmp@test\twritten\t114688",
+			"zroot/var/tmp@test\ttype\tsnapshot",
+		}, "\n")+"\n", "", 0)
+
+		info, err := s.Get(context.Background(), "zroot/var/tmp@test")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := testutil.NewTestData().GetOutput["zroot/var/tmp@test"]
+		if info.Name != expected.Name || info.Dataset != expected.Dataset {
+			t.Errorf("Unexpected name/dataset: %q %q", info.Name, info.Dataset)
+		}
+		if !info.Creation.Equal(time.Unix(1754526169, 0)) {
+			t.Errorf("Unexpected creation: %v", info.Creation)
+		}
+		if info.Used != expected.Used || info.Referenced != expected.Referenced || info.Written != expected.Written {
+			t.Errorf("Unexpected space accounting: %+v", info)
+		}
+		if info.GUID != expected.GUID || info.Type != "snapshot" || info.Clones != nil {
+			t.Errorf("Unexpected guid/type/clones: %+v", info)
+		}
+	})
+
+	t.Run("empty output", func(t *testing.T) {
+		s, runner := newFakeSnapshot(t)
+		runner.Expect(argv...)
+
+		if _, err := s.Get(context.Background(), "zroot/var/tmp@test"); !errors.Is(err, zfs.ErrSnapshotNotFound) {
+			t.Errorf("Expected not found error, got %v", err)
+		}
+	})
+
+	t.Run("zfs error", func(t *testing.T) {
+		s, runner := newFakeSnapshot(t)
+		runner.Expect(argv...).Return("", "cannot open 'zroot/var/tmp@test': dataset does not exist\n", 1)
+
+		_, err := s.Get(context.Background(), "zroot/var/tmp@test")
+		if err == nil || !strings.Contains(err.Error(), "dataset does not exist") {
+			t.Errorf("Expected zfs error, got %v", err)
+		}
+		if !errors.Is(err, zfs.ErrSnapshotNotFound) || errors.Is(err, zfs.ErrDatasetNotFound) {
+			t.Errorf("Expected snapshot not found, got %v", err)
+		}
+		var zfsErr *zfs.Error
+		if !errors.As(err, &zfsErr) {
+			t.Fatalf("Expected *zfs.Error, got %T", err)
+		}
+		if strings.Join(zfsErr.Argv, " ") != strings.Join(argv, " ") || zfsErr.ExitCode != 1 || !strings.HasPrefix(zfsErr.Stderr, "cannot open") {
+			t.Errorf("Unexpected error details: %+v", zfsErr)
+		}
+	})
+
+	t.Run("runner failure", func(t *testing.T) {
+		s, runner := newFakeSnapshot(t)
+		runner.Expect(argv...).Fail(context.DeadlineExceeded)
+
+		_, err := s.Get(context.Background(), "zroot/var/tmp@test")
+		if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, zfs.ErrTimeout) {
+			t.Errorf("Expected timeout, got %v", err)
+		}
+	})
+}
+
+func TestSnapshotCreateRunner(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          zfs.CreateOptions
+		argv          []string
+		stderr        string
+		exitCode      int
+		errorContains string
+	}{
+		{
+			name: "simple",
+			argv: []string{"/sbin/zfs", "snapshot", "pool/data@backup"},
+		},
+		{
+			name: "recursive with properties",
+			opts: zfs.CreateOptions{Recursive: true, Properties: map[string]string{"com.example:b": "2", "com.example:a": "1"}},
+			argv: []string{"/sbin/zfs", "snapshot", "-r", "-o", "com.example:a=1", "-o", "com.example:b=2", "pool/data@backup"},
+		},
+		{
+			name:          "already exists",
+			argv:          []string{"/sbin/zfs", "snapshot", "pool/data@backup"},
+			stderr:        "cannot create snapshot 'pool/data@backup': dataset already exists\n",
+			exitCode:      1,
+			errorContains: "zfs snapshot pool/data@backup failed: exit status 1: cannot create snapshot 'pool/data@backup': dataset already exists",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, runner := newFakeSnapshot(t)
+			runner.Expect(tt.argv...).Return("", tt.stderr, tt.exitCode)
+
+			err := s.Create(context.Background(), "pool/data", "backup", tt.opts)
+			if tt.errorContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errorContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSnapshotDeleteRunner(t *testing.T) {
+	tests := []struct {
+		name              string
+		opts              zfs.DeleteOptions
+		argv              []string
+		stdout            string
+		expectedDestroyed []string
+		expectedReclaimed uint64
+	}{
+		{
+			name:              "simple",
+			argv:              []string{"/sbin/zfs", "destroy", "-v", "-p", "pool/data@backup"},
+			stdout:            "destroy\tpool/data@backup\nreclaim\t4096\n",
+			expectedDestroyed: []string{"pool/data@backup"},
+			expectedReclaimed: 4096,
+		},
+		{
+			name:              "dry run recursive deferred",
+			opts:              zfs.DeleteOptions{DryRun: true, Recursive: true, Defer: true},
+			argv:              []string{"/sbin/zfs", "destroy", "-v", "-p", "-n", "-r", "-d", "pool/data@backup"},
+			stdout:            "destroy\tpool/data@backup\ndestroy\tpool/data/child@backup\nreclaim\t8192\n",
+			expectedDestroyed: []string{"pool/data@backup", "pool/data/child@backup"},
+			expectedReclaimed: 8192,
+		},
+		{
+			name:              "no verbose output",
+			argv:              []string{"/sbin/zfs", "destroy", "-v", "-p", "pool/data@backup"},
+			expectedDestroyed: []string{"pool/data@backup"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, runner := newFakeSnapshot(t)
+			runner.Expect(tt.argv...).Return(tt.stdout, "", 0)
+
+			result, err := s.Delete(context.Background(), "pool/data@backup", tt.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(result.Destroyed, ",") != strings.Join(tt.expectedDestroyed, ",") {
+				t.Errorf("Expected destroyed %v, got %v", tt.expectedDestroyed, result.Destroyed)
+			}
+			if result.Reclaimed != tt.expectedReclaimed {
+				t.Errorf("Expected reclaimed %d, got %d", tt.expectedReclaimed, result.Reclaimed)
+			}
+		})
+	}
+}
+
+func TestSnapshotDeleteHeldRunner(t *testing.T) {
+	s, runner := newFakeSnapshot(t)
+	runner.Expect("/sbin/zfs", "destroy", "-v", "-p", "-r", "pool/data@backup").
+		Return("destroy\tpool/data@backup\ndestroy\tpool/data/child@backup\n", "cannot destroy snapshot pool/data/child@backup: dataset is busy\n", 1)
+
+	_, err := s.Delete(context.Background(), "pool/data@backup", zfs.DeleteOptions{Recursive: true})
+	if !errors.Is(err, zfs.ErrHeld) || !errors.Is(err, zfs.ErrBusy) {
+		t.Errorf("Expected a held snapshot error, got %v", err)
+	}
+}
+
+func TestSnapshotHoldsRunner(t *testing.T) {
+	ctx := context.Background()
+	s, runner := newFakeSnapshot(t)
+	runner.Expect("/sbin/zfs", "hold", "-r", "keep", "pool/data@daily")
+	runner.Expect("/sbin/zfs", "holds", "-H", "-p", "-r", "pool/data@daily").
+		Return("pool/data@daily\tkeep\t1736935200\npool/data/child@daily\tkeep\t1736935200\n", "", 0)
+	runner.Expect("/sbin/zfs", "release", "keep", "pool/data@daily").
+		Return("", "cannot release hold from snapshot 'pool/data@daily': no such tag on this dataset\n", 1)
+
+	if err := s.Hold(ctx, "pool/data@daily", "keep", zfs.HoldOptions{Recursive: true}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	holds, err := s.Holds(ctx, "pool/data@daily", zfs.HoldOptions{Recursive: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(holds) != 2 || holds[1].Snapshot != "pool/data/child@daily" || holds[1].Tag != "keep" {
+		t.Errorf("Unexpected holds: %+v", holds)
+	}
+	if !holds[0].Timestamp.Equal(time.Unix(1736935200, 0)) {
+		t.Errorf("Unexpected timestamp: %v", holds[0].Timestamp)
+	}
+
+	err = s.Release(ctx, "pool/data@daily", "keep", zfs.HoldOptions{})
+	if err == nil || !strings.Contains(err.Error(), "no such tag") {
+		t.Errorf("Expected release error, got %v", err)
+	}
+}
+
+func TestSnapshotRollbackRunner(t *testing.T) {
+	tests := []struct {
+		name string
+		opts zfs.RollbackOptions
+		argv []string
+	}{
+		{
+			name: "latest snapshot",
+			argv: []string{"/sbin/zfs", "rollback", "pool/data@daily"},
+		},
+		{
+			name: "destroy newer",
+			opts: zfs.RollbackOptions{DestroyNewer: true},
+			argv: []string{"/sbin/zfs", "rollback", "-r", "pool/data@daily"},
+		},
+		{
+			name: "destroy clones",
+			opts: zfs.RollbackOptions{DestroyNewer: true, DestroyClones: true},
+			argv: []string{"/sbin/zfs", "rollback", "-R", "pool/data@daily"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, runner := newFakeSnapshot(t)
+			runner.Expect(tt.argv...)
+			if err := s.Rollback(context.Background(), "pool/data@daily", tt.opts); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+
+	s, _ := newFakeSnapshot(t)
+	if err := s.Rollback(context.Background(), "pool/data", zfs.RollbackOptions{}); err == nil {
+		t.Error("Expected error for dataset name")
+	}
+}
+
+func TestSnapshotCopyRunner(t *testing.T) {
+	s, runner := newFakeSnapshot(t)
+	runner.Expect("/sbin/zfs", "send", "pool/data@safety", "|", "/sbin/zfs", "receive", "-u", "pool/copy")
+	runner.Expect("/sbin/zfs", "send", "pool/data@safety", "|", "/sbin/zfs", "receive", "-u", "pool/copy").
+		Return("", "cannot receive new filesystem stream: destination 'pool/copy' exists\n", 1)
+
+	if err := s.Copy(context.Background(), "pool/data@safety", "pool/copy"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err := s.Copy(context.Background(), "pool/data@safety", "pool/copy")
+	if err == nil || !strings.Contains(err.Error(), "exit status 1: cannot receive new filesystem stream") {
+		t.Errorf("Expected receive error, got %v", err)
+	}
+}
+
+func TestExecRunnerPipe(t *testing.T) {
+	ctx := context.Background()
+
+	res, err := zfs.ExecRunner{}.Pipe(ctx, []string{"echo", "stream"}, []string{"tr", "a-z", "A-Z"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(res.Stdout) != "STREAM\n" || res.ExitCode != 0 {
+		t.Errorf("Unexpected result: %q %d", res.Stdout, res.ExitCode)
+	}
+
+	res, err = zfs.ExecRunner{}.Pipe(ctx, []string{"sh", "-c", "echo broken >&2; exit 2"}, []string{"cat"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if res.ExitCode != 2 || string(res.Stderr) != "broken\n" {
+		t.Errorf("Expected sender failure, got %q %d", res.Stderr, res.ExitCode)
+	}
+}
+
+func TestSnapshotCloneRunner(t *testing.T) {
+	ctx := context.Background()
+	s, runner := newFakeSnapshot(t)
+	runner.Expect("/sbin/zfs", "clone", "pool/db@nightly", "pool/db-test")
+	runner.Expect("/sbin/zfs", "clone", "-p", "-o", "mountpoint=/srv/test", "-o", "readonly=off", "pool/db@nightly", "pool/test/db")
+	runner.Expect("/sbin/zfs", "promote", "pool/test/db")
+
+	if err := s.Clone(ctx, "pool/db@nightly", "pool/db-test", zfs.CloneOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	opts := zfs.CloneOptions{
+		CreateParents: true,
+		Properties:    map[string]string{"readonly": "off", "mountpoint": "/srv/test"},
+	}
+	if err := s.Clone(ctx, "pool/db@nightly", "pool/test/db", opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := s.Promote(ctx, "pool/test/db"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, err := range []error{
+		s.Clone(ctx, "pool/db", "pool/db-test", zfs.CloneOptions{}),
+		s.Clone(ctx, "pool/db@nightly", "pool/db@test", zfs.CloneOptions{}),
+		s.Clone(ctx, "pool/db@nightly", "pool/db-test", zfs.CloneOptions{Properties: map[string]string{"bad key": "x"}}),
+		s.Promote(ctx, "pool/db@nightly"),
+	} {
+		if err == nil {
+			t.Error("Expected validation error")
+		}
+	}
+}
+
+func TestSnapshotSendReceiveCommands(t *testing.T) {
+	s, _ := newFakeSnapshot(t)
+
+	tests := []struct {
+		name string
+		got  func() ([]string, error)
+		want string
+	}{
+		{"full", func() ([]string, error) { return s.SendCommand("pool/a@s2", zfs.SendOptions{}) }, "/sbin/zfs send pool/a@s2"},
+		{"incremental", func() ([]string, error) {
+			return s.SendCommand("pool/a@s2", zfs.SendOptions{From: "pool/a@s1"})
+		}, "/sbin/zfs send -i pool/a@s1 pool/a@s2"},
+		{"intermediates", func() ([]string, error) {
+			return s.SendCommand("pool/a@s2", zfs.SendOptions{From: "pool/a@s1", Intermediates: true})
+		}, "/sbin/zfs send -I pool/a@s1 pool/a@s2"},
+		{"from bookmark", func() ([]string, error) {
+			return s.SendCommand("pool/a@s2", zfs.SendOptions{From: "pool/a#s1"})
+		}, "/sbin/zfs send -i pool/a#s1 pool/a@s2"},
+		{"resume", func() ([]string, error) {
+			return s.SendCommand("", zfs.SendOptions{ResumeToken: "1-abc"})
+		}, "/sbin/zfs send -t 1-abc"},
+		{"receive", func() ([]string, error) {
+			return s.ReceiveCommand("backup/a", zfs.ReceiveOptions{Resumable: true, Force: true, Unmounted: true})
+		}, "/sbin/zfs receive -s -F -u backup/a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			argv, err := tt.got()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := strings.Join(argv, " "); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := s.SendCommand("pool/a", zfs.SendOptions{}); err == nil {
+		t.Error("Expected error for dataset name")
+	}
+	if _, err := s.SendCommand("pool/a@s2", zfs.SendOptions{From: "pool/a"}); err == nil {
+		t.Error("Expected error for incremental source without @")
+	}
+	if _, err := s.SendCommand("pool/a@s2", zfs.SendOptions{From: "pool/a#s1", Intermediates: true}); err == nil {
+		t.Error("Expected error for intermediates from a bookmark")
+	}
+	if _, err := s.ReceiveCommand("", zfs.ReceiveOptions{}); err == nil {
+		t.Error("Expected error for empty dataset")
+	}
+}
+
+func TestSnapshotResumeTokenRunner(t *testing.T) {
+	ctx := context.Background()
+	s, runner := newFakeSnapshot(t)
+	runner.Expect("/sbin/zfs", "get", "-H", "-o", "value", "receive_resume_token", "backup/a").Return("-\n", "", 0)
+	runner.Expect("/sbin/zfs", "get", "-H", "-o", "value", "receive_resume_token", "backup/a").Return("1-abc-def\n", "", 0)
+	runner.Expect("/sbin/zfs", "receive", "-A", "backup/a")
+
+	token, err := s.ResumeToken(ctx, "backup/a")
+	if err != nil || token != "" {
+		t.Errorf("Expected no token, got %q, %v", token, err)
+	}
+	token, err = s.ResumeToken(ctx, "backup/a")
+	if err != nil || token != "1-abc-def" {
+		t.Errorf("Expected token, got %q, %v", token, err)
+	}
+	if err := s.AbortReceive(ctx, "backup/a"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestSnapshotBookmarksRunner(t *testing.T) {
+	ctx := context.Background()
+	s, runner := newFakeSnapshot(t)
+	runner.Expect("/sbin/zfs", "bookmark", "pool/data@daily", "pool/data#daily")
+	runner.Expect("/sbin/zfs", "list", "-H", "-p", "-t", "bookmark", "-o", "name,creation,guid,createtxg", "-r", "pool/data").
+		Return("pool/data#daily\t1736935200\t16532700914722816504\t1234\npool/data/child#daily\t1736935200\t42\t1234\n", "", 0)
+	runner.Expect("/sbin/zfs", "destroy", "pool/data#daily")
+
+	if err := s.Bookmark(ctx, "pool/data@daily", "pool/data#daily"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	bookmarks, err := s.Bookmarks(ctx, zfs.ListOptions{Dataset: "pool/data", Recursive: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(bookmarks) != 2 || bookmarks[1].Dataset != "pool/data/child" {
+		t.Fatalf("Unexpected bookmarks: %+v", bookmarks)
+	}
+	b := bookmarks[0]
+	if b.GUID != 16532700914722816504 || b.CreateTXG != 1234 || !b.Creation.Equal(time.Unix(1736935200, 0)) {
+		t.Errorf("Unexpected bookmark: %+v", b)
+	}
+
+	if err := s.DeleteBookmark(ctx, "pool/data#daily"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	for _, err := range []error{
+		s.Bookmark(ctx, "pool/data@daily", "pool/other#daily"),
+		s.Bookmark(ctx, "pool/data", "pool/data#daily"),
+		s.DeleteBookmark(ctx, "pool/data@daily"),
+	} {
+		if err == nil {
+			t.Error("Expected validation error")
+		}
+	}
+}
+
+func TestSnapshotDiffRunner(t *testing.T) {
+	ctx := context.Background()
+	s, runner := newFakeSnapshot(t)
+	runner.Expect("/sbin/zfs", "diff", "-H", "-F", "-t", "pool/data@a", "pool/data@b").
+		Return("1736935200.123456789\t+\tF\t/pool/data/new file\\0040(1).txt\n"+
+			"1736935201.000000000\t-\t/\t/pool/data/old\n"+
+			"1736935202.5\tM\t@\t/pool/data/link\n"+
+			"1736935203.000000000\tR\tF\t/pool/data/a\\0134b\t/pool/data/c\n", "", 0)
+	runner.Expect("/sbin/zfs", "diff", "-H", "-F", "-t", "pool/data@a").
+		Return("1736935200.000000000\tX\tF\t/pool/data/file\n", "", 0)
+
+	changes, err := s.Diff(ctx, "pool/data@a", "pool/data@b")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(changes) != 4 {
+		t.Fatalf("Expected 4 changes, got %d", len(changes))
+	}
+	if c := changes[0]; c.Type != model.ChangeAdded || c.FileType != model.FileTypeFile ||
+		c.Path != "/pool/data/new file (1).txt" || !c.Time.Equal(time.Unix(1736935200, 123456789)) {
+		t.Errorf("Unexpected added change: %+v", c)
+	}
+	if c := changes[1]; c.Type != model.ChangeRemoved || c.FileType != model.FileTypeDirectory {
+		t.Errorf("Unexpected removed change: %+v", c)
+	}
+	if c := changes[2]; c.Type != model.ChangeModified || c.FileType != model.FileTypeSymlink || c.Time.Nanosecond() != 500000000 {
+		t.Errorf("Unexpected modified change: %+v", c)
+	}
+	if c := changes[3]; c.Type != model.ChangeRenamed || c.Path != `/pool/data/a\b` || c.NewPath != "/pool/data/c" {
+		t.Errorf("Unexpected renamed change: %+v", c)
+	}
+
+	if _, err := s.Diff(ctx, "pool/data@a", ""); err == nil || !strings.Contains(err.Error(), "unexpected zfs diff change") {
+		t.Errorf("Expected parse error, got %v", err)
+	}
+	if _, err := s.Diff(ctx, "pool/data", ""); err == nil {
+		t.Error("Expected validation error")
+	}
+}
+
+func TestDatasetsRunner(t *testing.T) {
+	ctx := context.Background()
+	get := []string{"/sbin/zfs", "get", "-H", "-p", "-o", "name,property,value", "-t", "filesystem,volume", "all"}
+	tree := "pool\ttype\tfilesystem\npool\tused\t3000\npool\tavailable\t9000\npool\tmountpoint\t/pool\npool\tquota\t0\npool\torigin\t-\n" +
+		"pool/data\ttype\tfilesystem\npool/data\tcreation\t1736935200\npool/data\tused\t2000\npool/data\tavailable\t9000\n" +
+		"pool/data\treferenced\t1500\npool/data\tmountpoint\t/srv/data\npool/data\tquota\t10000\n" +
+		"pool/data\tcom.example:owner\tbackup team\npool/data\tcompression\tlz4\n" +
+		"pool/data/vol\ttype\tvolume\npool/data/vol\tused\t1000\npool/data/vol\tmountpoint\t-\npool/data/vol\tquota\t-\n" +
+		"pool/data/vol\torigin\tpool/data@base\n"
+
+	s, runner := newFakeSnapshot(t)
+	runner.Expect(get...).Return(tree, "", 0)
+	runner.Expect(append(get, "-r", "pool/data")...).Return("pool/data\ttype\tfilesystem\npool/data/vol\ttype\tvolume\n", "", 0)
+	runner.Expect(append(get, "pool/data")...).Return("pool/data\ttype\tfilesystem\npool/data\tcom.example:owner\tbackup team\n", "", 0)
+	runner.Expect(append(get, "pool/missing")...).Return("", "cannot open 'pool/missing': dataset does not exist\n", 1)
+	runner.Expect(append(get, "pool/empty")...).Return("", "", 0)
+	runner.Expect(append(get, "-d", "1", "pool")...).Return("pool\ttype\tfilesystem\npool/data\ttype\tfilesystem\n", "", 0)
+
+	d := s.Datasets()
+	all, err := d.List(ctx, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("Expected 3 datasets, got %d", len(all))
+	}
+	if all[0].Name != "pool" || all[0].Mountpoint != "/pool" || all[0].Origin != "" {
+		t.Errorf("Unexpected pool: %+v", all[0])
+	}
+	data := all[1]
+	if data.Pool != "pool" || data.Type != "filesystem" || data.Used != 2000 || data.Available != 9000 ||
+		data.Referenced != 1500 || data.Quota != 10000 || data.Mountpoint != "/srv/data" ||
+		!data.Creation.Equal(time.Unix(1736935200, 0)) {
+		t.Errorf("Unexpected dataset: %+v", data)
+	}
+	if len(data.Properties) != 1 || data.Properties["com.example:owner"] != "backup team" {
+		t.Errorf("Expected only user properties, got %v", data.Properties)
+	}
+	if vol := all[2]; vol.Type != "volume" || vol.Mountpoint != "" || vol.Quota != 0 || vol.Origin != "pool/data@base" {
+		t.Errorf("Unexpected volume: %+v", vol)
+	}
+
+	tree2, err := d.List(ctx, "pool/data")
+	if err != nil || len(tree2) != 2 {
+		t.Errorf("Expected dataset tree, got %v, %v", tree2, err)
+	}
+
+	ds, err := d.Get(ctx, "pool/data")
+	if err != nil || ds.Name != "pool/data" {
+		t.Errorf("Expected pool/data, got %v, %v", ds, err)
+	}
+	if _, err := d.Get(ctx, "pool/missing"); !errors.Is(err, zfs.ErrDatasetNotFound) || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Expected does not exist error, got %v", err)
+	}
+	if _, err := d.Get(ctx, "pool/empty"); !errors.Is(err, zfs.ErrDatasetNotFound) {
+		t.Errorf("Expected zfs.ErrDatasetNotFound, got %v", err)
+	}
+
+	children, err := d.Children(ctx, "pool")
+	if err != nil || len(children) != 1 || children[0].Name != "pool/data" {
+		t.Errorf("Expected the direct children of pool, got %v, %v", children, err)
+	}
+
+	if _, err := d.Get(ctx, "pool/data@snap"); err == nil {
+		t.Error("Expected validation error")
+	}
+}
@@ -0,0 +1,126 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SendOptions controls the stream produced by `zfs send`.
+type SendOptions struct {
+	// From is the snapshot or bookmark the stream is incremental from. When
+	// empty, a full stream is sent.
+	From string
+
+	// Intermediates includes every snapshot between From and the snapshot
+	// in the stream (-I) rather than only the difference between them (-i).
+	// It cannot be used when From is a bookmark.
+	Intermediates bool
+
+	// ResumeToken resumes an interrupted stream (-t). The snapshot and the
+	// other options are taken from the token.
+	ResumeToken string
+}
+
+// ReceiveOptions controls how `zfs receive` applies a stream.
+type ReceiveOptions struct {
+	// Resumable saves the state of an interrupted receive so that it can be
+	// resumed with the dataset's receive_resume_token (-s).
+	Resumable bool
+
+	// Force rolls the dataset back to its most recent snapshot before
+	// receiving an incremental stream (-F).
+	Force bool
+
+	// Unmounted does not mount the received dataset (-u).
+	Unmounted bool
+}
+
+// noResumeToken is the value of receive_resume_token when there is no
+// interrupted receive to resume.
+const noResumeToken = "-"
+
+// SendCommand returns the argv of the `zfs send` that streams snapshot. It
+// does not run the command; the caller connects it to a receive, usually
+// with PipeRunner.
+func (c *Snapshot) SendCommand(snapshot string, opts SendOptions) ([]string, error) {
+	if token := strings.TrimSpace(opts.ResumeToken); token != "" {
+		return []string{c.ZFSPath, "send", "-t", token}, nil
+	}
+
+	snapshot = strings.TrimSpace(snapshot)
+	if !IsValidSnapshotName(snapshot) {
+		return nil, NewArgumentError("invalid snapshot name format: %s (must contain @)", snapshot)
+	}
+
+	argv := []string{c.ZFSPath, "send"}
+	if from := strings.TrimSpace(opts.From); from != "" {
+		bookmark := IsValidBookmarkName(from)
+		if !bookmark && !IsValidSnapshotName(from) {
+			return nil, NewArgumentError("invalid incremental source format: %s (must contain @ or #)", from)
+		}
+		if opts.Intermediates && bookmark {
+			return nil, fmt.Errorf("intermediate snapshots cannot be sent from bookmark %s", from)
+		}
+		if opts.Intermediates {
+			argv = append(argv, "-I", from)
+		} else {
+			argv = append(argv, "-i", from)
+		}
+	}
+	return append(argv, snapshot), nil
+}
+
+// ReceiveCommand returns the argv of the `zfs receive` that applies a stream
+// to dataset. Like SendCommand, it does not run the command.
+func (c *Snapshot) ReceiveCommand(dataset string, opts ReceiveOptions) ([]string, error) {
+	dataset = strings.TrimSpace(dataset)
+	if !IsValidDatasetName(dataset) {
+		return nil, NewArgumentError("invalid dataset name format: %s", dataset)
+	}
+
+	argv := []string{c.ZFSPath, "receive"}
+	if opts.Resumable {
+		argv = append(argv, "-s")
+	}
+	if opts.Force {
+		argv = append(argv, "-F")
+	}
+	if opts.Unmounted {
+		argv = append(argv, "-u")
+	}
+	return append(argv, dataset), nil
+}
+
+// ResumeToken returns the receive_resume_token of dataset, or an empty
+// string when it has no interrupted receive to resume.
+func (c *Snapshot) ResumeToken(ctx context.Context, dataset string) (string, error) {
+	dataset = strings.TrimSpace(dataset)
+	if !IsValidDatasetName(dataset) {
+		return "", NewArgumentError("invalid dataset name format: %s", dataset)
+	}
+
+	out, err := c.run(ctx, "get", "-H", "-o", "value", "receive_resume_token", dataset)
+	if err != nil {
+		return "", fmt.Errorf("zfs get receive_resume_token %s failed: %w", dataset, err)
+	}
+	token := strings.TrimSpace(out)
+	if token == noResumeToken {
+		return "", nil
+	}
+	return token, nil
+}
+
+// AbortReceive discards the saved state of an interrupted receive into
+// dataset using `zfs receive -A`.
+func (c *Snapshot) AbortReceive(ctx context.Context, dataset string) error {
+	dataset = strings.TrimSpace(dataset)
+	if !IsValidDatasetName(dataset) {
+		return NewArgumentError("invalid dataset name format: %s", dataset)
+	}
+
+	if _, err := c.run(ctx, "receive", "-A", dataset); err != nil {
+		return fmt.Errorf("zfs receive -A %s failed: %w", dataset, err)
+	}
+	return nil
+}
@@ -2,12 +2,12 @@
 package zfs
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"math"
-	"os/exec"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -31,8 +31,11 @@ func WithZFSPath(path string) Option { return func(s *Snapshot) { s.ZFSPath = pa
 // WithTimeout sets the default timeout for CLI calls.
 func WithTimeout(d time.Duration) Option { return func(s *Snapshot) { s.Timeout = d } }
 
+// WithRunner sets the Runner used to execute zfs. If not provided, ExecRunner is used.
+func WithRunner(r Runner) Option { return func(s *Snapshot) { s.Runner = r } }
+
 // NewSnapshot creates a new CLI-backed snapshotter with options.
-// Defaults: ZFSPath=DefaultZFSBinary, Timeout=DefaultTimeout.
+// Defaults: ZFSPath=DefaultZFSBinary, Timeout=DefaultTimeout, Runner=ExecRunner.
 func NewSnapshot(opts ...Option) *Snapshot {
 	s := &Snapshot{
 		ZFSPath: DefaultZFSBinary,
@@ -52,45 +55,86 @@ func NewSnapshot(opts ...Option) *Snapshot {
 	if s.Timeout <= 0 {
 		s.Timeout = DefaultTimeout
 	}
+	if s.Runner == nil {
+		s.Runner = ExecRunner{}
+	}
 	return s
 }
 
 // Snapshot is a concrete implementation of Snapshotter that
-// will use the `zfs` command line interface under the hood.
-// Methods are currently stubbed and will be implemented to shell out
-// to the ZFS CLI in future edits.
+// uses the `zfs` command line interface under the hood.
 type Snapshot struct {
 	// Path to the zfs binary, e.g. "/sbin/zfs". If empty, "zfs" on PATH is used.
 	ZFSPath string
 
 	// Optional default timeout for CLI calls.
 	Timeout time.Duration
+
+	// Runner executes the zfs binary.
+	Runner Runner
 }
 
 // Compile-time check that Snapshot implements Snapshotter.
 var _ Snapshotter = (*Snapshot)(nil)
 
-// execContext is a small seam for testing and future customization.
-func (c *Snapshot) execContext(ctx context.Context, name string, args ...string) *exec.Cmd {
-	return exec.CommandContext(ctx, name, args...)
+// run executes zfs with args under the default timeout and returns stdout.
+// A non-zero exit status or a timeout is returned as an *Error.
+func (c *Snapshot) run(ctx context.Context, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	argv := append([]string{c.ZFSPath}, args...)
+	res, err := c.Runner.Run(ctx, argv)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "", &Error{Argv: argv, Stderr: string(res.Stderr), Err: err, kind: ErrTimeout}
+	}
+	if err != nil {
+		return "", err
+	}
+	if res.ExitCode != 0 {
+		return "", NewError(argv, string(res.Stderr), res.ExitCode)
+	}
+	return string(res.Stdout), nil
 }
 
-// List returns the names of ZFS snapshots using the `zfs` CLI.
-func (c *Snapshot) List(ctx context.Context) ([]string, error) {
-	args := []string{"list", "-H", "-t", "snapshot", "-o", "name"}
+// ListOptions scopes which snapshots are listed.
+type ListOptions struct {
+	// Dataset limits the listing to snapshots of this dataset. When empty,
+	// snapshots of every dataset are listed.
+	Dataset string
 
-	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
-	defer cancel()
+	// Recursive includes snapshots of all descendents of Dataset.
+	Recursive bool
+}
+
+// args returns the `zfs list` arguments that scope the listing.
+func (o ListOptions) args() ([]string, error) {
+	dataset := strings.TrimSpace(o.Dataset)
+	if dataset == "" {
+		return nil, nil
+	}
+	if !IsValidDatasetName(dataset) {
+		return nil, NewArgumentError("invalid dataset name format: %s", dataset)
+	}
+	if o.Recursive {
+		return []string{"-r", dataset}, nil
+	}
+	return []string{"-d", "1", dataset}, nil
+}
+
+// List returns the names of ZFS snapshots using the `zfs` CLI.
+func (c *Snapshot) List(ctx context.Context, opts ListOptions) ([]string, error) {
+	scope, err := opts.args()
+	if err != nil {
+		return nil, err
+	}
+	args := append([]string{"list", "-H", "-t", "snapshot", "-o", "name"}, scope...)
 
-	cmd := c.execContext(ctx, c.ZFSPath, args...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("zfs list failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	out, err := c.run(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("zfs list failed: %w", err)
 	}
 
-	out := stdout.String()
 	if out == "" {
 		return []string{}, nil
 	}
@@ -107,9 +151,55 @@ func (c *Snapshot) List(ctx context.Context) ([]string, error) {
 	return snapshots, nil
 }
 
-// Create creates a snapshot. Stub: returns nil for now.
+// ListDetailed returns ZFS snapshots with all of their properties using a
+// single `zfs list` invocation, avoiding one `zfs get` per snapshot.
+func (c *Snapshot) ListDetailed(ctx context.Context, opts ListOptions) ([]*model.Snapshot, error) {
+	scope, err := opts.args()
+	if err != nil {
+		return nil, err
+	}
+	args := append([]string{"list", "-H", "-p", "-t", "snapshot", "-o", strings.Join(snapshotProperties, ",")}, scope...)
+
+	stdout, err := c.run(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("zfs list failed: %w", err)
+	}
+
+	return parseListDetailed(stdout)
+}
+
+// parseListDetailed parses `zfs list -H -p` output whose columns are
+// snapshotProperties, in order.
+func parseListDetailed(out string) ([]*model.Snapshot, error) {
+	snapshots := []*model.Snapshot{}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != len(snapshotProperties) {
+			return nil, fmt.Errorf("unexpected zfs list output: expected %d columns, got %d: %q", len(snapshotProperties), len(fields), line)
+		}
+		info := &model.Snapshot{}
+		for i, prop := range snapshotProperties {
+			setSnapshotProperty(info, prop, fields[i])
+		}
+		snapshots = append(snapshots, info)
+	}
+	return snapshots, nil
+}
+
+// CreateOptions controls how a snapshot is created.
+type CreateOptions struct {
+	// Recursive atomically snapshots all descendent datasets (-r).
+	Recursive bool
+
+	// Properties are set on the snapshot at creation time (-o property=value).
+	Properties map[string]string
+}
+
 // Create creates a ZFS snapshot with the given name for the specified dataset.
-func (c *Snapshot) Create(ctx context.Context, dataset, snapshotName string) error {
+func (c *Snapshot) Create(ctx context.Context, dataset, snapshotName string, opts CreateOptions) error {
 	dataset = strings.TrimSpace(dataset)
 	snapshotName = strings.TrimSpace(snapshotName)
 
@@ -122,12 +212,12 @@ func (c *Snapshot) Create(ctx context.Context, dataset, snapshotName string) err
 
 	// Validate dataset name format
 	if !IsValidDatasetName(dataset) {
-		return fmt.Errorf("invalid dataset name format: %s", dataset)
+		return NewArgumentError("invalid dataset name format: %s", dataset)
 	}
 
 	// Validate snapshot name format (must be valid component, not full snapshot name)
 	if !IsValidSnapshotComponent(snapshotName) {
-		return fmt.Errorf("invalid snapshot name format: %s (must start with letter, contain only alphanumeric, underscore, hyphen, colon, period)", snapshotName)
+		return NewArgumentError("invalid snapshot name format: %s (must start with letter, contain only alphanumeric, underscore, hyphen, colon, period)", snapshotName)
 	}
 
 	// Construct full snapshot name
@@ -135,29 +225,124 @@ func (c *Snapshot) Create(ctx context.Context, dataset, snapshotName string) err
 
 	// Validate the full snapshot name
 	if !IsValidSnapshotName(fullSnapshotName) {
-		return fmt.Errorf("invalid snapshot name format: %s", fullSnapshotName)
+		return NewArgumentError("invalid snapshot name format: %s", fullSnapshotName)
 	}
 
-	args := []string{"snapshot", fullSnapshotName}
+	args := []string{"snapshot"}
+	if opts.Recursive {
+		args = append(args, "-r")
+	}
+	propArgs, err := propertyArgs(opts.Properties)
+	if err != nil {
+		return err
+	}
+	args = append(args, propArgs...)
+	args = append(args, fullSnapshotName)
 
-	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
-	defer cancel()
+	if _, err := c.run(ctx, args...); err != nil {
+		return fmt.Errorf("zfs snapshot %s failed: %w", fullSnapshotName, err)
+	}
+
+	return nil
+}
+
+// DeleteOptions controls how a snapshot is destroyed.
+type DeleteOptions struct {
+	// Recursive destroys the snapshot in all descendent datasets (-r).
+	Recursive bool
+
+	// Defer marks the snapshot for deferred destruction when it has holds
+	// or clones instead of failing (-d).
+	Defer bool
+
+	// DryRun reports what would be destroyed without destroying anything (-n).
+	DryRun bool
+}
+
+// DeleteResult describes the outcome of a destroy operation.
+type DeleteResult struct {
+	// Snapshots that were destroyed, or would be destroyed for a dry run.
+	Destroyed []string
 
-	cmd := c.execContext(ctx, c.ZFSPath, args...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	// Space reclaimed, or that would be reclaimed for a dry run (bytes).
+	Reclaimed uint64
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("zfs snapshot %s failed: %w: %s", fullSnapshotName, err, strings.TrimSpace(stderr.String()))
+// Delete destroys a snapshot using `zfs destroy`. The verbose parsable
+// output of zfs is used to report the destroyed snapshots and reclaimed space.
+func (c *Snapshot) Delete(ctx context.Context, name string, opts DeleteOptions) (*DeleteResult, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("snapshot name is required")
 	}
 
-	return nil
+	// Refuse anything that is not a snapshot so a typo can never destroy a dataset.
+	if !IsValidSnapshotName(name) {
+		return nil, NewArgumentError("invalid snapshot name format: %s (must contain @)", name)
+	}
+
+	args := []string{"destroy", "-v", "-p"}
+	if opts.DryRun {
+		args = append(args, "-n")
+	}
+	if opts.Recursive {
+		args = append(args, "-r")
+	}
+	if opts.Defer {
+		args = append(args, "-d")
+	}
+	args = append(args, name)
+
+	stdout, err := c.run(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("zfs destroy %s failed: %w", name, err)
+	}
+
+	result := parseDestroyOutput(stdout)
+	if len(result.Destroyed) == 0 {
+		result.Destroyed = []string{name}
+	}
+	return result, nil
 }
 
-// Delete destroys a snapshot.
-func (c *Snapshot) Delete(_ context.Context, _ string) error {
-	return nil
+// propertyArgs converts properties into sorted "-o property=value" arguments
+// so the resulting argv is deterministic.
+func propertyArgs(props map[string]string) ([]string, error) {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		if strings.TrimSpace(k) == "" || strings.ContainsAny(k, "= \t") {
+			return nil, NewArgumentError("invalid property name: %q", k)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	args := make([]string, 0, len(keys)*2)
+	for _, k := range keys {
+		args = append(args, "-o", k+"="+props[k])
+	}
+	return args, nil
+}
+
+// parseDestroyOutput parses the output of `zfs destroy -v -p`, which emits
+// lines of the form "destroy\t<snapshot>" and "reclaim\t<bytes>".
+func parseDestroyOutput(out string) *DeleteResult {
+	result := &DeleteResult{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "destroy":
+			result.Destroyed = append(result.Destroyed, fields[1])
+		case "reclaim":
+			if v, err := parseUint(fields[1]); err == nil {
+				result.Reclaimed = v
+			}
+		}
+	}
+	return result
 }
 
 // Get returns detailed information for a given snapshot using `zfs get`.
@@ -169,36 +354,24 @@ func (c *Snapshot) Get(ctx context.Context, name string) (*model.Snapshot, error
 
 	// Validate snapshot name format
 	if !IsValidSnapshotName(name) {
-		return nil, fmt.Errorf("invalid snapshot name format: %s (must contain @)", name)
+		return nil, NewArgumentError("invalid snapshot name format: %s (must contain @)", name)
 	}
 
 	// Query properties in a single call; -H for scriptable, -p for parsable numbers
-	props := []string{
-		"name", "creation", "used", "referenced", "clones", "defer_destroy",
-		"logicalused", "logicalreferenced", "guid", "userrefs", "written", "type",
-	}
-	args := []string{"get", "-H", "-p", "-o", "property,value", strings.Join(props, ","), name}
-
-	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
-	defer cancel()
+	args := []string{"get", "-H", "-p", "-o", "name,property,value", strings.Join(snapshotProperties, ","), name}
 
-	cmd := c.execContext(ctx, c.ZFSPath, args...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("zfs get failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	stdout, err := c.run(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("zfs get failed: %w", err)
 	}
 
-	out := strings.TrimSpace(stdout.String())
+	out := strings.TrimSpace(stdout)
 	if out == "" {
-		return nil, fmt.Errorf("snapshot not found: %s", name)
+		return nil, fmt.Errorf("%w: %s", ErrSnapshotNotFound, name)
 	}
 
 	info := &model.Snapshot{}
-	// Parse lines of the form: <name>\t<property>\t<value>\t- (with -o property,value we expect: <property>\t<value>)
-	// But because we used -o property,value and provided dataset, output is lines: <name>\t<property>\t<value>\t<source>
-	// We'll split on newlines and then fields by tabs, taking property and value from positions 1 and 2.
+	// Output lines are of the form: <name>\t<property>\t<value>
 	lines := strings.Split(out, "\n")
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
@@ -210,57 +383,7 @@ func (c *Snapshot) Get(ctx context.Context, name string) (*model.Snapshot, error
 			continue
 		}
 		// fields[0]=dataset@snap, fields[1]=property, fields[2]=value
-		prop := fields[1]
-		val := fields[2]
-		switch prop {
-		case "name":
-			info.Name = val
-			if at := strings.Index(val, "@"); at > 0 {
-				info.Dataset = val[:at]
-			}
-		case "creation":
-			if v, err := parseUint(val); err == nil && v <= math.MaxInt64 {
-				info.Creation = time.Unix(int64(v), 0).UTC()
-			}
-		case "used":
-			if v, err := parseUint(val); err == nil {
-				info.Used = v
-			}
-		case "referenced":
-			if v, err := parseUint(val); err == nil {
-				info.Referenced = v
-			}
-		case "clones":
-			if val == "-" || val == "" {
-				info.Clones = nil
-			} else {
-				info.Clones = strings.Split(val, ",")
-			}
-		case "defer_destroy":
-			info.DeferDestroy = val == "on" || val == "yes" || val == "1"
-		case "logicalused":
-			if v, err := parseUint(val); err == nil {
-				info.LogicalUsed = v
-			}
-		case "logicalreferenced":
-			if v, err := parseUint(val); err == nil {
-				info.LogicalReferenced = v
-			}
-		case "guid":
-			if v, err := parseUint(val); err == nil {
-				info.GUID = v
-			}
-		case "userrefs":
-			if v, err := parseUint(val); err == nil {
-				info.UserRefs = v
-			}
-		case "written":
-			if v, err := parseUint(val); err == nil {
-				info.Written = v
-			}
-		case "type":
-			info.Type = val
-		}
+		setSnapshotProperty(info, fields[1], fields[2])
 	}
 
 	// If name was not emitted, fall back to provided
@@ -273,6 +396,66 @@ func (c *Snapshot) Get(ctx context.Context, name string) (*model.Snapshot, error
 	return info, nil
 }
 
+// snapshotProperties are the properties queried to populate model.Snapshot.
+var snapshotProperties = []string{
+	"name", "creation", "used", "referenced", "clones", "defer_destroy",
+	"logicalused", "logicalreferenced", "guid", "userrefs", "written", "type",
+}
+
+// setSnapshotProperty sets the model.Snapshot field for a parsable (-p)
+// property value. Unknown properties and unparsable values are ignored.
+func setSnapshotProperty(info *model.Snapshot, prop, val string) {
+	switch prop {
+	case "name":
+		info.Name = val
+		if at := strings.Index(val, "@"); at > 0 {
+			info.Dataset = val[:at]
+		}
+	case "creation":
+		if v, err := parseUint(val); err == nil && v <= math.MaxInt64 {
+			info.Creation = time.Unix(int64(v), 0).UTC()
+		}
+	case "used":
+		if v, err := parseUint(val); err == nil {
+			info.Used = v
+		}
+	case "referenced":
+		if v, err := parseUint(val); err == nil {
+			info.Referenced = v
+		}
+	case "clones":
+		if val == "-" || val == "" {
+			info.Clones = nil
+		} else {
+			info.Clones = strings.Split(val, ",")
+		}
+	case "defer_destroy":
+		info.DeferDestroy = val == "on" || val == "yes" || val == "1"
+	case "logicalused":
+		if v, err := parseUint(val); err == nil {
+			info.LogicalUsed = v
+		}
+	case "logicalreferenced":
+		if v, err := parseUint(val); err == nil {
+			info.LogicalReferenced = v
+		}
+	case "guid":
+		if v, err := parseUint(val); err == nil {
+			info.GUID = v
+		}
+	case "userrefs":
+		if v, err := parseUint(val); err == nil {
+			info.UserRefs = v
+		}
+	case "written":
+		if v, err := parseUint(val); err == nil {
+			info.Written = v
+		}
+	case "type":
+		info.Type = val
+	}
+}
+
 // parseUint parses a positive integer, returning 0 on error.
 func parseUint(s string) (uint64, error) {
 	s = strings.TrimSpace(s)
@@ -308,6 +491,9 @@ var (
 
 	// zfsSnapshotRegex validates snapshot names (dataset@snapshot)
 	zfsSnapshotRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.:-]*(/[a-zA-Z][a-zA-Z0-9_.:-]*)*@[a-zA-Z][a-zA-Z0-9_.:-]*$`)
+
+	// zfsBookmarkRegex validates bookmark names (dataset#bookmark)
+	zfsBookmarkRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.:-]*(/[a-zA-Z][a-zA-Z0-9_.:-]*)*#[a-zA-Z][a-zA-Z0-9_.:-]*$`)
 )
 
 // IsValidSnapshotName validates ZFS snapshot names according to official specifications.
@@ -337,6 +523,16 @@ func IsValidSnapshotName(name string) bool {
 	return zfsSnapshotRegex.MatchString(name)
 }
 
+// IsValidBookmarkName validates ZFS bookmark names.
+// Format: dataset#bookmark where both parts follow ZFS naming rules.
+func IsValidBookmarkName(name string) bool {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 255 {
+		return false
+	}
+	return zfsBookmarkRegex.MatchString(name)
+}
+
 // IsValidDatasetName validates ZFS dataset names according to official specifications.
 func IsValidDatasetName(name string) bool {
 	name = strings.TrimSpace(name)
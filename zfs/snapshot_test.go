@@ -1,6 +1,7 @@
 package zfs
 
 import (
+	"context"
 	"strings"
 	"testing"
 	"time"
@@ -332,6 +333,32 @@ func TestIsValidDatasetName(t *testing.T) {
 	}
 }
 
+func TestIsValidBookmarkName(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected bool
+	}{
+		{name: "simple bookmark", input: "pool#mark", expected: true},
+		{name: "nested dataset", input: "pool/dataset/child#daily-2025.01:01", expected: true},
+		{name: "snapshot", input: "pool/dataset@snap", expected: false},
+		{name: "missing bookmark", input: "pool/dataset#", expected: false},
+		{name: "missing dataset", input: "#mark", expected: false},
+		{name: "two separators", input: "pool#a#b", expected: false},
+		{name: "empty", input: "", expected: false},
+		{name: "too long", input: "pool#" + strings.Repeat("a", 251), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := IsValidBookmarkName(tt.input)
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v for input: %q", tt.expected, result, tt.input)
+			}
+		})
+	}
+}
+
 func TestIsValidSnapshotComponent(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -681,3 +708,235 @@ func TestSnapshotCreate(t *testing.T) {
 		})
 	}
 }
+
+func TestSnapshotDeleteValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		snapshot      string
+		errorContains string
+	}{
+		{
+			name:          "empty name",
+			snapshot:      "",
+			errorContains: "snapshot name is required",
+		},
+		{
+			name:          "whitespace name",
+			snapshot:      "   ",
+			errorContains: "snapshot name is required",
+		},
+		{
+			name:          "dataset without snapshot",
+			snapshot:      "pool/dataset",
+			errorContains: "invalid snapshot name format",
+		},
+		{
+			name:          "invalid snapshot component",
+			snapshot:      "pool/dataset@123backup",
+			errorContains: "invalid snapshot name format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Validation fails before the zfs binary is executed
+			s := NewSnapshot(WithZFSPath("/nonexistent/zfs"))
+			_, err := s.Delete(context.Background(), tt.snapshot, DeleteOptions{})
+			if err == nil {
+				t.Fatalf("Expected error but got none")
+			}
+			if !strings.Contains(err.Error(), tt.errorContains) {
+				t.Errorf("Expected error containing %q, got %q", tt.errorContains, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseDestroyOutput(t *testing.T) {
+	tests := []struct {
+		name              string
+		output            string
+		expectedDestroyed []string
+		expectedReclaimed uint64
+	}{
+		{
+			name:              "empty output",
+			output:            "",
+			expectedDestroyed: nil,
+			expectedReclaimed: 0,
+		},
+		{
+			name:              "single snapshot",
+			output:            "destroy\tpool/dataset@backup\nreclaim\t65536\n",
+			expectedDestroyed: []string{"pool/dataset@backup"},
+			expectedReclaimed: 65536,
+		},
+		{
+			name:              "recursive",
+			output:            "destroy\tpool/dataset@daily\ndestroy\tpool/dataset/child@daily\nreclaim\t131072\n",
+			expectedDestroyed: []string{"pool/dataset@daily", "pool/dataset/child@daily"},
+			expectedReclaimed: 131072,
+		},
+		{
+			name:              "unparsable reclaim",
+			output:            "destroy\tpool@snap\nreclaim\t-\n",
+			expectedDestroyed: []string{"pool@snap"},
+			expectedReclaimed: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseDestroyOutput(tt.output)
+			if strings.Join(result.Destroyed, ",") != strings.Join(tt.expectedDestroyed, ",") {
+				t.Errorf("Expected destroyed %v, got %v", tt.expectedDestroyed, result.Destroyed)
+			}
+			if result.Reclaimed != tt.expectedReclaimed {
+				t.Errorf("Expected reclaimed %d, got %d", tt.expectedReclaimed, result.Reclaimed)
+			}
+		})
+	}
+}
+
+func TestPropertyArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		props       map[string]string
+		expected    []string
+		expectError bool
+	}{
+		{
+			name:     "no properties",
+			props:    nil,
+			expected: []string{},
+		},
+		{
+			name:     "sorted output",
+			props:    map[string]string{"com.example:b": "2", "com.example:a": "1"},
+			expected: []string{"-o", "com.example:a=1", "-o", "com.example:b=2"},
+		},
+		{
+			name:        "empty name",
+			props:       map[string]string{"": "1"},
+			expectError: true,
+		},
+		{
+			name:        "name containing equals",
+			props:       map[string]string{"a=b": "1"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := propertyArgs(tt.props)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(args, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("Expected %v, got %v", tt.expected, args)
+			}
+		})
+	}
+}
+
+func TestParseListDetailed(t *testing.T) {
+	out := "zroot/var/tmp@test\t1754526169\t65536\t114688\t-\toff\t-\t48128\t16532700914722816504\t0\t114688\tsnapshot\n" +
+		"zroot/data@base\t1754526200\t0\t2048\tzroot/clone1,zroot/clone2\ton\t1024\t4096\t42\t2\t2048\tsnapshot\n"
+
+	snapshots, err := parseListDetailed(out)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("Expected 2 snapshots, got %d", len(snapshots))
+	}
+
+	first := snapshots[0]
+	if first.Name != "zroot/var/tmp@test" || first.Dataset != "zroot/var/tmp" {
+		t.Errorf("Unexpected name/dataset: %q %q", first.Name, first.Dataset)
+	}
+	if !first.Creation.Equal(time.Unix(1754526169, 0)) {
+		t.Errorf("Unexpected creation: %v", first.Creation)
+	}
+	if first.Used != 65536 || first.Referenced != 114688 || first.LogicalUsed != 0 || first.LogicalReferenced != 48128 {
+		t.Errorf("Unexpected space accounting: %+v", first)
+	}
+	if first.Clones != nil || first.DeferDestroy {
+		t.Errorf("Unexpected clones/defer_destroy: %v %v", first.Clones, first.DeferDestroy)
+	}
+	if first.GUID != 16532700914722816504 || first.Written != 114688 || first.Type != "snapshot" {
+		t.Errorf("Unexpected guid/written/type: %+v", first)
+	}
+
+	second := snapshots[1]
+	if len(second.Clones) != 2 || second.Clones[1] != "zroot/clone2" {
+		t.Errorf("Unexpected clones: %v", second.Clones)
+	}
+	if !second.DeferDestroy || second.UserRefs != 2 {
+		t.Errorf("Unexpected defer_destroy/user_refs: %v %d", second.DeferDestroy, second.UserRefs)
+	}
+}
+
+func TestParseListDetailedErrors(t *testing.T) {
+	if snapshots, err := parseListDetailed(""); err != nil || len(snapshots) != 0 {
+		t.Errorf("Expected empty result for empty output, got %v, %v", snapshots, err)
+	}
+	if _, err := parseListDetailed("zroot@snap\t123\n"); err == nil {
+		t.Error("Expected error for short line")
+	}
+}
+
+func TestListOptionsArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        ListOptions
+		expected    []string
+		expectError bool
+	}{
+		{
+			name:     "all snapshots",
+			opts:     ListOptions{},
+			expected: nil,
+		},
+		{
+			name:     "single dataset",
+			opts:     ListOptions{Dataset: "pool/data"},
+			expected: []string{"-d", "1", "pool/data"},
+		},
+		{
+			name:     "recursive",
+			opts:     ListOptions{Dataset: "pool/data", Recursive: true},
+			expected: []string{"-r", "pool/data"},
+		},
+		{
+			name:        "invalid dataset",
+			opts:        ListOptions{Dataset: "pool//data"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := tt.opts.args()
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(args, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("Expected %v, got %v", tt.expected, args)
+			}
+		})
+	}
+}
@@ -9,14 +9,82 @@ import (
 // Snapshotter defines the contract for managing ZFS snapshots.
 type Snapshotter interface {
 	// List returns a list of ZFS snapshot names.
-	List(ctx context.Context) ([]string, error)
+	List(ctx context.Context, opts ListOptions) ([]string, error)
+
+	// ListDetailed returns ZFS snapshots with all of their properties.
+	ListDetailed(ctx context.Context, opts ListOptions) ([]*model.Snapshot, error)
 
 	// Create creates a ZFS snapshot with the given name for the specified dataset.
-	Create(ctx context.Context, name, dataset string) error
+	Create(ctx context.Context, dataset, name string, opts CreateOptions) error
 
-	// Delete removes the ZFS snapshot with the given name.
-	Delete(ctx context.Context, name string) error
+	// Delete destroys the ZFS snapshot with the given name.
+	Delete(ctx context.Context, name string, opts DeleteOptions) (*DeleteResult, error)
 
 	// Get returns detailed information for the specified snapshot name.
 	Get(ctx context.Context, name string) (*model.Snapshot, error)
 }
+
+// Holder manages user holds on snapshots.
+type Holder interface {
+	// Hold places a user hold with the given tag on a snapshot.
+	Hold(ctx context.Context, name, tag string, opts HoldOptions) error
+
+	// Release removes the user hold with the given tag from a snapshot.
+	Release(ctx context.Context, name, tag string, opts HoldOptions) error
+
+	// Holds lists the user holds on a snapshot.
+	Holds(ctx context.Context, name string, opts HoldOptions) ([]*model.Hold, error)
+}
+
+// Rollbacker rolls datasets back to a snapshot.
+type Rollbacker interface {
+	// Rollback discards all changes made to the dataset since the snapshot.
+	Rollback(ctx context.Context, name string, opts RollbackOptions) error
+}
+
+// Copier copies snapshots into new datasets.
+type Copier interface {
+	// Copy receives a full copy of snapshot into the new dataset target.
+	Copy(ctx context.Context, snapshot, target string) error
+}
+
+// Cloner creates and promotes clones.
+type Cloner interface {
+	// Clone creates the dataset target from a snapshot.
+	Clone(ctx context.Context, snapshot, target string, opts CloneOptions) error
+
+	// Promote makes a clone independent of the snapshot it was created from.
+	Promote(ctx context.Context, dataset string) error
+}
+
+// Bookmarker manages bookmarks.
+type Bookmarker interface {
+	// Bookmark creates a bookmark of a snapshot.
+	Bookmark(ctx context.Context, snapshot, bookmark string) error
+
+	// Bookmarks lists bookmarks.
+	Bookmarks(ctx context.Context, opts ListOptions) ([]*model.Bookmark, error)
+
+	// DeleteBookmark destroys a bookmark.
+	DeleteBookmark(ctx context.Context, name string) error
+}
+
+// Differ reports the file changes between snapshots.
+type Differ interface {
+	// Diff returns the changes between snapshot from and the later snapshot
+	// or dataset to. When to is empty, from is compared to its live dataset.
+	Diff(ctx context.Context, from, to string) ([]*model.Change, error)
+}
+
+// Datasets discovers filesystems and volumes.
+type Datasets interface {
+	// List returns every filesystem and volume, or when root is set, root
+	// and its descendents.
+	List(ctx context.Context, root string) ([]*model.Dataset, error)
+
+	// Get returns the named dataset.
+	Get(ctx context.Context, name string) (*model.Dataset, error)
+
+	// Children returns the direct children of the named dataset.
+	Children(ctx context.Context, name string) ([]*model.Dataset, error)
+}